@@ -1,16 +1,25 @@
 package app
 
 import (
+	"bytes"
 	"github.com/gofiber/fiber/v3"
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
+	"github.com/valyala/fasthttp"
 	"gorm.io/gorm"
 	"os"
 	"prod/internal/adapters/config"
 	"prod/internal/adapters/controller/api/validator"
 	"prod/internal/adapters/logger"
+	"regexp"
 )
 
+// defaultImportBodyLimit is used when imports.body-limit is not set.
+const defaultImportBodyLimit = 32 * 1024 * 1024
+
+// importPath matches the upload of a CSV file with codes, the only request allowed a body over the default limit.
+var importPath = regexp.MustCompile(`^/api/business/promo/[^/]+/codes/import/?$`)
+
 // App is a struct that contains the fiber app, database connection, listen port, validator, logging boolean etc.
 type App struct {
 	Fiber     *fiber.App
@@ -32,6 +41,21 @@ func New(config *config.Config) *App {
 	},
 	)
 
+	importBodyLimit := viper.GetInt("imports.body-limit")
+	if importBodyLimit <= 0 {
+		importBodyLimit = defaultImportBodyLimit
+	}
+
+	// Размер тела проверяет fasthttp до роутинга, поэтому CSV с кодами больше стандартных 4 МБ разрешается по пути
+	fiberApp.Server().HeaderReceived = func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		path, _, _ := bytes.Cut(header.RequestURI(), []byte("?"))
+		if header.IsPost() && importPath.Match(bytes.ToLower(path)) {
+			return fasthttp.RequestConfig{MaxRequestBodySize: importBodyLimit}
+		}
+
+		return fasthttp.RequestConfig{}
+	}
+
 	return &App{
 		Fiber:     fiberApp,
 		DB:        config.Database,
@@ -0,0 +1,274 @@
+// Command bench seeds the database with synthetic data and measures latency of the user feed and history.
+/*
+ * Usage: go run ./cmd/bench -promos 10000 -likes 1000000 -runs 50
+ * Uses the same config.yaml and POSTGRES_* env as the server. Seeded rows are tagged
+ * (emails @bench.local, promo_common BENCH), -clean removes them, -seed=false reuses existing ones.
+ */
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"gorm.io/gorm"
+	"prod/internal/adapters/config"
+	"prod/internal/adapters/database/postgres"
+	"prod/internal/adapters/database/redis"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"prod/internal/domain/service"
+	"prod/internal/domain/utils/cursor"
+	"slices"
+	"time"
+)
+
+const benchUserEmail = "bench-1@bench.local"
+
+var (
+	seed        = flag.Bool("seed", true, "seed synthetic data before measuring")
+	clean       = flag.Bool("clean", false, "remove seeded data and exit")
+	promos      = flag.Int("promos", 10000, "number of promos to seed")
+	likes       = flag.Int("likes", 1000000, "number of likes to seed")
+	activations = flag.Int("activations", 100000, "number of activations to seed")
+	users       = flag.Int("users", 1000, "number of users to seed")
+	runs        = flag.Int("runs", 50, "measured runs per scenario")
+	limit       = flag.Int("limit", 20, "page size")
+)
+
+func main() {
+	flag.Parse()
+
+	appConfig := config.Configure()
+	db := appConfig.Database
+	ctx := context.Background()
+
+	if *clean || *seed {
+		if err := cleanup(db); err != nil {
+			logger.Log.Panicf("failed to clean bench data: %v", err)
+		}
+		if *clean {
+			return
+		}
+
+		started := time.Now()
+		if err := seedData(db); err != nil {
+			logger.Log.Panicf("failed to seed bench data: %v", err)
+		}
+		fmt.Printf("seeded %d promos, %d likes, %d activations, %d users in %s\n", *promos, *likes, *activations, *users, time.Since(started).Round(time.Millisecond))
+	}
+
+	var user entity.User
+	if err := db.Where("email = ?", benchUserEmail).First(&user).Error; err != nil {
+		logger.Log.Panicf("bench user not found, run with -seed: %v", err)
+	}
+
+	promoService := service.NewPromoService(postgres.NewPromoStorage(db), postgres.NewBusinessStorage(db), redis.NewPromoCacheStorage(appConfig.Redis))
+
+	// Курсор второй страницы для keyset-сценария
+	_, nextCursor, _, err := promoService.GetFeed(ctx, &user, dto.PromoFeedRequest{}, dto.Page{Limit: *limit, Keyset: true})
+	if err != nil {
+		logger.Log.Panicf("failed to get the first feed page: %v", err)
+	}
+	second, err := cursorPage(nextCursor)
+	if err != nil {
+		logger.Log.Panicf("failed to decode the feed cursor: %v", err)
+	}
+
+	scenarios := []struct {
+		name string
+		run  func() error
+	}{
+		{"feed new, first page", func() error {
+			_, _, _, err := promoService.GetFeed(ctx, &user, dto.PromoFeedRequest{}, dto.Page{Limit: *limit})
+			return err
+		}},
+		{"feed new, offset 5000", func() error {
+			_, _, _, err := promoService.GetFeed(ctx, &user, dto.PromoFeedRequest{}, dto.Page{Limit: *limit, Offset: 5000})
+			return err
+		}},
+		{"feed new, cursor page 2", func() error {
+			_, _, _, err := promoService.GetFeed(ctx, &user, dto.PromoFeedRequest{}, second)
+			return err
+		}},
+		{"feed popular, first page", func() error {
+			_, _, _, err := promoService.GetFeed(ctx, &user, dto.PromoFeedRequest{SortBy: "popular"}, dto.Page{Limit: *limit})
+			return err
+		}},
+		{"feed relevance, first page", func() error {
+			_, _, _, err := promoService.GetFeed(ctx, &user, dto.PromoFeedRequest{SortBy: "relevance"}, dto.Page{Limit: *limit})
+			return err
+		}},
+		{"history, first page", func() error {
+			_, _, _, err := promoService.GetHistory(ctx, user.ID, dto.Page{Limit: *limit})
+			return err
+		}},
+	}
+
+	fmt.Printf("%-28s %10s %10s %10s %10s\n", "scenario", "p50", "p95", "p99", "max")
+	for _, scenario := range scenarios {
+		// Прогрев
+		if err := scenario.run(); err != nil {
+			logger.Log.Panicf("%s: %v", scenario.name, err)
+		}
+
+		durations := make([]time.Duration, 0, *runs)
+		for i := 0; i < *runs; i++ {
+			started := time.Now()
+			if err := scenario.run(); err != nil {
+				logger.Log.Panicf("%s: %v", scenario.name, err)
+			}
+			durations = append(durations, time.Since(started))
+		}
+		slices.Sort(durations)
+
+		fmt.Printf("%-28s %10s %10s %10s %10s\n",
+			scenario.name,
+			percentile(durations, 50),
+			percentile(durations, 95),
+			percentile(durations, 99),
+			durations[len(durations)-1].Round(time.Microsecond),
+		)
+	}
+}
+
+func cursorPage(nextCursor string) (dto.Page, error) {
+	page := dto.Page{Limit: *limit, Keyset: true}
+	if nextCursor == "" {
+		return page, nil
+	}
+
+	decoded, err := cursor.Decode(nextCursor)
+	if err != nil {
+		return dto.Page{}, err
+	}
+	page.Cursor = decoded
+
+	return page, nil
+}
+
+func percentile(sorted []time.Duration, p int) time.Duration {
+	index := (len(sorted) - 1) * p / 100
+	return sorted[index].Round(time.Microsecond)
+}
+
+// seedData is a function that inserts synthetic businesses, users, promos, categories, likes and activations.
+func seedData(db *gorm.DB) error {
+	queries := []struct {
+		query string
+		args  []interface{}
+	}{
+		{
+			query: `
+				INSERT INTO businesses (id, created_at, updated_at, email, name)
+				SELECT gen_random_uuid(), now(), now(), 'bench-' || g || '@bench.local', 'Bench company ' || g
+				FROM generate_series(1, GREATEST(CAST(? AS int) / 100, 1)) g`,
+			args: []interface{}{*promos},
+		},
+		{
+			query: `
+				INSERT INTO users (id, created_at, updated_at, email, name, surname, age, country, country_original)
+				SELECT gen_random_uuid(), now(), now(), 'bench-' || g || '@bench.local', 'Bench', 'User', 25, 643, 'RU'
+				FROM generate_series(1, ?) g`,
+			args: []interface{}{*users},
+		},
+		{
+			query: `
+				WITH bs AS (SELECT ARRAY_AGG(id) AS ids FROM businesses WHERE email LIKE 'bench-%@bench.local')
+				INSERT INTO promos (company_id, created_at, updated_at, active, active_from, active_until, description,
+									max_count, mode, promo_common, age_from, age_until, country, country_original)
+				SELECT bs.ids[1 + g % ARRAY_LENGTH(bs.ids, 1)],
+					   now() - random() * INTERVAL '90 days',
+					   now(),
+					   TRUE,
+					   now() - INTERVAL '1 day',
+					   now() + INTERVAL '365 days',
+					   'Bench promo number ' || g,
+					   100000,
+					   'COMMON',
+					   'BENCH',
+					   0,
+					   1000,
+					   0,
+					   ''
+				FROM generate_series(1, ?) g, bs`,
+			args: []interface{}{*promos},
+		},
+		{
+			// От одной до трёх категорий на промо
+			query: `
+				INSERT INTO categories (promo_id, name, index)
+				SELECT p.promo_id,
+					   (ARRAY ['food', 'tech', 'travel', 'sport', 'beauty', 'kids', 'books', 'auto', 'home', 'music'])
+						   [1 + (ABS(HASHTEXT(p.promo_id::text)) + i * 3) % 10],
+					   i
+				FROM promos p
+						 CROSS JOIN generate_series(0, 2) i
+				WHERE p.promo_common = 'BENCH'
+				  AND i <= ABS(HASHTEXT(p.promo_id::text)) % 3`,
+		},
+		{
+			query: `
+				WITH ps AS (SELECT ARRAY_AGG(promo_id) AS ids FROM promos WHERE promo_common = 'BENCH'),
+					 us AS (SELECT ARRAY_AGG(id) AS ids FROM users WHERE email LIKE 'bench-%@bench.local')
+				INSERT INTO likes (promo_id, user_id, "like")
+				SELECT ps.ids[1 + FLOOR(random() * ARRAY_LENGTH(ps.ids, 1))::int], us.ids[1 + g % ARRAY_LENGTH(us.ids, 1)], TRUE
+				FROM generate_series(1, ?) g, ps, us`,
+			args: []interface{}{*likes},
+		},
+		{
+			query: `
+				WITH ps AS (SELECT ARRAY_AGG(promo_id) AS ids FROM promos WHERE promo_common = 'BENCH'),
+					 us AS (SELECT ARRAY_AGG(id) AS ids FROM users WHERE email LIKE 'bench-%@bench.local')
+				INSERT INTO activations (user_id, promo_id, created_at)
+				SELECT us.ids[1 + g % ARRAY_LENGTH(us.ids, 1)], ps.ids[1 + FLOOR(random() * ARRAY_LENGTH(ps.ids, 1))::int], now() - random() * INTERVAL '30 days'
+				FROM generate_series(1, ?) g, ps, us`,
+			args: []interface{}{*activations},
+		},
+		{
+			query: `
+				UPDATE promos p
+				SET like_count = l.count
+				FROM (SELECT promo_id, COUNT(*) AS count FROM likes GROUP BY promo_id) l
+				WHERE l.promo_id = p.promo_id
+				  AND p.promo_common = 'BENCH'`,
+		},
+		{
+			query: `
+				UPDATE promos p
+				SET used_count = a.count
+				FROM (SELECT promo_id, COUNT(*) AS count FROM activations GROUP BY promo_id) a
+				WHERE a.promo_id = p.promo_id
+				  AND p.promo_common = 'BENCH'`,
+		},
+		{query: `ANALYZE`},
+	}
+
+	for _, q := range queries {
+		if err := db.Exec(q.query, q.args...).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanup is a function that removes everything seedData inserted.
+func cleanup(db *gorm.DB) error {
+	queries := []string{
+		`DELETE FROM likes WHERE user_id IN (SELECT id FROM users WHERE email LIKE 'bench-%@bench.local')`,
+		`DELETE FROM activations WHERE user_id IN (SELECT id FROM users WHERE email LIKE 'bench-%@bench.local')`,
+		`DELETE FROM categories WHERE promo_id IN (SELECT promo_id FROM promos WHERE promo_common = 'BENCH')`,
+		`DELETE FROM promos WHERE promo_common = 'BENCH'`,
+		`DELETE FROM users WHERE email LIKE 'bench-%@bench.local'`,
+		`DELETE FROM businesses WHERE email LIKE 'bench-%@bench.local'`,
+	}
+
+	for _, query := range queries {
+		if err := db.Exec(query).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,15 +1,34 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
 	"prod/cmd/app"
 	"prod/internal/adapters/config"
 	"prod/internal/adapters/controller/api/setup"
+	"prod/internal/adapters/logger"
+	"syscall"
 )
 
 func main() {
 	appConfig := config.Configure()
 	mainApp := app.New(appConfig)
 
-	setup.Setup(mainApp)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	workers := setup.Setup(ctx, mainApp)
+
+	// По сигналу сервер перестает принимать запросы, а фоновые задачи дописывают свое состояние
+	go func() {
+		<-ctx.Done()
+		if err := mainApp.Fiber.Shutdown(); err != nil {
+			logger.Log.Error(err)
+		}
+	}()
+
 	mainApp.Start()
+	stop()
+	workers.Wait()
 }
@@ -86,6 +86,12 @@ func Configure() *Config {
 		logger.Log.Panicf("Failed to run migrations: %v", errMigrate)
 	}
 
+	for _, rawMigration := range postgresRepo.RawMigrations {
+		if errMigrate := database.Exec(rawMigration).Error; errMigrate != nil {
+			logger.Log.Panicf("Failed to run raw migration: %v", errMigrate)
+		}
+	}
+
 	logger.Log.Info("Database initialized")
 
 	logger.Log.Info("Initializing redis...")
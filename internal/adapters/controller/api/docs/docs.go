@@ -0,0 +1,112 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v3"
+	"net/http"
+	"prod/internal/adapters/logger"
+	"slices"
+	"strings"
+)
+
+const (
+	apiPrefix  = "/api"
+	docsPrefix = "/api/docs"
+)
+
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8"/>
+    <title>PROD API</title>
+    <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css"/>
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>
+    window.onload = () => {
+        window.ui = SwaggerUIBundle({url: "openapi.json", dom_id: "#swagger-ui"});
+    };
+</script>
+</body>
+</html>`
+
+type DocsHandler struct {
+	spec []byte
+}
+
+func NewDocsHandler() *DocsHandler {
+	spec, err := json.Marshal(Build())
+	if err != nil {
+		logger.Log.Panicf("failed to marshal openapi spec: %v", err)
+	}
+
+	return &DocsHandler{spec: spec}
+}
+
+func (h DocsHandler) ui(c fiber.Ctx) error {
+	// Relative spec URL in the page only resolves with the trailing slash
+	if !strings.HasSuffix(c.Path(), "/") {
+		return c.Redirect().To(c.Path() + "/")
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(fiber.StatusOK).SendString(swaggerUI)
+}
+
+func (h DocsHandler) openAPI(c fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Status(fiber.StatusOK).Send(h.spec)
+}
+
+func (h DocsHandler) Setup(router fiber.Router) {
+	docsGroup := router.Group("/docs")
+	docsGroup.Get("/", h.ui)
+	docsGroup.Get("/openapi.json", h.openAPI)
+}
+
+// CheckRoutes is a function that compares registered fiber routes with the route table.
+/*
+ * Returns an error listing routes missing from the spec and spec entries without a handler.
+ */
+func CheckRoutes(registered []fiber.Route) error {
+	documented := make(map[string]bool, len(Routes))
+	for _, route := range Routes {
+		documented[route.Method+" "+route.Path] = false
+	}
+
+	var undocumented []string
+	for _, route := range registered {
+		if route.Method == http.MethodHead || !strings.HasPrefix(route.Path, apiPrefix+"/") || strings.HasPrefix(route.Path, docsPrefix) {
+			continue
+		}
+
+		key := route.Method + " " + strings.TrimPrefix(route.Path, apiPrefix)
+		if _, ok := documented[key]; !ok {
+			undocumented = append(undocumented, key)
+			continue
+		}
+		documented[key] = true
+	}
+
+	var unregistered []string
+	for key, seen := range documented {
+		if !seen {
+			unregistered = append(unregistered, key)
+		}
+	}
+
+	if len(undocumented) == 0 && len(unregistered) == 0 {
+		return nil
+	}
+
+	slices.Sort(undocumented)
+	slices.Sort(unregistered)
+
+	return fmt.Errorf("openapi spec drift: undocumented routes [%s], unregistered spec routes [%s]",
+		strings.Join(undocumented, ", "),
+		strings.Join(unregistered, ", "),
+	)
+}
@@ -0,0 +1,24 @@
+package docs_test
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/docs"
+	"prod/internal/adapters/controller/api/setup"
+	"prod/internal/adapters/controller/api/validator"
+	"prod/internal/adapters/logger"
+	"testing"
+)
+
+// TestRoutesMatchSpec fails when a handler registers a route missing from docs.Routes or the other way round.
+func TestRoutesMatchSpec(t *testing.T) {
+	logger.New(false, "")
+
+	// Обработчики только сохраняют подключения, запросы к базе и Redis при регистрации не выполняются
+	testApp := &app.App{Fiber: fiber.New(), Validator: validator.New()}
+	setup.Routes(testApp)
+
+	if err := docs.CheckRoutes(testApp.Fiber.GetRoutes(true)); err != nil {
+		t.Fatal(err)
+	}
+}
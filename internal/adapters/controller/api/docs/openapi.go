@@ -0,0 +1,211 @@
+package docs
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Document is an OpenAPI 3 document.
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []Server                        `json:"servers"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+const securitySchemeName = "bearerAuth"
+
+var pathParamRegexp = regexp.MustCompile(`:(\w+)`)
+
+// openAPIPath is a function that converts fiber path params (":id") to OpenAPI ones ("{id}").
+func openAPIPath(path string) string {
+	return pathParamRegexp.ReplaceAllString(path, "{$1}")
+}
+
+// operationID is a function that builds a unique operation id from the method and the path.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '_' }) {
+		part = strings.TrimPrefix(part, ":")
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// Build is a function that generates the OpenAPI document from the route table.
+func Build() *Document {
+	generator := newSchemaGenerator()
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "PROD promo aggregation platform",
+			Description: "B2B and B2C API of the promo aggregation platform.",
+			Version:     "1.0.0",
+		},
+		Servers: []Server{{URL: "/api"}},
+		Paths:   make(map[string]map[string]Operation),
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{
+				securitySchemeName: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "Access token issued by /user/auth/sign-in or /business/auth/sign-in.",
+				},
+			},
+		},
+	}
+
+	for _, route := range Routes {
+		operation := Operation{
+			Tags:        []string{route.Tag},
+			Summary:     route.Summary,
+			OperationID: operationID(route.Method, route.Path),
+			Responses:   make(map[string]Response),
+		}
+
+		if route.Params != nil {
+			operation.Parameters = generator.parameters(route.Params)
+		}
+
+		if route.Body != nil {
+			operation.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]MediaType{
+					route.bodyContentType(): {Schema: generator.schema(route.Body)},
+				},
+			}
+		}
+
+		if route.Auth {
+			operation.Security = []map[string][]string{{securitySchemeName: {}}}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		success := Response{Description: http.StatusText(status)}
+		switch response := route.Response.(type) {
+		case nil:
+		case string:
+			contentType := "text/plain"
+			if route.ResponseContentType != "" {
+				contentType = route.ResponseContentType
+			}
+			success.Content = map[string]MediaType{
+				contentType: {Schema: &Schema{Type: "string", Example: response}},
+			}
+		default:
+			schema := generator.schema(response)
+			if route.CursorResponse != nil {
+				schema = &Schema{OneOf: []*Schema{schema, generator.schema(route.CursorResponse)}}
+			}
+			success.Content = map[string]MediaType{
+				route.responseContentType(): {Schema: schema},
+			}
+		}
+		if len(route.Files) > 0 {
+			success.Content = make(map[string]MediaType, len(route.Files))
+			for _, contentType := range route.Files {
+				success.Content[contentType] = MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+			}
+		}
+		if route.TotalCount {
+			success.Headers = map[string]Header{
+				"X-Total-Count": {
+					Description: "Total number of items, in cursor mode only sent with with_total=true",
+					Schema:      &Schema{Type: "integer"},
+				},
+			}
+		}
+		operation.Responses[strconv.Itoa(status)] = success
+
+		for _, code := range route.Errors {
+			var body interface{} = errorResponse
+			if override, ok := route.ErrorBodies[code]; ok {
+				body = override
+			}
+			operation.Responses[strconv.Itoa(code)] = Response{
+				Description: http.StatusText(code),
+				Content: map[string]MediaType{
+					"application/json": {Schema: generator.schema(body)},
+				},
+			}
+		}
+
+		path := openAPIPath(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]Operation)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = operation
+	}
+
+	doc.Components.Schemas = generator.components
+	return doc
+}
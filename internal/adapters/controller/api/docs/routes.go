@@ -0,0 +1,760 @@
+package docs
+
+import (
+	"net/http"
+	"prod/internal/domain/dto"
+)
+
+// Route is a struct that describes one API endpoint for the OpenAPI document.
+/*
+ * Path is relative to /api and uses fiber syntax. Params is a struct with uri/query tags,
+ * Body and Response are DTO values (Response may be a string for text/plain endpoints).
+ * CursorResponse is the envelope returned instead of Response when the "cursor" param is passed.
+ * ErrorBodies overrides the dto.HTTPResponse body of the given error statuses.
+ */
+type Route struct {
+	Method              string
+	Path                string
+	Tag                 string
+	Summary             string
+	Auth                bool
+	Params              interface{}
+	Body                interface{}
+	BodyContentType     string
+	Response            interface{}
+	CursorResponse      interface{}
+	ResponseContentType string
+	Files               []string // content types of a file response, instead of Response
+	Status              int
+	TotalCount          bool
+	Errors              []int
+	ErrorBodies         map[int]interface{}
+}
+
+func (r Route) bodyContentType() string {
+	if r.BodyContentType != "" {
+		return r.BodyContentType
+	}
+	return "application/json"
+}
+
+func (r Route) responseContentType() string {
+	if r.ResponseContentType != "" {
+		return r.ResponseContentType
+	}
+	return "application/json"
+}
+
+var errorResponse = dto.HTTPResponse{}
+
+var exportFiles = []string{"text/csv", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"}
+
+// Routes is a list of all endpoints registered in setup.Setup.
+/*
+ * Keep in sync with the handlers' Setup methods: CheckRoutes reports any drift on startup.
+ */
+var Routes = []Route{
+	// Ping
+	{
+		Method:   http.MethodGet,
+		Path:     "/ping",
+		Tag:      "ping",
+		Summary:  "Health check",
+		Response: "GOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOL",
+	},
+
+	// Metrics
+	{
+		Method:   http.MethodGet,
+		Path:     "/metrics/cache",
+		Tag:      "metrics",
+		Summary:  "Promo cache hit and miss counters of this instance",
+		Response: dto.CacheMetrics{},
+	},
+
+	// B2B auth
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/auth/sign-up",
+		Tag:      "b2b",
+		Summary:  "Register a company",
+		Body:     dto.BusinessRegister{},
+		Response: dto.BusinessRegisterResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusConflict},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/auth/sign-in",
+		Tag:      "b2b",
+		Summary:  "Sign in as a company",
+		Body:     dto.BusinessLogin{},
+		Response: dto.BusinessLoginResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+
+	// B2B promo
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo",
+		Tag:      "b2b",
+		Summary:  "Create a promo",
+		Auth:     true,
+		Body:     dto.PromoCreate{},
+		Response: dto.PromoCreateResponse{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:         http.MethodGet,
+		Path:           "/business/promo",
+		Tag:            "b2b",
+		Summary:        "List company promos",
+		Auth:           true,
+		Params:         dto.PromoGetWithPaginationRequest{},
+		Response:       []dto.PromoDTO{},
+		CursorResponse: dto.CursorPage[dto.PromoDTO]{},
+		TotalCount:     true,
+		Errors:         []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/business/promo/:id",
+		Tag:      "b2b",
+		Summary:  "Get a company promo",
+		Auth:     true,
+		Params:   dto.PromoGetByID{},
+		Response: dto.PromoDTO{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPatch,
+		Path:     "/business/promo/:id",
+		Tag:      "b2b",
+		Summary:  "Update a company promo",
+		Auth:     true,
+		Params:   dto.PromoGetByID{},
+		Body:     dto.PromoUpdate{},
+		Response: dto.PromoDTO{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/business/promo/:id/stat",
+		Tag:      "b2b",
+		Summary:  "Get promo activation stats",
+		Auth:     true,
+		Params:   dto.PromoStats{},
+		Response: dto.PromoStatsResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/business/promo/:id/analytics",
+		Tag:      "b2b",
+		Summary:  "Get promo activations, likes, comments and views over time with the view funnel",
+		Auth:     true,
+		Params:   dto.PromoAnalyticsRequest{},
+		Response: dto.PromoAnalyticsResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:         http.MethodGet,
+		Path:           "/business/promo/:id/comments",
+		Tag:            "b2b",
+		Summary:        "List comments of a company promo",
+		Auth:           true,
+		Params:         dto.GetComments{},
+		Response:       []dto.Comment{},
+		CursorResponse: dto.CursorPage[dto.Comment]{},
+		TotalCount:     true,
+		Errors:         []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo/:id/comments/:comment_id/replies",
+		Tag:      "b2b",
+		Summary:  "Reply to a comment of a company promo on behalf of the company",
+		Auth:     true,
+		Params:   dto.OfficialReply{},
+		Body:     dto.OfficialReply{},
+		Response: dto.Comment{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo/:id/comments/:comment_id/hide",
+		Tag:      "b2b",
+		Summary:  "Hide a published or pending comment of a company promo",
+		Auth:     true,
+		Params:   dto.ModerateComment{},
+		Response: dto.HTTPResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo/:id/comments/:comment_id/approve",
+		Tag:      "b2b",
+		Summary:  "Publish a pending or hidden comment of a company promo",
+		Auth:     true,
+		Params:   dto.ModerateComment{},
+		Response: dto.HTTPResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo/:id/comments/:comment_id/reject",
+		Tag:      "b2b",
+		Summary:  "Reject a pending comment of a company promo",
+		Auth:     true,
+		Params:   dto.ModerateComment{},
+		Response: dto.HTTPResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		Method:         http.MethodGet,
+		Path:           "/business/comments/moderation",
+		Tag:            "b2b",
+		Summary:        "List comments of company promos waiting for moderation, oldest first",
+		Auth:           true,
+		Params:         dto.ModerationQueueRequest{},
+		Response:       []dto.ModerationComment{},
+		CursorResponse: dto.CursorPage[dto.ModerationComment]{},
+		TotalCount:     true,
+		Errors:         []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/business/promo/:id/activations/export",
+		Tag:     "b2b",
+		Summary: "Download activations of a company promo as CSV or XLSX",
+		Auth:    true,
+		Params:  dto.PromoActivationsExportRequest{},
+		Files:   exportFiles,
+		Errors:  []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/business/activations/export",
+		Tag:     "b2b",
+		Summary: "Download activations of all company promos over a range as CSV or XLSX",
+		Auth:    true,
+		Params:  dto.CompanyActivationsExportRequest{},
+		Files:   exportFiles,
+		Errors:  []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/business/stats",
+		Tag:      "b2b",
+		Summary:  "Get the company dashboard over all promos",
+		Auth:     true,
+		Params:   dto.CompanyStatsRequest{},
+		Response: dto.CompanyStatsResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:  http.MethodDelete,
+		Path:    "/business/promo/:id",
+		Tag:     "b2b",
+		Summary: "Soft delete a company promo, its stats are kept",
+		Auth:    true,
+		Params:  dto.PromoGetByID{},
+		Status:  http.StatusNoContent,
+		Errors:  []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo/:id/archive",
+		Tag:      "b2b",
+		Summary:  "Archive a company promo",
+		Auth:     true,
+		Params:   dto.PromoGetByID{},
+		Response: dto.PromoDTO{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo/:id/unarchive",
+		Tag:      "b2b",
+		Summary:  "Return a company promo from the archive",
+		Auth:     true,
+		Params:   dto.PromoGetByID{},
+		Response: dto.PromoDTO{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo/:id/clone",
+		Tag:      "b2b",
+		Summary:  "Copy a company promo into a new draft",
+		Auth:     true,
+		Params:   dto.PromoGetByID{},
+		Response: dto.PromoDTO{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo/:id/publish",
+		Tag:      "b2b",
+		Summary:  "Publish a draft promo",
+		Auth:     true,
+		Params:   dto.PromoGetByID{},
+		Response: dto.PromoDTO{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo/:id/pause",
+		Tag:      "b2b",
+		Summary:  "Pause a scheduled or live promo",
+		Auth:     true,
+		Params:   dto.PromoGetByID{},
+		Response: dto.PromoDTO{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo/:id/resume",
+		Tag:      "b2b",
+		Summary:  "Resume a paused promo",
+		Auth:     true,
+		Params:   dto.PromoGetByID{},
+		Response: dto.PromoDTO{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/business/promo/codes/low-inventory",
+		Tag:      "b2b",
+		Summary:  "List UNIQUE promos running out of codes",
+		Auth:     true,
+		Params:   dto.PromoCodesLowInventory{},
+		Response: dto.PromoCodesLowInventoryResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo/:id/codes",
+		Tag:      "b2b",
+		Summary:  "Upload unique codes to a UNIQUE promo",
+		Auth:     true,
+		Params:   dto.PromoCodesAdd{},
+		Body:     dto.PromoCodesAdd{},
+		Response: dto.PromoCodesAddResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:     http.MethodGet,
+		Path:       "/business/promo/:id/codes",
+		Tag:        "b2b",
+		Summary:    "List unique codes of a promo with their redeemers",
+		Auth:       true,
+		Params:     dto.PromoCodesList{},
+		Response:   []dto.PromoCode{},
+		TotalCount: true,
+		Errors:     []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/promo/:id/codes/revoke",
+		Tag:      "b2b",
+		Summary:  "Revoke unused unique codes of a promo",
+		Auth:     true,
+		Params:   dto.PromoCodesRevoke{},
+		Body:     dto.PromoCodesRevoke{},
+		Response: dto.PromoCodesRevokeResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:          http.MethodPost,
+		Path:            "/business/promo/:id/codes/import",
+		Tag:             "b2b",
+		Summary:         "Start a background import of unique codes from a CSV file",
+		Auth:            true,
+		Params:          dto.PromoCodesImport{},
+		Body:            dto.PromoCodesImport{},
+		BodyContentType: "multipart/form-data",
+		Response:        dto.PromoCodesImportResponse{},
+		Status:          http.StatusAccepted,
+		Errors:          []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/business/promo/:id/codes/import/:import_id",
+		Tag:      "b2b",
+		Summary:  "Get progress of a code import",
+		Auth:     true,
+		Params:   dto.PromoCodesImportGet{},
+		Response: dto.PromoCodesImportResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+
+	// B2B webhooks
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/webhooks",
+		Tag:      "b2b",
+		Summary:  "Register a webhook, the signing secret is only returned here",
+		Auth:     true,
+		Body:     dto.WebhookCreate{},
+		Response: dto.WebhookDTO{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/business/webhooks",
+		Tag:      "b2b",
+		Summary:  "List company webhooks",
+		Auth:     true,
+		Response: []dto.WebhookDTO{},
+		Errors:   []int{http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/business/webhooks/:id",
+		Tag:      "b2b",
+		Summary:  "Get a company webhook",
+		Auth:     true,
+		Params:   dto.WebhookGetByID{},
+		Response: dto.WebhookDTO{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPatch,
+		Path:     "/business/webhooks/:id",
+		Tag:      "b2b",
+		Summary:  "Change the url, events or active flag of a webhook",
+		Auth:     true,
+		Params:   dto.WebhookGetByID{},
+		Body:     dto.WebhookUpdate{},
+		Response: dto.WebhookDTO{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:  http.MethodDelete,
+		Path:    "/business/webhooks/:id",
+		Tag:     "b2b",
+		Summary: "Delete a webhook with its delivery log",
+		Auth:    true,
+		Params:  dto.WebhookGetByID{},
+		Status:  http.StatusNoContent,
+		Errors:  []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/webhooks/:id/rotate-secret",
+		Tag:      "b2b",
+		Summary:  "Issue a new signing secret of a webhook",
+		Auth:     true,
+		Params:   dto.WebhookGetByID{},
+		Response: dto.WebhookDTO{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:         http.MethodGet,
+		Path:           "/business/webhooks/:id/deliveries",
+		Tag:            "b2b",
+		Summary:        "Delivery log of a webhook, status=dead lists the dead letters",
+		Auth:           true,
+		Params:         dto.WebhookDeliveriesRequest{},
+		Response:       []dto.WebhookDeliveryDTO{},
+		CursorResponse: dto.CursorPage[dto.WebhookDeliveryDTO]{},
+		TotalCount:     true,
+		Errors:         []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/business/webhooks/:id/deliveries/:delivery_id/redeliver",
+		Tag:      "b2b",
+		Summary:  "Queue a delivery again with a fresh set of attempts",
+		Auth:     true,
+		Params:   dto.WebhookDeliveryRedeliver{},
+		Response: dto.WebhookDeliveryDTO{},
+		Status:   http.StatusAccepted,
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+
+	// B2C auth and profile
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/auth/sign-up",
+		Tag:      "b2c",
+		Summary:  "Register a user",
+		Body:     dto.UserRegister{},
+		Response: dto.UserRegisterResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusConflict},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/auth/sign-in",
+		Tag:      "b2c",
+		Summary:  "Sign in as a user",
+		Body:     dto.UserLogin{},
+		Response: dto.UserRegisterResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/profile",
+		Tag:      "b2c",
+		Summary:  "Get the user profile",
+		Auth:     true,
+		Response: dto.UserProfile{},
+		Errors:   []int{http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodPatch,
+		Path:     "/user/profile",
+		Tag:      "b2c",
+		Summary:  "Update the user profile",
+		Auth:     true,
+		Body:     dto.UserProfileUpdate{},
+		Response: dto.UserProfile{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+
+	// B2C promo
+	{
+		Method:         http.MethodGet,
+		Path:           "/user/feed",
+		Tag:            "b2c",
+		Summary:        "Get the promo feed",
+		Auth:           true,
+		Params:         dto.PromoFeedRequest{},
+		Response:       []dto.PromoForUser{},
+		CursorResponse: dto.CursorPage[dto.PromoForUser]{},
+		TotalCount:     true,
+		Errors:         []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:         http.MethodGet,
+		Path:           "/user/promo/history",
+		Tag:            "b2c",
+		Summary:        "Get activation history",
+		Auth:           true,
+		Params:         dto.PromoHistory{},
+		Response:       []dto.PromoForUser{},
+		CursorResponse: dto.CursorPage[dto.PromoForUser]{},
+		TotalCount:     true,
+		Errors:         []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:     http.MethodGet,
+		Path:       "/user/promo/search",
+		Tag:        "b2c",
+		Summary:    "Full-text search over promos with facets",
+		Auth:       true,
+		Params:     dto.PromoSearchRequest{},
+		Response:   dto.PromoSearchResponse{},
+		TotalCount: true,
+		Errors:     []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/promo/:id",
+		Tag:      "b2c",
+		Summary:  "Get a promo",
+		Auth:     true,
+		Params:   dto.PromoGetByID{},
+		Response: dto.PromoForUser{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+
+	// B2C actions
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/promo/:id/like",
+		Tag:      "b2c",
+		Summary:  "Like a promo",
+		Auth:     true,
+		Params:   dto.AddLike{},
+		Response: dto.HTTPResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/user/promo/:id/like",
+		Tag:      "b2c",
+		Summary:  "Remove a like",
+		Auth:     true,
+		Params:   dto.AddLike{},
+		Response: dto.HTTPResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/promo/:id/comments",
+		Tag:      "b2c",
+		Summary:  "Comment a promo",
+		Auth:     true,
+		Params:   dto.AddComment{},
+		Body:     dto.AddComment{},
+		Response: dto.Comment{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusTooManyRequests},
+	},
+	{
+		Method:         http.MethodGet,
+		Path:           "/user/promo/:id/comments",
+		Tag:            "b2c",
+		Summary:        "List promo comments",
+		Auth:           true,
+		Params:         dto.GetComments{},
+		Response:       []dto.Comment{},
+		CursorResponse: dto.CursorPage[dto.Comment]{},
+		TotalCount:     true,
+		Errors:         []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/promo/:id/comments/:comment_id",
+		Tag:      "b2c",
+		Summary:  "Get a comment",
+		Auth:     true,
+		Params:   dto.GetCommentById{},
+		Response: dto.Comment{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPut,
+		Path:     "/user/promo/:id/comments/:comment_id",
+		Tag:      "b2c",
+		Summary:  "Edit a comment",
+		Auth:     true,
+		Params:   dto.UpdateComment{},
+		Body:     dto.UpdateComment{},
+		Response: dto.Comment{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/user/promo/:id/comments/:comment_id",
+		Tag:      "b2c",
+		Summary:  "Delete a comment",
+		Auth:     true,
+		Params:   dto.DeleteCommentById{},
+		Response: dto.HTTPResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/promo/:id/comments/:comment_id/report",
+		Tag:      "b2c",
+		Summary:  "Report a comment",
+		Auth:     true,
+		Params:   dto.ReportComment{},
+		Body:     dto.ReportComment{},
+		Response: dto.HTTPResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/promo/:id/activate",
+		Tag:      "b2c",
+		Summary:  "Activate a promo",
+		Auth:     true,
+		Params:   dto.Activate{},
+		Response: dto.ActivateResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests},
+		ErrorBodies: map[int]interface{}{
+			http.StatusTooManyRequests: dto.ActivationLimitResponse{},
+		},
+	},
+
+	// B2C realtime
+	{
+		Method:              http.MethodGet,
+		Path:                "/user/updates",
+		Tag:                 "b2c",
+		Summary:             "Stream of like, comment and stock updates of the given promos visible to the user (server-sent events)",
+		Auth:                true,
+		Params:              dto.PromoLiveUpdatesRequest{},
+		Response:            "event: update\ndata: {\"promo_id\":\"...\",\"type\":\"like\",\"like_count\":3,...}\n\n",
+		ResponseContentType: "text/event-stream",
+		Errors:              []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusServiceUnavailable},
+	},
+
+	// B2C notifications
+	{
+		Method:         http.MethodGet,
+		Path:           "/user/notifications",
+		Tag:            "b2c",
+		Summary:        "List notifications, newest first, the unread count is in X-Unread-Count",
+		Auth:           true,
+		Params:         dto.NotificationsRequest{},
+		Response:       []dto.NotificationDTO{},
+		CursorResponse: dto.CursorPage[dto.NotificationDTO]{},
+		TotalCount:     true,
+		Errors:         []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/notifications/unread-count",
+		Tag:      "b2c",
+		Summary:  "Number of unread notifications",
+		Auth:     true,
+		Response: dto.NotificationUnreadCount{},
+		Errors:   []int{http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/notifications/read-all",
+		Tag:      "b2c",
+		Summary:  "Mark all notifications as read",
+		Auth:     true,
+		Response: dto.NotificationsReadAll{},
+		Errors:   []int{http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/user/notifications/preferences",
+		Tag:      "b2c",
+		Summary:  "Notification types the user gets",
+		Auth:     true,
+		Response: dto.NotificationPreferences{},
+		Errors:   []int{http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodPatch,
+		Path:     "/user/notifications/preferences",
+		Tag:      "b2c",
+		Summary:  "Turn notification types on or off, omitted types are kept",
+		Auth:     true,
+		Body:     dto.NotificationPreferencesUpdate{},
+		Response: dto.NotificationPreferences{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/notifications/:id/read",
+		Tag:      "b2c",
+		Summary:  "Mark a notification as read",
+		Auth:     true,
+		Params:   dto.NotificationRead{},
+		Response: dto.HTTPResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/user/companies/:id/favorite",
+		Tag:      "b2c",
+		Summary:  "Add a company to favorites to be notified of its new promos",
+		Auth:     true,
+		Params:   dto.FavoriteCompanyRequest{},
+		Response: dto.HTTPResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     "/user/companies/:id/favorite",
+		Tag:      "b2c",
+		Summary:  "Remove a company from favorites",
+		Auth:     true,
+		Params:   dto.FavoriteCompanyRequest{},
+		Response: dto.HTTPResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+}
@@ -0,0 +1,272 @@
+package docs
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI 3 schema object.
+type Schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	OneOf      []*Schema          `json:"oneOf,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	Enum       []string           `json:"enum,omitempty"`
+	MinLength  *int               `json:"minLength,omitempty"`
+	MaxLength  *int               `json:"maxLength,omitempty"`
+	Minimum    *int               `json:"minimum,omitempty"`
+	Maximum    *int               `json:"maximum,omitempty"`
+	MinItems   *int               `json:"minItems,omitempty"`
+	MaxItems   *int               `json:"maxItems,omitempty"`
+	Example    interface{}        `json:"example,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaGenerator is a struct that converts Go types to OpenAPI schemas and collects named components.
+type schemaGenerator struct {
+	components map[string]*Schema
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{components: make(map[string]*Schema)}
+}
+
+// schema is a method that returns a schema for the value's type, registering structs as components.
+func (g *schemaGenerator) schema(value interface{}) *Schema {
+	return g.typeSchema(reflect.TypeOf(value))
+}
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct:
+		name := componentName(t)
+		if _, ok := g.components[name]; !ok {
+			// Reserve the name first so that recursive types terminate
+			g.components[name] = &Schema{Type: "object"}
+			g.components[name] = g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.typeSchema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: "object"}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &Schema{Type: "number"}
+	case t.Kind() == reflect.Interface:
+		return &Schema{}
+	default:
+		return &Schema{Type: "string"}
+	}
+}
+
+// componentName is a function that returns the component name of a struct type.
+/*
+ * Generic instantiations are named after their type arguments: CursorPage[pkg.Comment] becomes CursorPage_Comment.
+ */
+func componentName(t reflect.Type) string {
+	base, args, generic := strings.Cut(t.Name(), "[")
+	if !generic {
+		return base
+	}
+
+	var parts []string
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		parts = append(parts, arg[strings.LastIndex(arg, ".")+1:])
+	}
+	return base + "_" + strings.Join(parts, "_")
+}
+
+// structSchema is a method that builds an object schema from the json-tagged fields of a struct.
+/*
+ * Fields bound from the path or query (uri/query tags) and untagged fields are not part of the body.
+ */
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embedded := g.structSchema(field.Type)
+			for name, property := range embedded.Properties {
+				schema.Properties[name] = property
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		jsonTag, ok := field.Tag.Lookup("json")
+		if !ok || jsonTag == "-" {
+			continue
+		}
+		if _, isURI := field.Tag.Lookup("uri"); isURI {
+			continue
+		}
+		if _, isQuery := field.Tag.Lookup("query"); isQuery {
+			continue
+		}
+
+		name := strings.Split(jsonTag, ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.typeSchema(field.Type)
+		if field.Type.Kind() == reflect.Ptr && property.Ref == "" {
+			property.Nullable = true
+		}
+		required := applyValidateTag(property, field.Tag.Get("validate"))
+		applyExample(property, field.Tag.Get("example"))
+		if format, ok := field.Tag.Lookup("format"); ok {
+			property.Format = format
+		}
+
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// parameters is a method that builds path and query parameters from the uri/query-tagged fields of a struct.
+func (g *schemaGenerator) parameters(value interface{}) []Parameter {
+	t := reflect.TypeOf(value)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		in, name := "", ""
+		if tag, ok := field.Tag.Lookup("uri"); ok {
+			in, name = "path", tag
+		} else if tag, ok := field.Tag.Lookup("query"); ok {
+			in, name = "query", tag
+		} else {
+			continue
+		}
+		name = strings.Split(name, ",")[0]
+
+		schema := g.typeSchema(field.Type)
+		required := applyValidateTag(schema, field.Tag.Get("validate"))
+		applyExample(schema, field.Tag.Get("example"))
+
+		params = append(params, Parameter{
+			Name:     name,
+			In:       in,
+			Required: in == "path" || required,
+			Schema:   schema,
+		})
+	}
+
+	return params
+}
+
+// applyValidateTag is a function that maps go-playground validator rules onto the schema.
+/*
+ * Rules after "dive" apply to the slice items. Returns true if the field is required.
+ */
+func applyValidateTag(schema *Schema, tag string) bool {
+	if tag == "" {
+		return false
+	}
+
+	required := false
+	target := schema
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			required = target == schema
+		case "dive":
+			if target.Items != nil {
+				target = target.Items
+			}
+		case "email":
+			target.Format = "email"
+		case "url":
+			target.Format = "uri"
+		case "uuid", "uuid4":
+			target.Format = "uuid"
+		case "oneof":
+			target.Enum = strings.Fields(value)
+		case "min", "max", "len":
+			bound, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			applyBound(target, key, bound)
+		}
+	}
+
+	return required
+}
+
+func applyBound(schema *Schema, key string, bound int) {
+	var lower, upper **int
+	switch schema.Type {
+	case "string":
+		lower, upper = &schema.MinLength, &schema.MaxLength
+	case "integer", "number":
+		lower, upper = &schema.Minimum, &schema.Maximum
+	case "array":
+		lower, upper = &schema.MinItems, &schema.MaxItems
+	default:
+		return
+	}
+
+	switch key {
+	case "min":
+		*lower = &bound
+	case "max":
+		*upper = &bound
+	case "len":
+		*lower, *upper = &bound, &bound
+	}
+}
+
+func applyExample(schema *Schema, example string) {
+	if example == "" {
+		return
+	}
+
+	switch schema.Type {
+	case "integer":
+		if value, err := strconv.Atoi(example); err == nil {
+			schema.Example = value
+		}
+	case "boolean":
+		if value, err := strconv.ParseBool(example); err == nil {
+			schema.Example = value
+		}
+	default:
+		schema.Example = example
+	}
+}
@@ -0,0 +1,59 @@
+package i18n
+
+import (
+	"github.com/biter777/countries"
+	"github.com/gofiber/fiber/v3"
+	"prod/internal/domain/entity"
+)
+
+// Supported languages
+const (
+	RU = "ru"
+	EN = "en"
+
+	Default = RU
+)
+
+// russianSpeaking is a list of countries whose users get Russian messages when no Accept-Language is sent.
+var russianSpeaking = []countries.CountryCode{
+	countries.RU,
+	countries.BY,
+	countries.KZ,
+	countries.KG,
+}
+
+// Resolve is a function that returns the language of the request.
+/*
+ * Accept-Language header has priority, then the country of the authenticated user, then Default.
+ */
+func Resolve(c fiber.Ctx) string {
+	if c.Get(fiber.HeaderAcceptLanguage) != "" {
+		if lang := c.AcceptsLanguages(RU, EN); lang != "" {
+			return lang
+		}
+	}
+
+	if user, ok := c.Locals("user").(*entity.User); ok && user != nil && user.Country != countries.Unknown {
+		for _, country := range russianSpeaking {
+			if user.Country == country {
+				return RU
+			}
+		}
+		return EN
+	}
+
+	return Default
+}
+
+// Message is a function that returns the message by key in the given language.
+func Message(lang string, key Key) string {
+	if message, ok := bundles[lang][key]; ok {
+		return message
+	}
+	return bundles[Default][key]
+}
+
+// T is a function that returns the message by key in the language of the request.
+func T(c fiber.Ctx, key Key) string {
+	return Message(Resolve(c), key)
+}
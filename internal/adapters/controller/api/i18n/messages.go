@@ -0,0 +1,107 @@
+package i18n
+
+type Key string
+
+// Message keys
+const (
+	BadRequest             Key = "bad_request"
+	InternalError          Key = "internal_error"
+	Unauthorized           Key = "unauthorized"
+	TokenExpired           Key = "token_expired"
+	AccessDenied           Key = "access_denied"
+	InsufficientRights     Key = "insufficient_rights"
+	InvalidCredentials     Key = "invalid_credentials"
+	EmailTaken             Key = "email_taken"
+	InvalidAvatarURL       Key = "invalid_avatar_url"
+	InvalidPassword        Key = "invalid_password"
+	InvalidName            Key = "invalid_name"
+	InvalidSurname         Key = "invalid_surname"
+	UserCreateFailed       Key = "user_create_failed"
+	ProfileUpdateFailed    Key = "profile_update_failed"
+	TokensFailed           Key = "tokens_failed"
+	PromoNotFound          Key = "promo_not_found"
+	PromoNotOwned          Key = "promo_not_owned"
+	CommentNotFound        Key = "comment_not_found"
+	PromoNotUnique         Key = "promo_not_unique"
+	CodeImportNotFound     Key = "code_import_not_found"
+	CodeImportNoFile       Key = "code_import_no_file"
+	CodeImportQueueFull    Key = "code_import_queue_full"
+	PromoStatusConflict    Key = "promo_status_conflict"
+	ActivationLimitReached Key = "activation_limit_reached"
+	WebhookNotFound        Key = "webhook_not_found"
+	DeliveryNotFound       Key = "delivery_not_found"
+	NotificationNotFound   Key = "notification_not_found"
+	CompanyNotFound        Key = "company_not_found"
+	CommentRejected        Key = "comment_rejected"
+	CommentLimitReached    Key = "comment_limit_reached"
+	CommentStatusConflict  Key = "comment_status_conflict"
+)
+
+var bundles = map[string]map[Key]string{
+	RU: {
+		BadRequest:             "Ошибка в данных запроса.",
+		InternalError:          "Ошибка сервера.",
+		Unauthorized:           "Пользователь не авторизован.",
+		TokenExpired:           "Время действия токена истекло.",
+		AccessDenied:           "Доступ запрещен.",
+		InsufficientRights:     "Недостаточно прав.",
+		InvalidCredentials:     "Неверный email или пароль.",
+		EmailTaken:             "Такой email уже зарегистрирован.",
+		InvalidAvatarURL:       "Некорректная ссылка на аватар.",
+		InvalidPassword:        "Некорректный пароль.",
+		InvalidName:            "Некорректное имя.",
+		InvalidSurname:         "Некорректная фамилия.",
+		UserCreateFailed:       "Ошибка при создании пользователя.",
+		ProfileUpdateFailed:    "Ошибка при обновлении профиля.",
+		TokensFailed:           "Ошибка при генерации токенов.",
+		PromoNotFound:          "Промо не найдено.",
+		PromoNotOwned:          "Промокод не принадлежит этой компании.",
+		CommentNotFound:        "Комментарий не найден.",
+		PromoNotUnique:         "Промо не в режиме уникальных промокодов.",
+		CodeImportNotFound:     "Импорт кодов не найден.",
+		CodeImportNoFile:       "Передайте CSV-файл с кодами в поле file.",
+		CodeImportQueueFull:    "Слишком много импортов кодов, попробуйте позже.",
+		PromoStatusConflict:    "Статус промо не позволяет это действие.",
+		ActivationLimitReached: "Лимит активаций исчерпан.",
+		WebhookNotFound:        "Вебхук не найден.",
+		DeliveryNotFound:       "Доставка не найдена.",
+		NotificationNotFound:   "Уведомление не найдено.",
+		CompanyNotFound:        "Компания не найдена.",
+		CommentRejected:        "Комментарий не прошел модерацию.",
+		CommentLimitReached:    "Слишком много комментариев, попробуйте позже.",
+		CommentStatusConflict:  "Статус комментария не позволяет это действие.",
+	},
+	EN: {
+		BadRequest:             "Invalid request data.",
+		InternalError:          "Internal server error.",
+		Unauthorized:           "User is not authorized.",
+		TokenExpired:           "Token has expired.",
+		AccessDenied:           "Access denied.",
+		InsufficientRights:     "Insufficient rights.",
+		InvalidCredentials:     "Invalid email or password.",
+		EmailTaken:             "This email is already registered.",
+		InvalidAvatarURL:       "Invalid avatar URL.",
+		InvalidPassword:        "Invalid password.",
+		InvalidName:            "Invalid name.",
+		InvalidSurname:         "Invalid surname.",
+		UserCreateFailed:       "Failed to create user.",
+		ProfileUpdateFailed:    "Failed to update profile.",
+		TokensFailed:           "Failed to generate auth tokens.",
+		PromoNotFound:          "Promo not found.",
+		PromoNotOwned:          "Promo does not belong to this company.",
+		CommentNotFound:        "Comment not found.",
+		PromoNotUnique:         "Promo does not use unique codes.",
+		CodeImportNotFound:     "Code import not found.",
+		CodeImportNoFile:       "Pass a CSV file with codes in the file field.",
+		CodeImportQueueFull:    "Too many code imports, try again later.",
+		PromoStatusConflict:    "The promo status does not allow this action.",
+		ActivationLimitReached: "Activation limit reached.",
+		WebhookNotFound:        "Webhook not found.",
+		DeliveryNotFound:       "Delivery not found.",
+		NotificationNotFound:   "Notification not found.",
+		CompanyNotFound:        "Company not found.",
+		CommentRejected:        "The comment did not pass moderation.",
+		CommentLimitReached:    "Too many comments, try again later.",
+		CommentStatusConflict:  "The comment status does not allow this action.",
+	},
+}
@@ -1,21 +1,66 @@
 package setup
 
 import (
+	"context"
 	"github.com/gofiber/fiber/v3/middleware/cors"
-	"github.com/gofiber/fiber/v3/middleware/logger"
+	fiberLogger "github.com/gofiber/fiber/v3/middleware/logger"
 	"github.com/spf13/viper"
 	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/docs"
 	v1 "prod/internal/adapters/controller/api/v1"
 	"prod/internal/adapters/controller/api/v1/b2b"
 	"prod/internal/adapters/controller/api/v1/b2c"
 	"prod/internal/adapters/controller/api/v1/middlewares"
+	"prod/internal/adapters/database/postgres"
+	"prod/internal/adapters/database/redis"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/service"
+	"sync"
 )
 
-func Setup(app *app.App) {
+// Worker is a background loop started by Setup, it returns when ctx is done.
+type Worker func(ctx context.Context)
+
+// Setup is a function that registers all routes, starts the background workers and checks the routes against the OpenAPI spec.
+/*
+ * The workers stop when ctx is done, the returned WaitGroup is done once all of them returned.
+ * Panics if export.pseudonym-key is not set.
+ */
+func Setup(ctx context.Context, app *app.App) *sync.WaitGroup {
+	// Ключ псевдонимов в выгрузках отдельный: с секретом токенов их можно было бы сопоставить между компаниями
+	if viper.GetString("export.pseudonym-key") == "" {
+		logger.Log.Panic("export.pseudonym-key is not set")
+	}
+
+	var wg sync.WaitGroup
+
+	for _, worker := range Routes(app) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker(ctx)
+		}()
+	}
+
+	if err := docs.CheckRoutes(app.Fiber.GetRoutes(true)); err != nil {
+		if viper.GetBool("settings.debug") {
+			logger.Log.Panic(err)
+		}
+		logger.Log.Error(err)
+	}
+
+	return &wg
+}
+
+// Routes is a function that registers all routes and event subscriptions and returns the background workers without starting them.
+func Routes(app *app.App) []Worker {
+	var workers []Worker
+
 	app.Fiber.Use(cors.New(cors.ConfigDefault))
 
 	if viper.GetBool("settings.debug") {
-		app.Fiber.Use(logger.New(logger.Config{TimeZone: viper.GetString("settings.timezone")}))
+		app.Fiber.Use(fiberLogger.New(fiberLogger.Config{TimeZone: viper.GetString("settings.timezone")}))
 	}
 
 	// Setup api v1 routes
@@ -26,19 +71,97 @@ func Setup(app *app.App) {
 	pingHandler := v1.NewPingHandler()
 	pingHandler.Setup(apiV1)
 
+	metricsHandler := v1.NewMetricsHandler()
+	metricsHandler.Setup(apiV1)
+
 	businessHandler := b2b.NewBusinessHandler(app)
 	businessHandler.Setup(apiV1)
 
 	promoHandler := b2b.NewPromoHandler(app)
 	promoHandler.Setup(apiV1, middlewareHandler.IsAuthenticated())
 
+	// Uploaded CSV files of unique codes are imported one at a time in the background
+	codeImportService := service.NewCodeImportService(postgres.NewCodeImportStorage(app.DB), postgres.NewPromoCodeStorage(app.DB), redis.NewPromoCacheStorage(app.Redis))
+	workers = append(workers, func(ctx context.Context) { codeImportService.Run(ctx, viper.GetDuration("imports.stale-after")) })
+
+	promoCodeHandler := b2b.NewPromoCodeHandler(app, codeImportService)
+	promoCodeHandler.Setup(apiV1, middlewareHandler.IsAuthenticated())
+
+	commentHandler := b2b.NewCommentHandler(app)
+	commentHandler.Setup(apiV1, middlewareHandler.IsAuthenticated())
+
+	exportHandler := b2b.NewExportHandler(app)
+	exportHandler.Setup(apiV1, middlewareHandler.IsAuthenticated())
+
+	webhookHandler := b2b.NewWebhookHandler(app)
+	webhookHandler.Setup(apiV1, middlewareHandler.IsAuthenticated())
+
+	statsHandler := b2b.NewStatsHandler(app)
+	statsHandler.Setup(apiV1, middlewareHandler.IsAuthenticated())
+
 	// Setup user routes
 	userAuthHandler := b2c.NewUserHandler(app)
 	userAuthHandler.Setup(apiV1, middlewareHandler.IsAuthenticated())
 
-	userPromoHandler := b2c.NewUserPromoHandler(app)
+	// Impressions and views are written in batches in the background
+	viewRecorder := service.NewViewRecorder(postgres.NewViewStorage(app.DB))
+	workers = append(workers, func(ctx context.Context) { viewRecorder.Run(ctx, viper.GetDuration("views.flush-interval")) })
+
+	userPromoHandler := b2c.NewUserPromoHandler(app, viewRecorder)
 	userPromoHandler.Setup(apiV1, middlewareHandler.IsAuthenticated())
 
 	userActionsHandler := b2c.NewActionsHandler(app)
 	userActionsHandler.Setup(apiV1, middlewareHandler.IsAuthenticated())
+
+	notificationHandler := b2c.NewNotificationHandler(app)
+	notificationHandler.Setup(apiV1, middlewareHandler.IsAuthenticated())
+
+	// Push live counters of promos to the clients of this instance
+	realtimeHub := service.NewRealtimeHub(postgres.NewPromoStorage(app.DB), redis.NewPromoUpdateStorage(app.Redis, viper.GetString("realtime.channel")), viper.GetInt64("realtime.max-connections"))
+	workers = append(workers, realtimeHub.Run)
+
+	realtimeHandler := b2c.NewRealtimeHandler(app, realtimeHub)
+	realtimeHandler.Setup(apiV1, middlewareHandler.IsAuthenticated())
+
+	// Keep the dashboard rollups fresh
+	statsService := service.NewStatsService(postgres.NewStatsStorage(app.DB))
+	workers = append(workers, func(ctx context.Context) { statsService.Run(ctx, viper.GetDuration("stats.refresh-interval")) })
+
+	// Send queued webhook deliveries
+	webhookService := service.NewWebhookService(postgres.NewWebhookStorage(app.DB))
+	workers = append(workers, func(ctx context.Context) { webhookService.Run(ctx, viper.GetDuration("webhooks.dispatch-interval")) })
+
+	// Publish domain events from the outbox
+	eventBus := service.NewEventBus(postgres.NewOutboxStorage(app.DB))
+	eventBus.Subscribe(dto.EventPromoActivated, webhookService.HandleEvent)
+	eventBus.Subscribe(dto.EventPromoExhausted, webhookService.HandleEvent)
+	eventBus.Subscribe(dto.EventCommentAdded, webhookService.HandleEvent)
+	eventBus.Subscribe(dto.EventLikeToggled, webhookService.HandleEvent)
+
+	// Notify users of new promos of favorite companies, replies to their comments and liked promos ending soon
+	notificationService := service.NewNotificationService(postgres.NewNotificationStorage(app.DB))
+	eventBus.Subscribe(dto.EventPromoPublished, notificationService.HandleEvent)
+	eventBus.Subscribe(dto.EventCommentAdded, notificationService.HandleEvent)
+	workers = append(workers, func(ctx context.Context) {
+		notificationService.Run(ctx, viper.GetDuration("notifications.sweep-interval"), viper.GetDuration("notifications.expiring-within"))
+	})
+
+	// Send the new counters of changed promos to the realtime streams of all instances
+	for _, eventType := range []string{dto.EventLikeToggled, dto.EventCommentAdded, dto.EventPromoActivated, dto.EventPromoExhausted} {
+		eventBus.Subscribe(eventType, realtimeHub.HandleEvent)
+	}
+
+	if viper.GetString("events.sink") == "redis" {
+		eventStream := redis.NewEventStreamStorage(app.Redis, viper.GetString("events.stream"), viper.GetInt64("events.stream-max-len"))
+		eventBus.Subscribe("*", eventStream.Publish)
+	}
+	workers = append(workers, func(ctx context.Context) {
+		eventBus.Run(ctx, viper.GetDuration("events.dispatch-interval"), viper.GetDuration("events.retention"))
+	})
+
+	// Setup OpenAPI docs
+	docsHandler := docs.NewDocsHandler()
+	docsHandler.Setup(apiV1)
+
+	return workers
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/gofiber/fiber/v3"
 	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
 	"prod/internal/adapters/controller/api/validator"
 	"prod/internal/adapters/database/postgres"
 	"prod/internal/adapters/database/redis"
@@ -48,14 +49,15 @@ func (h BusinessHandler) register(c fiber.Ctx) error {
 	if err := c.Bind().Body(&businessDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(businessDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(businessDTO, i18n.Resolve(c)); errValidate != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
@@ -63,7 +65,7 @@ func (h BusinessHandler) register(c fiber.Ctx) error {
 	if errCreate != nil {
 		return c.Status(fiber.StatusConflict).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Такой email уже зарегистрирован.",
+			Message: i18n.T(c, i18n.EmailTaken),
 		})
 	}
 
@@ -71,7 +73,7 @@ func (h BusinessHandler) register(c fiber.Ctx) error {
 	if tokensErr != nil || tokens == nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "failed to generate auth tokens",
+			Message: i18n.T(c, i18n.TokensFailed),
 		})
 	}
 
@@ -89,14 +91,15 @@ func (h BusinessHandler) login(c fiber.Ctx) error {
 	if err := c.Bind().Body(&businessDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(businessDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(businessDTO, i18n.Resolve(c)); errValidate != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
@@ -104,7 +107,7 @@ func (h BusinessHandler) login(c fiber.Ctx) error {
 	if errFetch != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Неверный email или пароль.",
+			Message: i18n.T(c, i18n.InvalidCredentials),
 		})
 	}
 
@@ -112,7 +115,7 @@ func (h BusinessHandler) login(c fiber.Ctx) error {
 	if passErr != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Неверный email или пароль.",
+			Message: i18n.T(c, i18n.InvalidCredentials),
 		})
 	}
 
@@ -120,7 +123,7 @@ func (h BusinessHandler) login(c fiber.Ctx) error {
 	if tokensErr != nil || tokens == nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "failed to generate auth tokens",
+			Message: i18n.T(c, i18n.TokensFailed),
 		})
 	}
 
@@ -0,0 +1,255 @@
+package b2b
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
+	"prod/internal/adapters/controller/api/validator"
+	"prod/internal/adapters/database/postgres"
+	"prod/internal/adapters/database/redis"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"prod/internal/domain/service"
+	"strconv"
+)
+
+type CommentService interface {
+	AddOfficialReply(ctx context.Context, companyID string, request dto.OfficialReply) (dto.Comment, error)
+	GetCompanyComments(ctx context.Context, companyID, promoID, parentID string, page dto.Page) ([]dto.Comment, string, int64, error)
+	HideComment(ctx context.Context, companyID string, request dto.ModerateComment) error
+	ApproveComment(ctx context.Context, companyID string, request dto.ModerateComment) error
+	RejectComment(ctx context.Context, companyID string, request dto.ModerateComment) error
+	GetModerationQueue(ctx context.Context, companyID string, request dto.ModerationQueueRequest, page dto.Page) ([]dto.ModerationComment, string, int64, error)
+}
+
+type CommentHandler struct {
+	commentService CommentService
+	validator      *validator.Validator
+}
+
+func NewCommentHandler(app *app.App) *CommentHandler {
+	actionsStorage := postgres.NewActionsStorage(app.DB)
+	activationStorage := postgres.NewActivationStorage(app.DB)
+	activationRedisStorage := redis.NewActivationStorage(app.Redis)
+	promoCacheStorage := redis.NewPromoCacheStorage(app.Redis)
+
+	return &CommentHandler{
+		commentService: service.NewActionsService(actionsStorage, activationStorage, activationRedisStorage, promoCacheStorage),
+		validator:      app.Validator,
+	}
+}
+
+// list is a method that returns comments of a company promo, top-level ones or replies of a thread, like the user endpoint.
+func (h CommentHandler) list(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var request dto.GetComments
+	if err := c.Bind().URI(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+	if err := c.Bind().Query(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	page, err := h.validator.GetPage(c, request.Limit, request.Offset, request.Cursor, request.WithTotal)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	comments, nextCursor, total, err := h.commentService.GetCompanyComments(c.Context(), business.ID, request.ID, request.ParentID, page)
+	if err != nil {
+		return commentError(c, err, request.ParentID != "")
+	}
+
+	if page.NeedTotal() {
+		c.Append("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+
+	if page.Keyset {
+		return c.Status(fiber.StatusOK).JSON(dto.CursorPage[dto.Comment]{Items: comments, NextCursor: nextCursor})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(comments)
+}
+
+// reply is a method that posts an official reply of the company to a comment under its promo.
+func (h CommentHandler) reply(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var request dto.OfficialReply
+	if err := c.Bind().URI(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+	if err := c.Bind().Body(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	comment, err := h.commentService.AddOfficialReply(c.Context(), business.ID, request)
+	if err != nil {
+		return commentError(c, err, true)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(comment)
+}
+
+// moderationQueue is a method that returns comments of the company's promos waiting for moderation, or hidden ones with status=hidden.
+func (h CommentHandler) moderationQueue(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var request dto.ModerationQueueRequest
+	if err := c.Bind().Query(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	page, err := h.validator.GetPage(c, request.Limit, request.Offset, request.Cursor, request.WithTotal)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	comments, nextCursor, total, err := h.commentService.GetModerationQueue(c.Context(), business.ID, request, page)
+	if err != nil {
+		return commentError(c, err, false)
+	}
+
+	if page.NeedTotal() {
+		c.Append("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+
+	if page.Keyset {
+		return c.Status(fiber.StatusOK).JSON(dto.CursorPage[dto.ModerationComment]{Items: comments, NextCursor: nextCursor})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(comments)
+}
+
+func (h CommentHandler) hide(c fiber.Ctx) error {
+	return h.moderate(c, h.commentService.HideComment)
+}
+
+func (h CommentHandler) approve(c fiber.Ctx) error {
+	return h.moderate(c, h.commentService.ApproveComment)
+}
+
+func (h CommentHandler) reject(c fiber.Ctx) error {
+	return h.moderate(c, h.commentService.RejectComment)
+}
+
+func (h CommentHandler) moderate(c fiber.Ctx, apply func(ctx context.Context, companyID string, request dto.ModerateComment) error) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var request dto.ModerateComment
+	if err := c.Bind().URI(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	if err := apply(c.Context(), business.ID, request); err != nil {
+		return commentError(c, err, true)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(dto.HTTPResponse{
+		Status: "ok",
+	})
+}
+
+// commentError is a function that maps errors of company comments to responses, NotFound is of the comment if byComment.
+func commentError(c fiber.Ctx, err error, byComment bool) error {
+	switch {
+	case errors.Is(err, errorz.Forbidden):
+		return c.Status(fiber.StatusForbidden).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.PromoNotOwned),
+		})
+	case errors.Is(err, errorz.Conflict):
+		return c.Status(fiber.StatusConflict).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.CommentStatusConflict),
+		})
+	case errors.Is(err, errorz.NotFound):
+		key := i18n.PromoNotFound
+		if byComment {
+			key = i18n.CommentNotFound
+		}
+		return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, key),
+		})
+	}
+
+	logger.Log.Error(err)
+	return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
+		Status:  "error",
+		Message: i18n.T(c, i18n.InternalError),
+	})
+}
+
+func (h CommentHandler) Setup(router fiber.Router, middleware fiber.Handler) {
+	commentGroup := router.Group("/business/promo")
+	commentGroup.Get("/:id/comments", h.list, middleware)
+	commentGroup.Post("/:id/comments/:comment_id/replies", h.reply, middleware)
+	commentGroup.Post("/:id/comments/:comment_id/hide", h.hide, middleware)
+	commentGroup.Post("/:id/comments/:comment_id/approve", h.approve, middleware)
+	commentGroup.Post("/:id/comments/:comment_id/reject", h.reject, middleware)
+
+	moderationGroup := router.Group("/business/comments")
+	moderationGroup.Get("/moderation", h.moderationQueue, middleware)
+}
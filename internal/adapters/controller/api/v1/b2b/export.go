@@ -0,0 +1,142 @@
+package b2b
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
+	"prod/internal/adapters/controller/api/validator"
+	"prod/internal/adapters/database/postgres"
+	"prod/internal/adapters/database/redis"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"prod/internal/domain/service"
+)
+
+type ExportService interface {
+	ExportPromoActivations(ctx context.Context, companyID string, request dto.PromoActivationsExportRequest) (service.Export, error)
+	ExportCompanyActivations(ctx context.Context, companyID string, request dto.CompanyActivationsExportRequest) (service.Export, error)
+}
+
+type ExportHandler struct {
+	exportService ExportService
+	validator     *validator.Validator
+}
+
+func NewExportHandler(app *app.App) *ExportHandler {
+	promoStorage := postgres.NewPromoStorage(app.DB)
+	businessStorage := postgres.NewBusinessStorage(app.DB)
+	promoCacheStorage := redis.NewPromoCacheStorage(app.Redis)
+
+	return &ExportHandler{
+		exportService: service.NewPromoService(promoStorage, businessStorage, promoCacheStorage),
+		validator:     app.Validator,
+	}
+}
+
+// promoActivations is a method that streams the activations of a promo of the company as CSV or XLSX.
+func (h ExportHandler) promoActivations(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var request dto.PromoActivationsExportRequest
+	if err := c.Bind().URI(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+	if err := c.Bind().Query(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	export, err := h.exportService.ExportPromoActivations(c.Context(), business.ID, request)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidExportRange) {
+			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.BadRequest),
+				Details: err.Error(),
+			})
+		}
+		return promoError(c, err)
+	}
+
+	return sendExport(c, export)
+}
+
+// companyActivations is a method that streams the activations of all promos of the company over a range as CSV or XLSX.
+func (h ExportHandler) companyActivations(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var request dto.CompanyActivationsExportRequest
+	if err := c.Bind().Query(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	export, err := h.exportService.ExportCompanyActivations(c.Context(), business.ID, request)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidExportRange) {
+			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.BadRequest),
+				Details: err.Error(),
+			})
+		}
+		return promoError(c, err)
+	}
+
+	return sendExport(c, export)
+}
+
+// sendExport is a function that streams the export as an attachment.
+/*
+ * The file is written after the handler returns, when the status is already sent,
+ * so an error in the middle can only be logged and cuts the file short.
+ */
+func sendExport(c fiber.Ctx, export service.Export) error {
+	c.Attachment(export.Filename)
+	c.Set(fiber.HeaderContentType, export.ContentType)
+
+	return c.Status(fiber.StatusOK).SendStreamWriter(func(w *bufio.Writer) {
+		// Контекст запроса к этому моменту уже переиспользуется fasthttp
+		if err := export.Write(context.Background(), w); err != nil {
+			logger.Log.Errorf("failed to stream %s: %v", export.Filename, err)
+			return
+		}
+		if err := w.Flush(); err != nil {
+			logger.Log.Errorf("failed to stream %s: %v", export.Filename, err)
+		}
+	})
+}
+
+func (h ExportHandler) Setup(router fiber.Router, middleware fiber.Handler) {
+	exportGroup := router.Group("/business")
+
+	exportGroup.Get("/promo/:id/activations/export", h.promoActivations, middleware)
+	exportGroup.Get("/activations/export", h.companyActivations, middleware)
+}
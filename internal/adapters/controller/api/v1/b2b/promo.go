@@ -6,24 +6,37 @@ import (
 	"github.com/biter777/countries"
 	"github.com/gofiber/fiber/v3"
 	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
 	"prod/internal/adapters/controller/api/validator"
 	"prod/internal/adapters/database/postgres"
+	"prod/internal/adapters/database/redis"
 	"prod/internal/adapters/logger"
 	"prod/internal/domain/common/errorz"
 	"prod/internal/domain/dto"
 	"prod/internal/domain/entity"
 	"prod/internal/domain/service"
+	"prod/internal/domain/utils/codegen"
+	"prod/internal/domain/utils/schedule"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type PromoService interface {
 	Create(ctx context.Context, fiberCTX fiber.Ctx, promoDTO dto.PromoCreate) (*entity.Promo, error)
 	GetByID(ctx context.Context, uuid string) (*entity.Promo, error)
-	GetWithPagination(ctx context.Context, companyId string, dto dto.PromoGetWithPagination) ([]entity.Promo, int64, error)
+	GetWithPagination(ctx context.Context, companyId string, dto dto.PromoGetWithPagination) ([]entity.Promo, string, int64, error)
 	Update(ctx context.Context, fiberCtx fiber.Ctx, dto dto.PromoUpdate, id string) (*entity.Promo, error)
+	Archive(ctx context.Context, id, companyID string) (*entity.Promo, error)
+	Unarchive(ctx context.Context, id, companyID string) (*entity.Promo, error)
+	Delete(ctx context.Context, id, companyID string) error
+	Clone(ctx context.Context, id, companyID string) (*entity.Promo, error)
+	Publish(ctx context.Context, id, companyID string) (*entity.Promo, error)
+	Pause(ctx context.Context, id, companyID string) (*entity.Promo, error)
+	Resume(ctx context.Context, id, companyID string) (*entity.Promo, error)
 	GetStats(ctx context.Context, promoID, companyID string) (dto.PromoStatsResponse, error)
+	GetAnalytics(ctx context.Context, companyID string, request dto.PromoAnalyticsRequest) (dto.PromoAnalyticsResponse, error)
 }
 
 type PromoHandler struct {
@@ -34,9 +47,10 @@ type PromoHandler struct {
 func NewPromoHandler(app *app.App) *PromoHandler {
 	promoStorage := postgres.NewPromoStorage(app.DB)
 	businessStorage := postgres.NewBusinessStorage(app.DB)
+	promoCacheStorage := redis.NewPromoCacheStorage(app.Redis)
 
 	return &PromoHandler{
-		promoService: service.NewPromoService(promoStorage, businessStorage),
+		promoService: service.NewPromoService(promoStorage, businessStorage, promoCacheStorage),
 		validator:    app.Validator,
 	}
 }
@@ -49,71 +63,79 @@ func (h PromoHandler) create(c fiber.Ctx) error {
 		logger.Log.Error(err)
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(promoDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(promoDTO, i18n.Resolve(c)); errValidate != nil {
 		logger.Log.Error(errValidate)
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
 	if len(promoDTO.Description) < 10 || len(promoDTO.Description) > 300 {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
 	if (promoDTO.Mode != "COMMON") && (promoDTO.Mode != "UNIQUE") {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if promoDTO.Mode == "UNIQUE" && (promoDTO.PromoUnique == nil || promoDTO.MaxCount != 1) {
+	if promoDTO.Mode == "UNIQUE" && ((promoDTO.PromoUnique == nil) == (promoDTO.Generator == nil) || promoDTO.MaxCount != 1) {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if (promoDTO.Mode == "COMMON" && promoDTO.PromoUnique != nil) || (promoDTO.Mode == "UNIQUE" && promoDTO.PromoCommon != "") {
+	if (promoDTO.Mode == "COMMON" && (promoDTO.PromoUnique != nil || promoDTO.Generator != nil)) || (promoDTO.Mode == "UNIQUE" && (promoDTO.PromoCommon != "" || promoDTO.Limits != nil)) {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
 	if len(promoDTO.Target.Country) > 2 {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
 	if countryCode := countries.ByName(strings.ToUpper(promoDTO.Target.Country)); countryCode == countries.Unknown && promoDTO.Target.Country != "" {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if !validTarget(promoDTO.Target) {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
 	if promoDTO.Target.AgeFrom != 0 && (promoDTO.Target.AgeUntil != 0 && promoDTO.Target.AgeUntil < promoDTO.Target.AgeFrom) {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
 	if promoDTO.MaxCount < 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
@@ -125,15 +147,23 @@ func (h PromoHandler) create(c fiber.Ctx) error {
 
 	promo, err := h.promoService.Create(c.Context(), c, promoDTO)
 	if err != nil {
+		if errors.Is(err, codegen.ErrInvalidSpec) || errors.Is(err, schedule.ErrInvalid) {
+			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.BadRequest),
+				Details: err.Error(),
+			})
+		}
 		if errors.Is(err, errorz.BadRequest) {
 			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: err.Error(),
+				Message: i18n.T(c, i18n.BadRequest),
 			})
 		} else {
+			logger.Log.Error(err)
 			return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: err.Error(),
+				Message: i18n.T(c, i18n.InternalError),
 			})
 		}
 	}
@@ -152,24 +182,27 @@ func (h PromoHandler) getWithPagination(c fiber.Ctx) error {
 	if err := c.Bind().Query(&promoRequestDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if promoRequestDTO.Limit == 0 {
-		promoRequestDTO.Limit = 10
+	page, err := h.validator.GetPage(c, promoRequestDTO.Limit, promoRequestDTO.Offset, promoRequestDTO.Cursor, promoRequestDTO.WithTotal)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
 	}
 
 	if promoRequestDTO.SortBy != "active_from" && promoRequestDTO.SortBy != "active_until" && promoRequestDTO.SortBy != "" {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
 	promoDTO := dto.PromoGetWithPagination{
-		Limit:  promoRequestDTO.Limit,
-		Offset: promoRequestDTO.Offset,
+		Page:   page,
 		SortBy: promoRequestDTO.SortBy,
 	}
 
@@ -177,11 +210,19 @@ func (h PromoHandler) getWithPagination(c fiber.Ctx) error {
 		promoDTO.Countries = append(promoDTO.Countries, countries.ByName(country))
 	}
 
-	promos, total, err := h.promoService.GetWithPagination(c.Context(), company.ID, promoDTO)
+	promos, nextCursor, total, err := h.promoService.GetWithPagination(c.Context(), company.ID, promoDTO)
 	if err != nil {
+		if errors.Is(err, errorz.BadRequest) {
+			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.BadRequest),
+			})
+		}
+
+		logger.Log.Error(err)
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: err.Error(),
+			Message: i18n.T(c, i18n.InternalError),
 		})
 	}
 
@@ -189,7 +230,8 @@ func (h PromoHandler) getWithPagination(c fiber.Ctx) error {
 
 	for _, promo := range promos {
 
-		var categories, promoUniques []string
+		var categories []string
+		included, excluded, affinities := targetLists(&promo)
 
 		for _, category := range promo.Categories {
 			if category.Name == "" {
@@ -198,13 +240,6 @@ func (h PromoHandler) getWithPagination(c fiber.Ctx) error {
 			categories = append(categories, category.Name)
 		}
 
-		for _, promoUnique := range promo.PromoUnique {
-			if promoUnique.Body == "" {
-				continue
-			}
-			promoUniques = append(promoUniques, promoUnique.Body)
-		}
-
 		ageUntil := promo.AgeUntil
 
 		if ageUntil == 1000 {
@@ -216,14 +251,17 @@ func (h PromoHandler) getWithPagination(c fiber.Ctx) error {
 			CompanyID:   promo.CompanyID,
 			CompanyName: company.Name,
 			Target: dto.Target{
-				AgeFrom:    promo.AgeFrom,
-				AgeUntil:   ageUntil,
-				Country:    promo.CountryOriginal,
-				Categories: categories,
+				AgeFrom:            promo.AgeFrom,
+				AgeUntil:           ageUntil,
+				Country:            promo.CountryOriginal,
+				Categories:         categories,
+				Countries:          included,
+				ExcludedCountries:  excluded,
+				AffinityCategories: affinities,
 			},
-			Active:      promo.Active,
-			ActiveFrom:  promo.ActiveFrom.Format("2006-01-02"),
-			ActiveUntil: promo.ActiveUntil.Format("2006-01-02"),
+			Active:      promo.IsActive(time.Now()),
+			ActiveFrom:  schedule.FormatBound(promo.ActiveFrom),
+			ActiveUntil: schedule.FormatBound(promo.ActiveUntil),
 			Description: promo.Description,
 			ImageURL:    promo.ImageURL,
 			MaxCount:    promo.MaxCount,
@@ -231,11 +269,20 @@ func (h PromoHandler) getWithPagination(c fiber.Ctx) error {
 			LikeCount:   promo.LikeCount,
 			UsedCount:   promo.UsedCount,
 			PromoCommon: promo.PromoCommon,
-			PromoUnique: promoUniques,
+			Status:      promo.EffectiveStatus(time.Now()),
+			Archived:    promo.ArchivedAt != nil,
+			Schedule:    promoSchedule(&promo),
+			Limits:      promoLimits(&promo),
 		})
 	}
 
-	c.Append("X-Total-Count", strconv.FormatInt(total, 10))
+	if page.NeedTotal() {
+		c.Append("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+
+	if page.Keyset {
+		return c.Status(fiber.StatusOK).JSON(dto.CursorPage[dto.PromoDTO]{Items: promoDTOs, NextCursor: nextCursor})
+	}
 
 	return c.Status(fiber.StatusOK).JSON(promoDTOs)
 }
@@ -248,56 +295,61 @@ func (h PromoHandler) getByID(c fiber.Ctx) error {
 	if err := c.Bind().URI(&promoIdDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(promoIdDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(promoIdDTO, i18n.Resolve(c)); errValidate != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
 	promo, err := h.promoService.GetByID(c.Context(), promoIdDTO.ID)
 
+	if err == nil && promo.DeletedAt != nil {
+		err = errorz.NotFound
+	}
+
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: err.Error(),
+			Message: i18n.T(c, i18n.PromoNotFound),
 		})
 	}
 
 	if promo.CompanyID != business.ID {
 		return c.Status(fiber.StatusUnauthorized).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Промокод не принадлежит этой компании.",
+			Message: i18n.T(c, i18n.PromoNotOwned),
 		})
 	}
 
-	var categories, promoUniques []string
+	var categories []string
+	included, excluded, affinities := targetLists(promo)
 
 	for _, category := range promo.Categories {
 		categories = append(categories, category.Name)
 	}
 
-	for _, promoUnique := range promo.PromoUnique {
-		promoUniques = append(promoUniques, promoUnique.Body)
-	}
-
 	promoDTO := dto.PromoDTO{
 		PromoID:     promo.PromoID,
 		CompanyID:   promo.CompanyID,
 		CompanyName: business.Name,
 		Target: dto.Target{
-			AgeFrom:    promo.AgeFrom,
-			AgeUntil:   promo.AgeUntil,
-			Country:    promo.CountryOriginal,
-			Categories: categories,
+			AgeFrom:            promo.AgeFrom,
+			AgeUntil:           promo.AgeUntil,
+			Country:            promo.CountryOriginal,
+			Categories:         categories,
+			Countries:          included,
+			ExcludedCountries:  excluded,
+			AffinityCategories: affinities,
 		},
-		Active:      promo.Active,
-		ActiveFrom:  promo.ActiveFrom.Format("2006-01-02"),
-		ActiveUntil: promo.ActiveUntil.Format("2006-01-02"),
+		Active:      promo.IsActive(time.Now()),
+		ActiveFrom:  schedule.FormatBound(promo.ActiveFrom),
+		ActiveUntil: schedule.FormatBound(promo.ActiveUntil),
 		Description: promo.Description,
 		ImageURL:    promo.ImageURL,
 		MaxCount:    promo.MaxCount,
@@ -305,7 +357,10 @@ func (h PromoHandler) getByID(c fiber.Ctx) error {
 		LikeCount:   promo.LikeCount,
 		UsedCount:   promo.UsedCount,
 		PromoCommon: promo.PromoCommon,
-		PromoUnique: promoUniques,
+		Status:      promo.EffectiveStatus(time.Now()),
+		Archived:    promo.ArchivedAt != nil,
+		Schedule:    promoSchedule(promo),
+		Limits:      promoLimits(promo),
 	}
 
 	if promoDTO.Target.AgeUntil == 1000 {
@@ -329,21 +384,21 @@ func (h PromoHandler) update(c fiber.Ctx) error {
 	if err := c.Bind().Body(&promoDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
 	if err := c.Bind().URI(&params); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
 	if (promoDTO.Description != nil) && (len(*promoDTO.Description) < 10 || len(*promoDTO.Description) > 300) {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
@@ -351,28 +406,35 @@ func (h PromoHandler) update(c fiber.Ctx) error {
 		if len(promoDTO.Target.Country) > 2 {
 			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Ошибка в данных запроса.",
+				Message: i18n.T(c, i18n.BadRequest),
 			})
 		}
 
 		if countryCode := countries.ByName(strings.ToUpper(promoDTO.Target.Country)); countryCode == countries.Unknown && promoDTO.Target.Country != "" {
 			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Ошибка в данных запроса.",
+				Message: i18n.T(c, i18n.BadRequest),
 			})
 		}
 
 		if (promoDTO.Target.AgeFrom != 0) && (promoDTO.Target.AgeUntil != 0) && (promoDTO.Target.AgeUntil < promoDTO.Target.AgeFrom) {
 			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Ошибка в данных запроса.",
+				Message: i18n.T(c, i18n.BadRequest),
+			})
+		}
+
+		if !validTarget(promoDTO.Target) {
+			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.BadRequest),
 			})
 		}
 
 		if slices.Contains(promoDTO.Target.Categories, "") {
 			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Ошибка в данных запроса.",
+				Message: i18n.T(c, i18n.BadRequest),
 			})
 		}
 	}
@@ -382,54 +444,62 @@ func (h PromoHandler) update(c fiber.Ctx) error {
 	if errors.Is(err, errorz.Forbidden) {
 		return c.Status(fiber.StatusForbidden).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Промокод не принадлежит этой компании.",
+			Message: i18n.T(c, i18n.PromoNotOwned),
 		})
 	}
 
 	if errors.Is(err, errorz.NotFound) {
 		return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Промокод не найден.",
+			Message: i18n.T(c, i18n.PromoNotFound),
+		})
+	}
+
+	if errors.Is(err, schedule.ErrInvalid) {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: err.Error(),
 		})
 	}
 
 	if errors.Is(err, errorz.BadRequest) {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: err.Error(),
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
 	if err != nil {
+		logger.Log.Error(err)
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: err.Error(),
+			Message: i18n.T(c, i18n.InternalError),
 		})
 	}
 
 	var categories []string
-	var promoUniques []string
+	included, excluded, affinities := targetLists(promo)
 
 	for _, category := range promo.Categories {
 		categories = append(categories, category.Name)
 	}
 
-	for _, promoUnique := range promo.PromoUnique {
-		promoUniques = append(promoUniques, promoUnique.Body)
-	}
-
 	promoReturn := dto.PromoDTO{
 		PromoID:     promo.PromoID,
 		CompanyID:   promo.CompanyID,
 		CompanyName: business.Name,
 		Target: dto.Target{
-			AgeFrom:    promo.AgeFrom,
-			AgeUntil:   promo.AgeUntil,
-			Categories: categories,
+			AgeFrom:            promo.AgeFrom,
+			AgeUntil:           promo.AgeUntil,
+			Categories:         categories,
+			Countries:          included,
+			ExcludedCountries:  excluded,
+			AffinityCategories: affinities,
 		},
-		Active:      promo.Active,
-		ActiveFrom:  promo.ActiveFrom.Format("2006-01-02"),
-		ActiveUntil: promo.ActiveUntil.Format("2006-01-02"),
+		Active:      promo.IsActive(time.Now()),
+		ActiveFrom:  schedule.FormatBound(promo.ActiveFrom),
+		ActiveUntil: schedule.FormatBound(promo.ActiveUntil),
 		Description: promo.Description,
 		ImageURL:    promo.ImageURL,
 		MaxCount:    promo.MaxCount,
@@ -437,7 +507,10 @@ func (h PromoHandler) update(c fiber.Ctx) error {
 		LikeCount:   promo.LikeCount,
 		UsedCount:   promo.UsedCount,
 		PromoCommon: promo.PromoCommon,
-		PromoUnique: promoUniques,
+		Status:      promo.EffectiveStatus(time.Now()),
+		Archived:    promo.ArchivedAt != nil,
+		Schedule:    promoSchedule(promo),
+		Limits:      promoLimits(promo),
 	}
 
 	if promo.Country != 0 {
@@ -458,21 +531,22 @@ func (h PromoHandler) stats(c fiber.Ctx) error {
 	if business == nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Пользователь не авторизован.",
+			Message: i18n.T(c, i18n.Unauthorized),
 		})
 	}
 
 	if err := c.Bind().URI(&requestDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(requestDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(requestDTO, i18n.Resolve(c)); errValidate != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
@@ -481,35 +555,335 @@ func (h PromoHandler) stats(c fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: err.Error(),
+			Message: i18n.T(c, i18n.PromoNotFound),
 		})
 	}
 
 	if promoByID.CompanyID != business.ID {
 		return c.Status(fiber.StatusForbidden).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Промокод не принадлежит этой компании.",
+			Message: i18n.T(c, i18n.PromoNotOwned),
 		})
 	}
 
 	promos, statsErr := h.promoService.GetStats(c.Context(), requestDTO.Id, business.ID)
 
 	if statsErr != nil {
-		if errors.Is(err, errorz.NotFound) {
+		if errors.Is(statsErr, errorz.NotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: err.Error(),
+				Message: i18n.T(c, i18n.PromoNotFound),
 			})
 		}
+		logger.Log.Error(statsErr)
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: err.Error(),
+			Message: i18n.T(c, i18n.InternalError),
 		})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(promos)
 }
 
+// analytics is a method that returns time-series analytics of a promo of the company.
+func (h PromoHandler) analytics(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var request dto.PromoAnalyticsRequest
+	if err := c.Bind().URI(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+	if err := c.Bind().Query(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	analytics, err := h.promoService.GetAnalytics(c.Context(), business.ID, request)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAnalyticsRange) {
+			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.BadRequest),
+				Details: err.Error(),
+			})
+		}
+		return promoError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(analytics)
+}
+
+// toPromoDTO is a function that converts a promo of the company to the B2B response.
+func toPromoDTO(promo *entity.Promo, companyName string) dto.PromoDTO {
+	var categories []string
+	included, excluded, affinities := targetLists(promo)
+
+	for _, category := range promo.Categories {
+		categories = append(categories, category.Name)
+	}
+
+	ageUntil := promo.AgeUntil
+	if ageUntil == 1000 {
+		ageUntil = 0
+	}
+
+	return dto.PromoDTO{
+		PromoID:     promo.PromoID,
+		CompanyID:   promo.CompanyID,
+		CompanyName: companyName,
+		Target: dto.Target{
+			AgeFrom:            promo.AgeFrom,
+			AgeUntil:           ageUntil,
+			Country:            promo.CountryOriginal,
+			Categories:         categories,
+			Countries:          included,
+			ExcludedCountries:  excluded,
+			AffinityCategories: affinities,
+		},
+		Active:      promo.IsActive(time.Now()),
+		ActiveFrom:  schedule.FormatBound(promo.ActiveFrom),
+		ActiveUntil: schedule.FormatBound(promo.ActiveUntil),
+		Description: promo.Description,
+		ImageURL:    promo.ImageURL,
+		MaxCount:    promo.MaxCount,
+		Mode:        promo.Mode,
+		LikeCount:   promo.LikeCount,
+		UsedCount:   promo.UsedCount,
+		PromoCommon: promo.PromoCommon,
+		Status:      promo.EffectiveStatus(time.Now()),
+		Archived:    promo.ArchivedAt != nil,
+		Schedule:    promoSchedule(promo),
+		Limits:      promoLimits(promo),
+	}
+}
+
+// targetLists is a function that returns included and excluded countries and affinity categories of the promo for the B2B response.
+func targetLists(promo *entity.Promo) (included, excluded, affinities []string) {
+	for _, country := range promo.Countries {
+		if country.Excluded {
+			excluded = append(excluded, country.Country.Alpha2())
+		} else {
+			included = append(included, country.Country.Alpha2())
+		}
+	}
+
+	for _, affinity := range promo.Affinities {
+		affinities = append(affinities, affinity.Name)
+	}
+
+	return included, excluded, affinities
+}
+
+// promoSchedule is a function that returns the promo's schedule for the B2B response.
+func promoSchedule(promo *entity.Promo) *dto.Schedule {
+	promoSchedule, err := schedule.Unmarshal(promo.Schedule)
+	if err != nil {
+		logger.Log.Errorf("invalid schedule of promo %s: %v", promo.PromoID, err)
+	}
+
+	return promoSchedule
+}
+
+// promoLimits is a function that returns the promo's activation limits for the B2B response, nil without limits.
+func promoLimits(promo *entity.Promo) *dto.PromoLimits {
+	if promo.UserLimit == 0 && promo.PeriodLimit == 0 {
+		return nil
+	}
+
+	return &dto.PromoLimits{
+		PerUser:       promo.UserLimit,
+		PerUserPeriod: promo.UserLimitPeriod,
+		PerPeriod:     promo.PeriodLimit,
+		Period:        promo.LimitPeriod,
+	}
+}
+
+// validTarget is a function that checks country lists and affinity categories of the target.
+/*
+ * Countries can't be combined with Country, a country can't be both included and excluded.
+ */
+func validTarget(target *dto.Target) bool {
+	if target.Country != "" && len(target.Countries) > 0 {
+		return false
+	}
+
+	for _, name := range append(slices.Clone(target.Countries), target.ExcludedCountries...) {
+		if countries.ByName(strings.ToUpper(name)) == countries.Unknown {
+			return false
+		}
+	}
+
+	for _, name := range target.Countries {
+		if slices.ContainsFunc(target.ExcludedCountries, func(excluded string) bool { return strings.EqualFold(excluded, name) }) {
+			return false
+		}
+	}
+
+	return !slices.Contains(target.AffinityCategories, "")
+}
+
+// promoError is a function that maps errors of actions on an owned promo to responses.
+func promoError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errorz.NotFound):
+		return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.PromoNotFound),
+		})
+	case errors.Is(err, errorz.Forbidden):
+		return c.Status(fiber.StatusForbidden).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.PromoNotOwned),
+		})
+	case errors.Is(err, errorz.Conflict):
+		return c.Status(fiber.StatusConflict).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.PromoStatusConflict),
+			Details: err.Error(),
+		})
+	}
+
+	logger.Log.Error(err)
+	return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
+		Status:  "error",
+		Message: i18n.T(c, i18n.InternalError),
+	})
+}
+
+// bindPromoID is a method that reads and validates the promo id from the path, returns the error response if it's invalid.
+func (h PromoHandler) bindPromoID(c fiber.Ctx) (string, *dto.HTTPResponse) {
+	var requestDTO dto.PromoGetByID
+
+	if err := c.Bind().URI(&requestDTO); err != nil {
+		return "", &dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		}
+	}
+
+	if errValidate := h.validator.ValidateData(requestDTO, i18n.Resolve(c)); errValidate != nil {
+		return "", &dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		}
+	}
+
+	return requestDTO.ID, nil
+}
+
+// Мягкое удаление промо
+func (h PromoHandler) delete(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	id, errResponse := h.bindPromoID(c)
+	if errResponse != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errResponse)
+	}
+
+	if err := h.promoService.Delete(c.Context(), id, business.ID); err != nil {
+		return promoError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Архивация промо
+func (h PromoHandler) archive(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	id, errResponse := h.bindPromoID(c)
+	if errResponse != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errResponse)
+	}
+
+	promo, err := h.promoService.Archive(c.Context(), id, business.ID)
+	if err != nil {
+		return promoError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(toPromoDTO(promo, business.Name))
+}
+
+// Возврат промо из архива
+func (h PromoHandler) unarchive(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	id, errResponse := h.bindPromoID(c)
+	if errResponse != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errResponse)
+	}
+
+	promo, err := h.promoService.Unarchive(c.Context(), id, business.ID)
+	if err != nil {
+		return promoError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(toPromoDTO(promo, business.Name))
+}
+
+// Копирование промо в новый черновик
+func (h PromoHandler) clone(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	id, errResponse := h.bindPromoID(c)
+	if errResponse != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errResponse)
+	}
+
+	promo, err := h.promoService.Clone(c.Context(), id, business.ID)
+	if err != nil {
+		return promoError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(toPromoDTO(promo, business.Name))
+}
+
+// Публикация черновика
+func (h PromoHandler) publish(c fiber.Ctx) error {
+	return h.changeStatus(c, h.promoService.Publish)
+}
+
+// Пауза: промо скрыто и не активируется, used_count не меняется
+func (h PromoHandler) pause(c fiber.Ctx) error {
+	return h.changeStatus(c, h.promoService.Pause)
+}
+
+// Снятие с паузы
+func (h PromoHandler) resume(c fiber.Ctx) error {
+	return h.changeStatus(c, h.promoService.Resume)
+}
+
+// changeStatus is a method that runs a status transition of the promo from the path and returns the updated promo.
+func (h PromoHandler) changeStatus(c fiber.Ctx, transition func(ctx context.Context, id, companyID string) (*entity.Promo, error)) error {
+	business := c.Locals("business").(*entity.Business)
+
+	id, errResponse := h.bindPromoID(c)
+	if errResponse != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errResponse)
+	}
+
+	promo, err := transition(c.Context(), id, business.ID)
+	if err != nil {
+		return promoError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(toPromoDTO(promo, business.Name))
+}
+
 func (h PromoHandler) Setup(router fiber.Router, middleware fiber.Handler) {
 	promoGroup := router.Group("/business")
 	promoGroup.Post("/promo", h.create, middleware)
@@ -517,4 +891,12 @@ func (h PromoHandler) Setup(router fiber.Router, middleware fiber.Handler) {
 	promoGroup.Get("/promo/:id", h.getByID, middleware)
 	promoGroup.Patch("/promo/:id", h.update, middleware)
 	promoGroup.Get("/promo/:id/stat", h.stats, middleware)
+	promoGroup.Get("/promo/:id/analytics", h.analytics, middleware)
+	promoGroup.Delete("/promo/:id", h.delete, middleware)
+	promoGroup.Post("/promo/:id/archive", h.archive, middleware)
+	promoGroup.Post("/promo/:id/unarchive", h.unarchive, middleware)
+	promoGroup.Post("/promo/:id/clone", h.clone, middleware)
+	promoGroup.Post("/promo/:id/publish", h.publish, middleware)
+	promoGroup.Post("/promo/:id/pause", h.pause, middleware)
+	promoGroup.Post("/promo/:id/resume", h.resume, middleware)
 }
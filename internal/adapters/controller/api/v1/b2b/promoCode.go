@@ -0,0 +1,306 @@
+package b2b
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"io"
+	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
+	"prod/internal/adapters/controller/api/validator"
+	"prod/internal/adapters/database/postgres"
+	"prod/internal/adapters/database/redis"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"prod/internal/domain/service"
+	"strconv"
+)
+
+type PromoCodeService interface {
+	AddCodes(ctx context.Context, companyID string, request dto.PromoCodesAdd) (dto.PromoCodesAddResponse, error)
+	GetCodes(ctx context.Context, companyID string, request dto.PromoCodesList) ([]dto.PromoCode, int64, error)
+	RevokeCodes(ctx context.Context, companyID string, request dto.PromoCodesRevoke) (dto.PromoCodesRevokeResponse, error)
+	GetLowInventory(ctx context.Context, companyID string, request dto.PromoCodesLowInventory) (dto.PromoCodesLowInventoryResponse, error)
+}
+
+type CodeImportService interface {
+	Start(ctx context.Context, companyID, promoID string, file io.Reader) (dto.PromoCodesImportResponse, error)
+	Get(ctx context.Context, companyID string, request dto.PromoCodesImportGet) (dto.PromoCodesImportResponse, error)
+}
+
+type PromoCodeHandler struct {
+	promoCodeService  PromoCodeService
+	codeImportService CodeImportService
+	validator         *validator.Validator
+}
+
+func NewPromoCodeHandler(app *app.App, codeImportService CodeImportService) *PromoCodeHandler {
+	promoCodeStorage := postgres.NewPromoCodeStorage(app.DB)
+	promoCacheStorage := redis.NewPromoCacheStorage(app.Redis)
+
+	return &PromoCodeHandler{
+		promoCodeService:  service.NewPromoCodeService(promoCodeStorage, promoCacheStorage),
+		codeImportService: codeImportService,
+		validator:         app.Validator,
+	}
+}
+
+// promoCodeError is a function that maps errors of the unique-code inventory to responses.
+func promoCodeError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errorz.NotFound):
+		return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.PromoNotFound),
+		})
+	case errors.Is(err, errorz.Forbidden):
+		return c.Status(fiber.StatusForbidden).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.PromoNotOwned),
+		})
+	case errors.Is(err, errorz.BadRequest):
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.PromoNotUnique),
+		})
+	case errors.Is(err, errorz.LimitReached):
+		return c.Status(fiber.StatusTooManyRequests).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.CodeImportQueueFull),
+		})
+	}
+
+	logger.Log.Error(err)
+	return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
+		Status:  "error",
+		Message: i18n.T(c, i18n.InternalError),
+	})
+}
+
+// Загрузка новых уникальных кодов
+func (h PromoCodeHandler) add(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var requestDTO dto.PromoCodesAdd
+	if err := c.Bind().URI(&requestDTO); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if err := c.Bind().Body(&requestDTO); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(requestDTO, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	res, err := h.promoCodeService.AddCodes(c.Context(), business.ID, requestDTO)
+	if err != nil {
+		return promoCodeError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+// Список кодов с их статусом и владельцами
+func (h PromoCodeHandler) list(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var requestDTO dto.PromoCodesList
+	if err := c.Bind().URI(&requestDTO); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if err := c.Bind().Query(&requestDTO); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(requestDTO, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	codes, total, err := h.promoCodeService.GetCodes(c.Context(), business.ID, requestDTO)
+	if err != nil {
+		return promoCodeError(c, err)
+	}
+
+	c.Append("X-Total-Count", strconv.FormatInt(total, 10))
+	return c.Status(fiber.StatusOK).JSON(codes)
+}
+
+// Отзыв неиспользованных кодов
+func (h PromoCodeHandler) revoke(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var requestDTO dto.PromoCodesRevoke
+	if err := c.Bind().URI(&requestDTO); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&requestDTO); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.BadRequest),
+			})
+		}
+	}
+
+	if errValidate := h.validator.ValidateData(requestDTO, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	res, err := h.promoCodeService.RevokeCodes(c.Context(), business.ID, requestDTO)
+	if err != nil {
+		return promoCodeError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+// Промо компании, у которых заканчиваются коды
+func (h PromoCodeHandler) lowInventory(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var requestDTO dto.PromoCodesLowInventory
+	if err := c.Bind().Query(&requestDTO); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(requestDTO, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	res, err := h.promoCodeService.GetLowInventory(c.Context(), business.ID, requestDTO)
+	if err != nil {
+		return promoCodeError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+// Фоновый импорт кодов из CSV-файла
+func (h PromoCodeHandler) importCodes(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var requestDTO dto.PromoCodesImport
+	if err := c.Bind().URI(&requestDTO); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(requestDTO, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.CodeImportNoFile),
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.Log.Error(err)
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.CodeImportNoFile),
+		})
+	}
+	defer file.Close()
+
+	res, err := h.codeImportService.Start(c.Context(), business.ID, requestDTO.ID, file)
+	if err != nil {
+		return promoCodeError(c, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(res)
+}
+
+// Прогресс импорта кодов
+func (h PromoCodeHandler) getImport(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var requestDTO dto.PromoCodesImportGet
+	if err := c.Bind().URI(&requestDTO); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(requestDTO, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	res, err := h.codeImportService.Get(c.Context(), business.ID, requestDTO)
+	if err != nil {
+		if errors.Is(err, errorz.NotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.CodeImportNotFound),
+			})
+		}
+		return promoCodeError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+func (h PromoCodeHandler) Setup(router fiber.Router, middleware fiber.Handler) {
+	promoCodeGroup := router.Group("/business/promo")
+	promoCodeGroup.Get("/codes/low-inventory", h.lowInventory, middleware)
+	promoCodeGroup.Post("/:id/codes", h.add, middleware)
+	promoCodeGroup.Get("/:id/codes", h.list, middleware)
+	promoCodeGroup.Post("/:id/codes/revoke", h.revoke, middleware)
+	promoCodeGroup.Post("/:id/codes/import", h.importCodes, middleware)
+	promoCodeGroup.Get("/:id/codes/import/:import_id", h.getImport, middleware)
+}
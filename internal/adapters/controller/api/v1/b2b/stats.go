@@ -0,0 +1,79 @@
+package b2b
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
+	"prod/internal/adapters/controller/api/validator"
+	"prod/internal/adapters/database/postgres"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"prod/internal/domain/service"
+)
+
+type StatsService interface {
+	GetCompanyStats(ctx context.Context, companyID string, request dto.CompanyStatsRequest) (dto.CompanyStatsResponse, error)
+}
+
+type StatsHandler struct {
+	statsService StatsService
+	validator    *validator.Validator
+}
+
+func NewStatsHandler(app *app.App) *StatsHandler {
+	statsStorage := postgres.NewStatsStorage(app.DB)
+
+	return &StatsHandler{
+		statsService: service.NewStatsService(statsStorage),
+		validator:    app.Validator,
+	}
+}
+
+// companyStats is a method that returns the dashboard over all promos of the company.
+func (h StatsHandler) companyStats(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var request dto.CompanyStatsRequest
+	if err := c.Bind().Query(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	stats, err := h.statsService.GetCompanyStats(c.Context(), business.ID, request)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAnalyticsRange) {
+			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.BadRequest),
+				Details: err.Error(),
+			})
+		}
+
+		logger.Log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.InternalError),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(stats)
+}
+
+func (h StatsHandler) Setup(router fiber.Router, middleware fiber.Handler) {
+	statsGroup := router.Group("/business/stats")
+
+	statsGroup.Get("", h.companyStats, middleware)
+}
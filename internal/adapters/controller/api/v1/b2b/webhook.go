@@ -0,0 +1,357 @@
+package b2b
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
+	"prod/internal/adapters/controller/api/validator"
+	"prod/internal/adapters/database/postgres"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"prod/internal/domain/service"
+	"strconv"
+	"strings"
+)
+
+type WebhookService interface {
+	Create(ctx context.Context, companyID string, request dto.WebhookCreate) (*entity.Webhook, error)
+	List(ctx context.Context, companyID string) ([]entity.Webhook, error)
+	GetByID(ctx context.Context, webhookID, companyID string) (*entity.Webhook, error)
+	Update(ctx context.Context, companyID string, request dto.WebhookUpdate) (*entity.Webhook, error)
+	RotateSecret(ctx context.Context, webhookID, companyID string) (*entity.Webhook, error)
+	Delete(ctx context.Context, webhookID, companyID string) error
+	Deliveries(ctx context.Context, companyID string, request dto.WebhookDeliveriesRequest, page dto.Page) ([]entity.WebhookDelivery, string, int64, error)
+	Redeliver(ctx context.Context, companyID string, request dto.WebhookDeliveryRedeliver) (*entity.WebhookDelivery, error)
+}
+
+type WebhookHandler struct {
+	webhookService WebhookService
+	validator      *validator.Validator
+}
+
+func NewWebhookHandler(app *app.App) *WebhookHandler {
+	webhookStorage := postgres.NewWebhookStorage(app.DB)
+
+	return &WebhookHandler{
+		webhookService: service.NewWebhookService(webhookStorage),
+		validator:      app.Validator,
+	}
+}
+
+// create is a method that registers a webhook, its secret is only shown in this response.
+func (h WebhookHandler) create(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var request dto.WebhookCreate
+	if err := c.Bind().Body(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	webhook, err := h.webhookService.Create(c.Context(), business.ID, request)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(toWebhookDTO(webhook, true))
+}
+
+func (h WebhookHandler) list(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	webhooks, err := h.webhookService.List(c.Context(), business.ID)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	res := make([]dto.WebhookDTO, 0, len(webhooks))
+	for i := range webhooks {
+		res = append(res, toWebhookDTO(&webhooks[i], false))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+func (h WebhookHandler) getByID(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	id, errResponse := h.bindWebhookID(c)
+	if errResponse != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errResponse)
+	}
+
+	webhook, err := h.webhookService.GetByID(c.Context(), id, business.ID)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(toWebhookDTO(webhook, false))
+}
+
+func (h WebhookHandler) update(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var request dto.WebhookUpdate
+	if err := c.Bind().Body(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+	if err := c.Bind().URI(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	webhook, err := h.webhookService.Update(c.Context(), business.ID, request)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(toWebhookDTO(webhook, false))
+}
+
+// rotateSecret is a method that issues a new secret of a webhook, it is only shown in this response.
+func (h WebhookHandler) rotateSecret(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	id, errResponse := h.bindWebhookID(c)
+	if errResponse != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errResponse)
+	}
+
+	webhook, err := h.webhookService.RotateSecret(c.Context(), id, business.ID)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(toWebhookDTO(webhook, true))
+}
+
+func (h WebhookHandler) delete(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	id, errResponse := h.bindWebhookID(c)
+	if errResponse != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errResponse)
+	}
+
+	if err := h.webhookService.Delete(c.Context(), id, business.ID); err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// deliveries is a method that returns the delivery log of a webhook, status=dead is the dead-letter list.
+func (h WebhookHandler) deliveries(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var request dto.WebhookDeliveriesRequest
+	if err := c.Bind().URI(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+	if err := c.Bind().Query(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	page, err := h.validator.GetPage(c, request.Limit, request.Offset, request.Cursor, request.WithTotal)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	deliveries, nextCursor, total, err := h.webhookService.Deliveries(c.Context(), business.ID, request, page)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	res := make([]dto.WebhookDeliveryDTO, 0, len(deliveries))
+	for i := range deliveries {
+		res = append(res, toWebhookDeliveryDTO(&deliveries[i]))
+	}
+
+	if page.NeedTotal() {
+		c.Append("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+
+	if page.Keyset {
+		return c.Status(fiber.StatusOK).JSON(dto.CursorPage[dto.WebhookDeliveryDTO]{Items: res, NextCursor: nextCursor})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+// redeliver is a method that queues a logged delivery again with a fresh set of attempts.
+func (h WebhookHandler) redeliver(c fiber.Ctx) error {
+	business := c.Locals("business").(*entity.Business)
+
+	var request dto.WebhookDeliveryRedeliver
+	if err := c.Bind().URI(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	delivery, err := h.webhookService.Redeliver(c.Context(), business.ID, request)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(toWebhookDeliveryDTO(delivery))
+}
+
+// bindWebhookID is a method that reads and validates the webhook id from the path, returns the error response if it's invalid.
+func (h WebhookHandler) bindWebhookID(c fiber.Ctx) (string, *dto.HTTPResponse) {
+	var request dto.WebhookGetByID
+
+	if err := c.Bind().URI(&request); err != nil {
+		return "", &dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		}
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return "", &dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		}
+	}
+
+	return request.ID, nil
+}
+
+// webhookError is a function that writes the response for an error of the webhook service.
+/*
+ * NotFound of a delivery is told apart by the route: only redeliver looks deliveries up.
+ */
+func webhookError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidWebhookURL):
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: err.Error(),
+		})
+	case errors.Is(err, errorz.NotFound):
+		key := i18n.WebhookNotFound
+		if c.Params("delivery_id") != "" {
+			key = i18n.DeliveryNotFound
+		}
+		return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, key),
+		})
+	}
+
+	logger.Log.Error(err)
+	return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
+		Status:  "error",
+		Message: i18n.T(c, i18n.InternalError),
+	})
+}
+
+// toWebhookDTO is a function that converts a webhook to the response, the secret is only included if withSecret.
+func toWebhookDTO(webhook *entity.Webhook, withSecret bool) dto.WebhookDTO {
+	res := dto.WebhookDTO{
+		ID:        webhook.WebhookID,
+		URL:       webhook.URL,
+		Events:    strings.Split(webhook.Events, ","),
+		Active:    webhook.Active,
+		CreatedAt: webhook.CreatedAt,
+	}
+	if withSecret {
+		res.Secret = webhook.Secret
+	}
+
+	return res
+}
+
+// toWebhookDeliveryDTO is a function that converts a logged delivery to the response.
+func toWebhookDeliveryDTO(delivery *entity.WebhookDelivery) dto.WebhookDeliveryDTO {
+	res := dto.WebhookDeliveryDTO{
+		ID:            delivery.DeliveryID,
+		EventID:       delivery.EventID,
+		Event:         delivery.Event,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		CreatedAt:     delivery.CreatedAt,
+		LastAttemptAt: delivery.LastAttemptAt,
+		LastStatus:    delivery.LastStatus,
+		LastError:     delivery.LastError,
+		DeliveredAt:   delivery.DeliveredAt,
+	}
+	if delivery.Status == "pending" {
+		res.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if err := json.Unmarshal([]byte(delivery.Payload), &res.Payload); err != nil {
+		logger.Log.Errorf("invalid payload of webhook delivery %s: %v", delivery.DeliveryID, err)
+	}
+
+	return res
+}
+
+func (h WebhookHandler) Setup(router fiber.Router, middleware fiber.Handler) {
+	webhookGroup := router.Group("/business/webhooks")
+
+	webhookGroup.Post("", h.create, middleware)
+	webhookGroup.Get("", h.list, middleware)
+	webhookGroup.Get("/:id", h.getByID, middleware)
+	webhookGroup.Patch("/:id", h.update, middleware)
+	webhookGroup.Delete("/:id", h.delete, middleware)
+	webhookGroup.Post("/:id/rotate-secret", h.rotateSecret, middleware)
+	webhookGroup.Get("/:id/deliveries", h.deliveries, middleware)
+	webhookGroup.Post("/:id/deliveries/:delivery_id/redeliver", h.redeliver, middleware)
+}
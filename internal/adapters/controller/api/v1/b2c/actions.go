@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"github.com/gofiber/fiber/v3"
+	"math"
 	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
 	"prod/internal/adapters/controller/api/validator"
 	"prod/internal/adapters/database/postgres"
 	"prod/internal/adapters/database/redis"
@@ -14,16 +16,18 @@ import (
 	"prod/internal/domain/entity"
 	"prod/internal/domain/service"
 	"strconv"
+	"time"
 )
 
 type ActionsService interface {
 	AddLike(ctx context.Context, userID, promoID string) error
 	DeleteLike(ctx context.Context, userID, promoID string) error
-	AddComment(ctx context.Context, userID, promoID, text string) (string, error)
-	GetComments(ctx context.Context, promoID string, limit, offset int) ([]dto.Comment, int64, error)
+	AddComment(ctx context.Context, userID, promoID string, parentID *string, text string) (dto.Comment, error)
+	GetComments(ctx context.Context, promoID, parentID string, page dto.Page) ([]dto.Comment, string, int64, error)
 	GetCommentById(ctx context.Context, commentID, promoID string) (dto.Comment, error)
 	UpdateComment(ctx context.Context, promoID, commentID, userID, text string) (dto.Comment, error)
 	DeleteComment(ctx context.Context, promoID, commentID, userID string) error
+	ReportComment(ctx context.Context, userID string, request dto.ReportComment) error
 	Activate(ctx context.Context, user *entity.User, promoID string) (string, error)
 }
 
@@ -36,9 +40,10 @@ func NewActionsHandler(app *app.App) *ActionsHandler {
 	actionsStorage := postgres.NewActionsStorage(app.DB)
 	activationStorage := postgres.NewActivationStorage(app.DB)
 	activationRedisStorage := redis.NewActivationStorage(app.Redis)
+	promoCacheStorage := redis.NewPromoCacheStorage(app.Redis)
 
 	return &ActionsHandler{
-		actionsService: service.NewActionsService(actionsStorage, activationStorage, activationRedisStorage),
+		actionsService: service.NewActionsService(actionsStorage, activationStorage, activationRedisStorage, promoCacheStorage),
 		validator:      app.Validator,
 	}
 }
@@ -50,14 +55,15 @@ func (h ActionsHandler) addLike(c fiber.Ctx) error {
 	if err := c.Bind().URI(&likeDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(likeDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(likeDTO, i18n.Resolve(c)); errValidate != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
@@ -66,7 +72,7 @@ func (h ActionsHandler) addLike(c fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка сервера.",
+			Message: i18n.T(c, i18n.InternalError),
 		})
 	}
 
@@ -82,14 +88,15 @@ func (h ActionsHandler) deleteLike(c fiber.Ctx) error {
 	if err := c.Bind().URI(&likeDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(likeDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(likeDTO, i18n.Resolve(c)); errValidate != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
@@ -98,7 +105,7 @@ func (h ActionsHandler) deleteLike(c fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка сервера.",
+			Message: i18n.T(c, i18n.InternalError),
 		})
 	}
 
@@ -114,39 +121,56 @@ func (h ActionsHandler) addComment(c fiber.Ctx) error {
 	if err := c.Bind().URI(&commentDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
 	if err := c.Bind().Body(&commentDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(commentDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(commentDTO, i18n.Resolve(c)); errValidate != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
-	id, err := h.actionsService.AddComment(c.Context(), user.ID, commentDTO.PromoID, commentDTO.Text)
+	comment, err := h.actionsService.AddComment(c.Context(), user.ID, commentDTO.PromoID, commentDTO.ParentID, commentDTO.Text)
 
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
+	var limitErr *errorz.LimitError
+	if errors.As(err, &limitErr) {
+		if limitErr.RetryAt != nil {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(time.Until(*limitErr.RetryAt).Seconds()))))
+		}
+		return c.Status(fiber.StatusTooManyRequests).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.CommentLimitReached),
+		})
+	}
+
+	if errors.Is(err, errorz.CommentRejected) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка сервера.",
+			Message: i18n.T(c, i18n.CommentRejected),
 		})
 	}
 
-	comment, errGet := h.actionsService.GetCommentById(c.Context(), id, commentDTO.PromoID)
+	if errors.Is(err, errorz.NotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.CommentNotFound),
+		})
+	}
 
-	if errGet != nil {
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка сервера.",
+			Message: i18n.T(c, i18n.InternalError),
 		})
 	}
 
@@ -159,38 +183,56 @@ func (h ActionsHandler) getComments(ctx fiber.Ctx) error {
 	if err := ctx.Bind().URI(&getCommentsDTO); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(ctx, i18n.BadRequest),
 		})
 	}
 
 	if err := ctx.Bind().Query(&getCommentsDTO); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(ctx, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(getCommentsDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(getCommentsDTO, i18n.Resolve(ctx)); errValidate != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(ctx, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
-	if getCommentsDTO.Limit == 0 {
-		getCommentsDTO.Limit = 10
+	page, err := h.validator.GetPage(ctx, getCommentsDTO.Limit, getCommentsDTO.Offset, getCommentsDTO.Cursor, getCommentsDTO.WithTotal)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(ctx, i18n.BadRequest),
+		})
 	}
 
-	comments, total, err := h.actionsService.GetComments(ctx.Context(), getCommentsDTO.ID, getCommentsDTO.Limit, getCommentsDTO.Offset)
+	comments, nextCursor, total, err := h.actionsService.GetComments(ctx.Context(), getCommentsDTO.ID, getCommentsDTO.ParentID, page)
+
+	if errors.Is(err, errorz.NotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(ctx, i18n.CommentNotFound),
+		})
+	}
 
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка сервера.",
+			Message: i18n.T(ctx, i18n.InternalError),
 		})
 	}
 
-	ctx.Append("X-Total-Count", strconv.FormatInt(total, 10))
+	if page.NeedTotal() {
+		ctx.Append("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+
+	if page.Keyset {
+		return ctx.Status(fiber.StatusOK).JSON(dto.CursorPage[dto.Comment]{Items: comments, NextCursor: nextCursor})
+	}
 
 	return ctx.Status(fiber.StatusOK).JSON(comments)
 }
@@ -201,14 +243,15 @@ func (h ActionsHandler) getCommentById(ctx fiber.Ctx) error {
 	if err := ctx.Bind().URI(&getCommentDTO); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(ctx, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(getCommentDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(getCommentDTO, i18n.Resolve(ctx)); errValidate != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(ctx, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
@@ -218,12 +261,12 @@ func (h ActionsHandler) getCommentById(ctx fiber.Ctx) error {
 		if errors.Is(err, errorz.NotFound) {
 			return ctx.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Комментарий не найден.",
+				Message: i18n.T(ctx, i18n.CommentNotFound),
 			})
 		} else {
 			return ctx.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Ошибка сервера.",
+				Message: i18n.T(ctx, i18n.InternalError),
 			})
 		}
 	}
@@ -238,41 +281,47 @@ func (h ActionsHandler) updateComment(c fiber.Ctx) error {
 	if err := c.Bind().URI(&commentDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
 	if err := c.Bind().Body(&commentDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(commentDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(commentDTO, i18n.Resolve(c)); errValidate != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
 	comment, err := h.actionsService.UpdateComment(c.Context(), commentDTO.ID, commentDTO.CommentID, user.ID, commentDTO.Text)
 
 	if err != nil {
-		if errors.Is(err, errorz.Forbidden) {
+		if errors.Is(err, errorz.CommentRejected) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.CommentRejected),
+			})
+		} else if errors.Is(err, errorz.Forbidden) {
 			return c.Status(fiber.StatusForbidden).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Недостаточно прав.",
+				Message: i18n.T(c, i18n.InsufficientRights),
 			})
 		} else if errors.Is(err, errorz.NotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Комментарий не найден.",
+				Message: i18n.T(c, i18n.CommentNotFound),
 			})
 		} else {
 			return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Ошибка сервера.",
+				Message: i18n.T(c, i18n.InternalError),
 			})
 		}
 	}
@@ -287,14 +336,15 @@ func (h ActionsHandler) deleteComment(c fiber.Ctx) error {
 	if err := c.Bind().URI(&commentDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(commentDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(commentDTO, i18n.Resolve(c)); errValidate != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
@@ -304,17 +354,17 @@ func (h ActionsHandler) deleteComment(c fiber.Ctx) error {
 		if errors.Is(err, errorz.Forbidden) {
 			return c.Status(fiber.StatusForbidden).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Недостаточно прав.",
+				Message: i18n.T(c, i18n.InsufficientRights),
 			})
 		} else if errors.Is(err, errorz.NotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Комментарий не найден.",
+				Message: i18n.T(c, i18n.CommentNotFound),
 			})
 		} else {
 			return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Ошибка сервера.",
+				Message: i18n.T(c, i18n.InternalError),
 			})
 		}
 	}
@@ -331,36 +381,48 @@ func (h ActionsHandler) activate(c fiber.Ctx) error {
 	if err := c.Bind().URI(&activateDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if err := h.validator.ValidateData(activateDTO); err != nil {
+	if err := h.validator.ValidateData(activateDTO, i18n.Resolve(c)); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: err.Message,
 		})
 	}
 
 	promo, err := h.actionsService.Activate(c.Context(), user, activateDTO.ID)
 
 	if err != nil {
-		if errors.Is(err, errorz.Forbidden) {
+		var limitErr *errorz.LimitError
+		if errors.As(err, &limitErr) {
+			if limitErr.RetryAt != nil {
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(time.Until(*limitErr.RetryAt).Seconds()))))
+			}
+			return c.Status(fiber.StatusTooManyRequests).JSON(dto.ActivationLimitResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.ActivationLimitReached),
+				Scope:   limitErr.Scope,
+				RetryAt: limitErr.RetryAt,
+			})
+		} else if errors.Is(err, errorz.Forbidden) {
 			return c.Status(fiber.StatusForbidden).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Доступ запрещен.",
+				Message: i18n.T(c, i18n.AccessDenied),
 			})
 		} else if errors.Is(err, errorz.NotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Промо не найдено.",
+				Message: i18n.T(c, i18n.PromoNotFound),
 			})
 
 		} else {
 			logger.Log.Error(err.Error())
 			return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Ошибка сервера.",
+				Message: i18n.T(c, i18n.InternalError),
 			})
 		}
 	}
@@ -368,6 +430,53 @@ func (h ActionsHandler) activate(c fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(dto.ActivateResponse{Promo: promo})
 }
 
+// reportComment is a method that reports a comment of another user as spam, abuse or other, a repeated report is ignored.
+func (h ActionsHandler) reportComment(c fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+	var reportDTO dto.ReportComment
+
+	if err := c.Bind().URI(&reportDTO); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if err := c.Bind().Body(&reportDTO); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(reportDTO, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	if err := h.actionsService.ReportComment(c.Context(), user.ID, reportDTO); err != nil {
+		if errors.Is(err, errorz.NotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.CommentNotFound),
+			})
+		}
+
+		logger.Log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.InternalError),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(dto.HTTPResponse{
+		Status: "ok",
+	})
+}
+
 func (h ActionsHandler) Setup(router fiber.Router, middleware fiber.Handler) {
 	actionsGroup := router.Group("/user/promo")
 
@@ -378,5 +487,6 @@ func (h ActionsHandler) Setup(router fiber.Router, middleware fiber.Handler) {
 	actionsGroup.Get("/:id/comments/:comment_id", h.getCommentById, middleware)
 	actionsGroup.Put("/:id/comments/:comment_id", h.updateComment, middleware)
 	actionsGroup.Delete("/:id/comments/:comment_id", h.deleteComment, middleware)
+	actionsGroup.Post("/:id/comments/:comment_id/report", h.reportComment, middleware)
 	actionsGroup.Post("/:id/activate", h.activate, middleware)
 }
@@ -0,0 +1,235 @@
+package b2c
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
+	"prod/internal/adapters/controller/api/validator"
+	"prod/internal/adapters/database/postgres"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"prod/internal/domain/service"
+	"strconv"
+)
+
+type NotificationService interface {
+	List(ctx context.Context, userID string, unread bool, page dto.Page) ([]dto.NotificationDTO, string, int64, error)
+	UnreadCount(ctx context.Context, userID string) (int64, error)
+	MarkRead(ctx context.Context, userID, notificationID string) error
+	MarkAllRead(ctx context.Context, userID string) (int64, error)
+	GetPreferences(ctx context.Context, userID string) (dto.NotificationPreferences, error)
+	UpdatePreferences(ctx context.Context, userID string, request dto.NotificationPreferencesUpdate) (dto.NotificationPreferences, error)
+	AddFavoriteCompany(ctx context.Context, userID, companyID string) error
+	DeleteFavoriteCompany(ctx context.Context, userID, companyID string) error
+}
+
+type NotificationHandler struct {
+	notificationService NotificationService
+	validator           *validator.Validator
+}
+
+func NewNotificationHandler(app *app.App) *NotificationHandler {
+	notificationStorage := postgres.NewNotificationStorage(app.DB)
+
+	return &NotificationHandler{
+		notificationService: service.NewNotificationService(notificationStorage),
+		validator:           app.Validator,
+	}
+}
+
+// list is a method that returns a page of the user's notifications, the unread count is in X-Unread-Count.
+func (h NotificationHandler) list(c fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	var request dto.NotificationsRequest
+	if err := c.Bind().Query(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	page, err := h.validator.GetPage(c, request.Limit, request.Offset, request.Cursor, request.WithTotal)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	notifications, nextCursor, total, err := h.notificationService.List(c.Context(), user.ID, request.Unread, page)
+	if err != nil {
+		return notificationError(c, err)
+	}
+
+	unread, err := h.notificationService.UnreadCount(c.Context(), user.ID)
+	if err != nil {
+		return notificationError(c, err)
+	}
+	c.Append("X-Unread-Count", strconv.FormatInt(unread, 10))
+
+	if page.NeedTotal() {
+		c.Append("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+
+	if page.Keyset {
+		return c.Status(fiber.StatusOK).JSON(dto.CursorPage[dto.NotificationDTO]{Items: notifications, NextCursor: nextCursor})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(notifications)
+}
+
+func (h NotificationHandler) unreadCount(c fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	unread, err := h.notificationService.UnreadCount(c.Context(), user.ID)
+	if err != nil {
+		return notificationError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(dto.NotificationUnreadCount{Unread: unread})
+}
+
+func (h NotificationHandler) markRead(c fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	var request dto.NotificationRead
+	if err := c.Bind().URI(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	if err := h.notificationService.MarkRead(c.Context(), user.ID, request.ID); err != nil {
+		return notificationError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(dto.HTTPResponse{
+		Status: "ok",
+	})
+}
+
+func (h NotificationHandler) markAllRead(c fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	updated, err := h.notificationService.MarkAllRead(c.Context(), user.ID)
+	if err != nil {
+		return notificationError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(dto.NotificationsReadAll{Updated: updated})
+}
+
+func (h NotificationHandler) getPreferences(c fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	preferences, err := h.notificationService.GetPreferences(c.Context(), user.ID)
+	if err != nil {
+		return notificationError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(preferences)
+}
+
+func (h NotificationHandler) updatePreferences(c fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	var request dto.NotificationPreferencesUpdate
+	if err := c.Bind().Body(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	preferences, err := h.notificationService.UpdatePreferences(c.Context(), user.ID, request)
+	if err != nil {
+		return notificationError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(preferences)
+}
+
+func (h NotificationHandler) addFavoriteCompany(c fiber.Ctx) error {
+	return h.favoriteCompany(c, h.notificationService.AddFavoriteCompany)
+}
+
+func (h NotificationHandler) deleteFavoriteCompany(c fiber.Ctx) error {
+	return h.favoriteCompany(c, h.notificationService.DeleteFavoriteCompany)
+}
+
+func (h NotificationHandler) favoriteCompany(c fiber.Ctx, apply func(ctx context.Context, userID, companyID string) error) error {
+	user := c.Locals("user").(*entity.User)
+
+	var request dto.FavoriteCompanyRequest
+	if err := c.Bind().URI(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	if err := apply(c.Context(), user.ID, request.ID); err != nil {
+		if errors.Is(err, errorz.NotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.CompanyNotFound),
+			})
+		}
+		return notificationError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(dto.HTTPResponse{
+		Status: "ok",
+	})
+}
+
+func notificationError(c fiber.Ctx, err error) error {
+	if errors.Is(err, errorz.NotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.NotificationNotFound),
+		})
+	}
+
+	logger.Log.Error(err)
+	return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
+		Status:  "error",
+		Message: i18n.T(c, i18n.InternalError),
+	})
+}
+
+func (h NotificationHandler) Setup(router fiber.Router, middleware fiber.Handler) {
+	notificationGroup := router.Group("/user/notifications")
+	notificationGroup.Get("", h.list, middleware)
+	notificationGroup.Get("/unread-count", h.unreadCount, middleware)
+	notificationGroup.Post("/read-all", h.markAllRead, middleware)
+	notificationGroup.Get("/preferences", h.getPreferences, middleware)
+	notificationGroup.Patch("/preferences", h.updatePreferences, middleware)
+	notificationGroup.Post("/:id/read", h.markRead, middleware)
+
+	companyGroup := router.Group("/user/companies")
+	companyGroup.Post("/:id/favorite", h.addFavoriteCompany, middleware)
+	companyGroup.Delete("/:id/favorite", h.deleteFavoriteCompany, middleware)
+}
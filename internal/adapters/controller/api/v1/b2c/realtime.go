@@ -0,0 +1,151 @@
+package b2c
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
+	"prod/internal/adapters/controller/api/validator"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"prod/internal/domain/service"
+	"slices"
+	"strings"
+	"time"
+)
+
+// realtimeHeartbeat is how often an idle stream gets a comment, so proxies keep it open and gone clients are noticed.
+const realtimeHeartbeat = 15 * time.Second
+
+type RealtimeHub interface {
+	Subscribe(ctx context.Context, user *entity.User, promoIDs []string) (*service.RealtimeClient, error)
+	Unsubscribe(client *service.RealtimeClient)
+}
+
+type RealtimeHandler struct {
+	hub       RealtimeHub
+	validator *validator.Validator
+}
+
+func NewRealtimeHandler(app *app.App, hub RealtimeHub) *RealtimeHandler {
+	return &RealtimeHandler{
+		hub:       hub,
+		validator: app.Validator,
+	}
+}
+
+// updates is a method that streams updates of the requested promos as server-sent events.
+/*
+ * Promos the user can't see in the feed are left out, 404 if none of them is visible.
+ * Each update is an "update" event with dto.PromoLiveUpdate as data. A client that falls behind gets an "overflow"
+ * event and the stream ends, it should reconnect and reload the promos it shows.
+ */
+func (h RealtimeHandler) updates(c fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	var request dto.PromoLiveUpdatesRequest
+	if err := c.Bind().Query(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(request, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	var promoIDs []string
+	for _, promoID := range strings.Split(request.PromoIDs, ",") {
+		promoID = strings.TrimSpace(promoID)
+		if err := uuid.Validate(promoID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.BadRequest),
+				Details: fmt.Sprintf("invalid promo id %q", promoID),
+			})
+		}
+		if !slices.Contains(promoIDs, promoID) {
+			promoIDs = append(promoIDs, promoID)
+		}
+	}
+	if len(promoIDs) > service.RealtimeMaxPromos {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: fmt.Sprintf("at most %d promos per connection", service.RealtimeMaxPromos),
+		})
+	}
+
+	client, err := h.hub.Subscribe(c.Context(), user, promoIDs)
+	if err != nil {
+		if errors.Is(err, errorz.NotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.PromoNotFound),
+			})
+		}
+
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, service.ErrTooManyConnections) {
+			status = fiber.StatusServiceUnavailable
+		} else {
+			logger.Log.Error(err)
+		}
+		return c.Status(status).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.InternalError),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	return c.Status(fiber.StatusOK).SendStreamWriter(func(w *bufio.Writer) {
+		defer h.hub.Unsubscribe(client)
+
+		heartbeat := time.NewTicker(realtimeHeartbeat)
+		defer heartbeat.Stop()
+
+		// Ошибка записи значит, что клиент отключился
+		if _, err := w.WriteString("retry: 3000\n\n"); err != nil || w.Flush() != nil {
+			return
+		}
+
+		for {
+			select {
+			case update := <-client.Updates():
+				body, _ := json.Marshal(update)
+				if _, err := fmt.Fprintf(w, "event: update\ndata: %s\n\n", body); err != nil || w.Flush() != nil {
+					return
+				}
+			case <-client.Dropped():
+				_, _ = w.WriteString("event: overflow\ndata: {}\n\n")
+				_ = w.Flush()
+				return
+			case <-heartbeat.C:
+				if _, err := w.WriteString(": ping\n\n"); err != nil || w.Flush() != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+func (h RealtimeHandler) Setup(router fiber.Router, middleware fiber.Handler) {
+	realtimeGroup := router.Group("/user")
+	realtimeGroup.Get("/updates", h.updates, middleware)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/biter777/countries"
 	"github.com/gofiber/fiber/v3"
 	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
 	"prod/internal/adapters/controller/api/validator"
 	"prod/internal/adapters/database/postgres"
 	"prod/internal/adapters/database/redis"
@@ -54,29 +55,30 @@ func (h UserHandler) register(c fiber.Ctx) error {
 		logger.Log.Error(err)
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(userDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(userDTO, i18n.Resolve(c)); errValidate != nil {
 		logger.Log.Error(errValidate)
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
 	if userDTO.AvatarURL != nil && *userDTO.AvatarURL == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Некорректная ссылка на аватар.",
+			Message: i18n.T(c, i18n.InvalidAvatarURL),
 		})
 	}
 
 	if countryCode := countries.ByName(strings.ToUpper(userDTO.Other.Country)); countryCode == countries.Unknown {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
@@ -84,7 +86,7 @@ func (h UserHandler) register(c fiber.Ctx) error {
 	if errors.Is(errCreate, errorz.EmailTaken) {
 		return c.Status(fiber.StatusConflict).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Такой email уже зарегистрирован.",
+			Message: i18n.T(c, i18n.EmailTaken),
 		})
 	}
 
@@ -92,7 +94,7 @@ func (h UserHandler) register(c fiber.Ctx) error {
 	if errCreate != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка при создании пользователя.",
+			Message: i18n.T(c, i18n.UserCreateFailed),
 		})
 	}
 
@@ -100,7 +102,7 @@ func (h UserHandler) register(c fiber.Ctx) error {
 	if tokensErr != nil || tokens == nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка при генерации токенов.",
+			Message: i18n.T(c, i18n.TokensFailed),
 		})
 	}
 
@@ -117,14 +119,15 @@ func (h UserHandler) login(c fiber.Ctx) error {
 	if err := c.Bind().Body(&userDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(userDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(userDTO, i18n.Resolve(c)); errValidate != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
@@ -132,7 +135,7 @@ func (h UserHandler) login(c fiber.Ctx) error {
 	if errFetch != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Неверный email или пароль.",
+			Message: i18n.T(c, i18n.InvalidCredentials),
 		})
 	}
 
@@ -140,7 +143,7 @@ func (h UserHandler) login(c fiber.Ctx) error {
 	if passErr != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Неверный email или пароль.",
+			Message: i18n.T(c, i18n.InvalidCredentials),
 		})
 	}
 
@@ -148,7 +151,7 @@ func (h UserHandler) login(c fiber.Ctx) error {
 	if tokensErr != nil || tokens == nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "failed to generate auth tokens",
+			Message: i18n.T(c, i18n.TokensFailed),
 		})
 	}
 
@@ -183,42 +186,43 @@ func (h UserHandler) updateProfile(c fiber.Ctx) error {
 	if err := c.Bind().Body(&userDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(userDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(userDTO, i18n.Resolve(c)); errValidate != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
 	if userDTO.AvatarURL != nil && *userDTO.AvatarURL == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Некорректная ссылка на аватар.",
+			Message: i18n.T(c, i18n.InvalidAvatarURL),
 		})
 	}
 
 	if userDTO.Password != nil && *userDTO.Password == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Некорректный пароль.",
+			Message: i18n.T(c, i18n.InvalidPassword),
 		})
 	}
 
 	if userDTO.Name != nil && *userDTO.Name == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Некорректная ссылка на аватар.",
+			Message: i18n.T(c, i18n.InvalidName),
 		})
 	}
 
 	if userDTO.Surname != nil && *userDTO.Surname == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Некорректная ссылка на аватар.",
+			Message: i18n.T(c, i18n.InvalidSurname),
 		})
 	}
 
@@ -242,7 +246,7 @@ func (h UserHandler) updateProfile(c fiber.Ctx) error {
 	if errUpdate != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка при обновлении профиля.",
+			Message: i18n.T(c, i18n.ProfileUpdateFailed),
 		})
 	}
 
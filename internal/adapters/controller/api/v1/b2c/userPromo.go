@@ -5,8 +5,11 @@ import (
 	"errors"
 	"github.com/gofiber/fiber/v3"
 	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
 	"prod/internal/adapters/controller/api/validator"
 	"prod/internal/adapters/database/postgres"
+	"prod/internal/adapters/database/redis"
+	"prod/internal/adapters/logger"
 	"prod/internal/domain/common/errorz"
 	"prod/internal/domain/dto"
 	"prod/internal/domain/entity"
@@ -15,22 +18,30 @@ import (
 )
 
 type PromoService interface {
-	GetFeed(ctx context.Context, user *entity.User, dto dto.PromoFeedRequest) ([]dto.PromoForUser, int64, error)
-	GetByIdUser(ctx context.Context, promoID, userID string) (dto.PromoForUser, error)
-	GetHistory(ctx context.Context, userID string, limit, offset int) ([]dto.PromoForUser, int64, error)
+	GetFeed(ctx context.Context, user *entity.User, dto dto.PromoFeedRequest, page dto.Page) ([]dto.PromoForUser, string, int64, error)
+	GetByIdUser(ctx context.Context, promoID string, user *entity.User) (dto.PromoForUser, error)
+	GetHistory(ctx context.Context, userID string, page dto.Page) ([]dto.PromoForUser, string, int64, error)
+	Search(ctx context.Context, user *entity.User, search dto.PromoSearchRequest) (dto.PromoSearchResponse, int64, error)
+}
+
+type ViewRecorder interface {
+	Record(userID, kind string, promoIDs ...string)
 }
 
 type UserPromoHandler struct {
 	PromoService PromoService
+	viewRecorder ViewRecorder
 	validator    *validator.Validator
 }
 
-func NewUserPromoHandler(app *app.App) *UserPromoHandler {
+func NewUserPromoHandler(app *app.App, viewRecorder ViewRecorder) *UserPromoHandler {
 	promoStorage := postgres.NewPromoStorage(app.DB)
 	businessStorage := postgres.NewBusinessStorage(app.DB)
+	promoCacheStorage := redis.NewPromoCacheStorage(app.Redis)
 
 	return &UserPromoHandler{
-		PromoService: service.NewPromoService(promoStorage, businessStorage),
+		PromoService: service.NewPromoService(promoStorage, businessStorage, promoCacheStorage),
+		viewRecorder: viewRecorder,
 		validator:    app.Validator,
 	}
 }
@@ -41,12 +52,24 @@ func (h UserPromoHandler) GetFeed(c fiber.Ctx) error {
 	if err := c.Bind().Query(&requestDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if requestDTO.Limit == 0 {
-		requestDTO.Limit = 10
+	if errValidate := h.validator.ValidateData(requestDTO, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
+		})
+	}
+
+	page, err := h.validator.GetPage(c, requestDTO.Limit, requestDTO.Offset, requestDTO.Cursor, requestDTO.WithTotal)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
 	}
 
 	user := c.Locals("user").(*entity.User)
@@ -54,20 +77,40 @@ func (h UserPromoHandler) GetFeed(c fiber.Ctx) error {
 	if user == nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Пользователь не авторизован.",
+			Message: i18n.T(c, i18n.Unauthorized),
 		})
 	}
 
-	promos, total, err := h.PromoService.GetFeed(c.Context(), user, requestDTO)
+	promos, nextCursor, total, err := h.PromoService.GetFeed(c.Context(), user, requestDTO, page)
 
 	if err != nil {
+		if errors.Is(err, errorz.BadRequest) {
+			return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+				Status:  "error",
+				Message: i18n.T(c, i18n.BadRequest),
+			})
+		}
+
+		logger.Log.Error(err)
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: err.Error(),
+			Message: i18n.T(c, i18n.InternalError),
 		})
 	}
 
-	c.Append("X-Total-Count", strconv.FormatInt(total, 10))
+	promoIDs := make([]string, 0, len(promos))
+	for _, promo := range promos {
+		promoIDs = append(promoIDs, promo.PromoID)
+	}
+	h.viewRecorder.Record(user.ID, entity.ViewKindImpression, promoIDs...)
+
+	if page.NeedTotal() {
+		c.Append("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+
+	if page.Keyset {
+		return c.Status(fiber.StatusOK).JSON(dto.CursorPage[dto.PromoForUser]{Items: promos, NextCursor: nextCursor})
+	}
 
 	return c.Status(fiber.StatusOK).JSON(promos)
 }
@@ -80,40 +123,44 @@ func (h UserPromoHandler) GetPromoByID(c fiber.Ctx) error {
 	if err := c.Bind().URI(&requestDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
 		})
 	}
 
-	if errValidate := h.validator.ValidateData(requestDTO); errValidate != nil {
+	if errValidate := h.validator.ValidateData(requestDTO, i18n.Resolve(c)); errValidate != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
 	if user == nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Пользователь не авторизован.",
+			Message: i18n.T(c, i18n.Unauthorized),
 		})
 	}
 
-	promo, err := h.PromoService.GetByIdUser(c.Context(), requestDTO.ID, user.ID)
+	promo, err := h.PromoService.GetByIdUser(c.Context(), requestDTO.ID, user)
 
 	if err != nil {
 		if errors.Is(err, errorz.NotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Промо не найдено.",
+				Message: i18n.T(c, i18n.PromoNotFound),
 			})
 		}
 
+		logger.Log.Error(err)
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: err.Error(),
+			Message: i18n.T(c, i18n.InternalError),
 		})
 	}
 
+	h.viewRecorder.Record(user.ID, entity.ViewKindDetail, promo.PromoID)
+
 	return c.Status(fiber.StatusOK).JSON(promo)
 }
 
@@ -124,7 +171,68 @@ func (h UserPromoHandler) GetHistory(c fiber.Ctx) error {
 	if err := c.Bind().Query(&requestDTO); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Ошибка в данных запроса.",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	page, err := h.validator.GetPage(c, requestDTO.Limit, requestDTO.Offset, requestDTO.Cursor, requestDTO.WithTotal)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.Unauthorized),
+		})
+	}
+
+	promos, nextCursor, total, err := h.PromoService.GetHistory(c.Context(), user.ID, page)
+
+	if err != nil {
+		logger.Log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.InternalError),
+		})
+	}
+
+	promoIDs := make([]string, 0, len(promos))
+	for _, promo := range promos {
+		promoIDs = append(promoIDs, promo.PromoID)
+	}
+	h.viewRecorder.Record(user.ID, entity.ViewKindImpression, promoIDs...)
+
+	if page.NeedTotal() {
+		c.Append("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+
+	if page.Keyset {
+		return c.Status(fiber.StatusOK).JSON(dto.CursorPage[dto.PromoForUser]{Items: promos, NextCursor: nextCursor})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(promos)
+}
+
+func (h UserPromoHandler) Search(c fiber.Ctx) error {
+	var requestDTO dto.PromoSearchRequest
+	user := c.Locals("user").(*entity.User)
+
+	if err := c.Bind().Query(&requestDTO); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+		})
+	}
+
+	if errValidate := h.validator.ValidateData(requestDTO, i18n.Resolve(c)); errValidate != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(dto.HTTPResponse{
+			Status:  "error",
+			Message: i18n.T(c, i18n.BadRequest),
+			Details: errValidate.Message,
 		})
 	}
 
@@ -135,27 +243,29 @@ func (h UserPromoHandler) GetHistory(c fiber.Ctx) error {
 	if user == nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: "Пользователь не авторизован.",
+			Message: i18n.T(c, i18n.Unauthorized),
 		})
 	}
 
-	promos, total, err := h.PromoService.GetHistory(c.Context(), user.ID, requestDTO.Limit, requestDTO.Offset)
+	result, total, err := h.PromoService.Search(c.Context(), user, requestDTO)
 
 	if err != nil {
+		logger.Log.Error(err)
 		return c.Status(fiber.StatusInternalServerError).JSON(dto.HTTPResponse{
 			Status:  "error",
-			Message: err.Error(),
+			Message: i18n.T(c, i18n.InternalError),
 		})
 	}
 
 	c.Append("X-Total-Count", strconv.FormatInt(total, 10))
 
-	return c.Status(fiber.StatusOK).JSON(promos)
+	return c.Status(fiber.StatusOK).JSON(result)
 }
 
 func (h UserPromoHandler) Setup(router fiber.Router, middleware fiber.Handler) {
 	userGroup := router.Group("/user")
 	userGroup.Get("/feed", h.GetFeed, middleware)
 	userGroup.Get("/promo/history", h.GetHistory, middleware)
+	userGroup.Get("/promo/search", h.Search, middleware)
 	userGroup.Get("/promo/:id", h.GetPromoByID, middleware)
 }
@@ -0,0 +1,21 @@
+package v1
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"prod/internal/adapters/database/redis"
+)
+
+type MetricsHandler struct{}
+
+func NewMetricsHandler() *MetricsHandler {
+	return &MetricsHandler{}
+}
+
+// cache is a handler that returns hit and miss counters of the promo caches of this instance.
+func (h MetricsHandler) cache(c fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(redis.CacheMetrics())
+}
+
+func (h MetricsHandler) Setup(router fiber.Router) {
+	router.Get("/metrics/cache", h.cache)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/gofiber/fiber/v3"
 	"prod/cmd/app"
+	"prod/internal/adapters/controller/api/i18n"
 	"prod/internal/adapters/database/postgres"
 	"prod/internal/adapters/database/redis"
 	"prod/internal/domain/dto"
@@ -66,14 +67,14 @@ func (h MiddlewareHandler) IsAuthenticated() fiber.Handler {
 		if (userVerifyErr != nil && businessVerifyErr != nil) || (!userVerify && !businessVerify) {
 			return c.Status(fiber.StatusUnauthorized).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Время действия токена истекло.",
+				Message: i18n.T(c, i18n.TokenExpired),
 			})
 		}
 
 		if (fetchErr != nil && businessFetchErr != nil) || (user == nil && business == nil) {
 			return c.Status(fiber.StatusUnauthorized).JSON(dto.HTTPResponse{
 				Status:  "error",
-				Message: "Пользователь не авторизован.",
+				Message: i18n.T(c, i18n.Unauthorized),
 			})
 		}
 
@@ -1,17 +1,43 @@
 package validator
 
 import (
-	"fmt"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	ruTranslations "github.com/go-playground/validator/v10/translations/ru"
 	"github.com/gofiber/fiber/v3"
+	"prod/internal/adapters/controller/api/i18n"
 	"prod/internal/adapters/logger"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/utils/cursor"
 	"strconv"
 	"strings"
 	"unicode"
 )
 
 type Validator struct {
-	validator *validator.Validate
+	validator   *validator.Validate
+	translators map[string]ut.Translator
+}
+
+// customTranslations is a list of messages for custom validation tags by language.
+var customTranslations = map[string]map[string]string{
+	i18n.RU: {
+		"username": "{0} должен содержать от 4 до 20 символов",
+		"code":     "{0} должен состоять из 6 символов и содержать заглавную букву или цифру",
+		"password": "{0} должен содержать минимум 8 символов, заглавные и строчные буквы и цифры",
+		"header":   "{0} должен содержать от 5 до 150 символов",
+		"body":     "{0} должен содержать от 5 до 1500 символов",
+	},
+	i18n.EN: {
+		"username": "{0} must be between 4 and 20 characters",
+		"code":     "{0} must be 6 characters long and contain an uppercase letter or a digit",
+		"password": "{0} must be at least 8 characters long and contain uppercase and lowercase letters and digits",
+		"header":   "{0} must be between 5 and 150 characters",
+		"body":     "{0} must be between 5 and 1500 characters",
+	},
 }
 
 type GlobalErrorHandlerResp struct {
@@ -62,46 +88,74 @@ func New() *Validator {
 		return len(fl.Field().String()) >= 5 && len(fl.Field().String()) <= 1500
 	})
 
-	return &Validator{
-		newValidator,
-	}
-}
+	enLocale := en.New()
+	universalTranslator := ut.New(enLocale, enLocale, ru.New())
 
-func (v Validator) ValidateData(data interface{}) *fiber.Error {
-	var validationErrors []ErrorResponse
+	translators := make(map[string]ut.Translator)
+	translators[i18n.EN], _ = universalTranslator.GetTranslator(i18n.EN)
+	translators[i18n.RU], _ = universalTranslator.GetTranslator(i18n.RU)
 
-	errs := v.validator.Struct(data)
-	if errs != nil {
-		for _, err := range errs.(validator.ValidationErrors) {
-			var elem ErrorResponse
-
-			elem.FailedField = err.Field() // Export struct field name
-			elem.Tag = err.Tag()           // Export struct tag
-			elem.Value = err.Value()       // Export field value
-			elem.Error = true
+	if err := enTranslations.RegisterDefaultTranslations(newValidator, translators[i18n.EN]); err != nil {
+		logger.Log.Errorf("failed to register en translations: %v", err)
+	}
+	if err := ruTranslations.RegisterDefaultTranslations(newValidator, translators[i18n.RU]); err != nil {
+		logger.Log.Errorf("failed to register ru translations: %v", err)
+	}
 
-			validationErrors = append(validationErrors, elem)
+	for lang, messages := range customTranslations {
+		for tag, message := range messages {
+			registerTranslation(newValidator, translators[lang], tag, message)
 		}
 	}
 
-	if len(validationErrors) > 0 && validationErrors[0].Error {
-		errMessages := make([]string, 0)
+	return &Validator{
+		validator:   newValidator,
+		translators: translators,
+	}
+}
 
-		for _, err := range validationErrors {
-			errMessages = append(errMessages, fmt.Sprintf(
-				"[%s]: '%v' | Needs to implement '%s'",
-				err.FailedField,
-				err.Value,
-				err.Tag,
-			))
+// registerTranslation is a function to register a message for a custom validation tag.
+func registerTranslation(v *validator.Validate, trans ut.Translator, tag, message string) {
+	_ = v.RegisterTranslation(tag, trans, func(ut ut.Translator) error {
+		return ut.Add(tag, message, true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		translated, err := ut.T(tag, fe.Field())
+		if err != nil {
+			return fe.Error()
 		}
+		return translated
+	})
+}
+
+// ValidateData is a method that validates the struct and returns validation messages in the given language.
+func (v Validator) ValidateData(data interface{}, lang string) *fiber.Error {
+	errs := v.validator.Struct(data)
+	if errs == nil {
+		return nil
+	}
 
+	validationErrors, ok := errs.(validator.ValidationErrors)
+	if !ok {
 		return &fiber.Error{
 			Code:    fiber.ErrBadRequest.Code,
-			Message: strings.Join(errMessages, " and "),
+			Message: errs.Error(),
 		}
 	}
-	return nil
+
+	trans, ok := v.translators[lang]
+	if !ok {
+		trans = v.translators[i18n.Default]
+	}
+
+	errMessages := make([]string, 0, len(validationErrors))
+	for _, err := range validationErrors {
+		errMessages = append(errMessages, err.Translate(trans))
+	}
+
+	return &fiber.Error{
+		Code:    fiber.ErrBadRequest.Code,
+		Message: strings.Join(errMessages, "; "),
+	}
 }
 
 func (v Validator) GetLimitAndOffset(c fiber.Ctx, defaultLimit string, defaultOffset string) (int, int) {
@@ -115,3 +169,35 @@ func (v Validator) GetLimitAndOffset(c fiber.Ctx, defaultLimit string, defaultOf
 	}
 	return limit, offset
 }
+
+// GetPage is a method that builds pagination params from the request.
+/*
+ * Presence of the "cursor" query param (even empty, for the first page) switches to keyset pagination.
+ */
+func (v Validator) GetPage(c fiber.Ctx, limit, offset int, cursorValue string, withTotal bool) (dto.Page, error) {
+	if limit == 0 {
+		limit = 10
+	}
+
+	if limit < 0 || offset < 0 {
+		return dto.Page{}, cursor.ErrInvalid
+	}
+
+	page := dto.Page{
+		Limit:     limit,
+		Offset:    offset,
+		Keyset:    c.Request().URI().QueryArgs().Has("cursor"),
+		WithTotal: withTotal,
+	}
+
+	if page.Keyset {
+		decoded, err := cursor.Decode(cursorValue)
+		if err != nil {
+			return dto.Page{}, err
+		}
+		page.Cursor = decoded
+		page.Offset = 0
+	}
+
+	return page, nil
+}
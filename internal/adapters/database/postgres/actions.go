@@ -5,6 +5,7 @@ import (
 	"gorm.io/gorm"
 	"prod/internal/domain/common/errorz"
 	"prod/internal/domain/dto"
+	"prod/internal/domain/utils/cursor"
 	"time"
 )
 
@@ -16,120 +17,216 @@ func NewActionsStorage(db *gorm.DB) *actionsStorage {
 	return &actionsStorage{db: db}
 }
 
-func (s *actionsStorage) IsLikedByUser(ctx context.Context, userID, promoID string) bool {
-	query := `
-		SELECT COUNT(*)
-		FROM likes l
-				 INNER JOIN promos p ON p.promo_id = l.promo_id
-				 INNER JOIN users u ON u.id = l.user_id
-		WHERE u.id = ?
-		  AND p.promo_id = ?
-		  AND l."like" = true`
-
-	var total int64
-	s.db.WithContext(ctx).Raw(query, userID, promoID).Scan(&total)
-
-	return total != 0
-}
-
+// AddLike is a method that likes the promo, the counter and LikeToggled are written only if the like changed.
+/*
+ * The promo row is locked until the end of the transaction, so concurrent likes of the user are applied one after another.
+ */
 func (s *actionsStorage) AddLike(ctx context.Context, userID, promoID string) error {
-	querySelect := `
-		SELECT COUNT(*)
-		FROM likes l
-				 INNER JOIN promos p ON p.promo_id = l.promo_id
-				 INNER JOIN users u ON u.id = l.user_id
-		WHERE u.id = ?
-		  AND p.promo_id = ?`
+	return s.toggleLike(ctx, userID, promoID, true)
+}
 
-	queryInsert := `INSERT INTO likes (user_id, promo_id, "like") VALUES (?, ?, true)`
+// DeleteLike is a method that unlikes the promo, the counter and LikeToggled are written only if the like changed.
+func (s *actionsStorage) DeleteLike(ctx context.Context, userID, promoID string) error {
+	return s.toggleLike(ctx, userID, promoID, false)
+}
 
+func (s *actionsStorage) toggleLike(ctx context.Context, userID, promoID string, like bool) error {
 	queryUpdate := `
-		WITH updated_likes AS (
-			UPDATE likes
-				SET "like" = TRUE
-				WHERE user_id = ? 
-          			AND promo_id = ? 
-					AND "like" = FALSE
-				RETURNING promo_id
-		)
-		UPDATE promos
-		SET like_count = like_count + 1
-		WHERE promo_id IN (SELECT promo_id FROM updated_likes)`
-
-	queryIncrement := `UPDATE promos SET like_count = like_count + 1 WHERE promo_id = ?`
+		UPDATE likes
+		SET "like"   = ?,
+			liked_at = CASE WHEN ? THEN now() ELSE liked_at END
+		WHERE user_id = ?
+		  AND promo_id = ?
+		  AND "like" <> ?`
+
+	queryInsert := `
+		INSERT INTO likes (user_id, promo_id, "like", liked_at)
+		SELECT ?, ?, ?, CASE WHEN ? THEN now() END
+		WHERE NOT EXISTS (SELECT 1 FROM likes WHERE user_id = ? AND promo_id = ?)`
+
+	queryCounter := `UPDATE promos SET like_count = like_count + ? WHERE promo_id = ?`
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`SELECT 1 FROM promos WHERE promo_id = ? FOR UPDATE`, promoID).Error; err != nil {
+			return err
+		}
 
-	var total int64
+		updated := tx.Exec(queryUpdate, like, like, userID, promoID, like)
+		if updated.Error != nil {
+			return updated.Error
+		}
 
-	// if actions record doesn't exist
-	if _ = s.db.WithContext(ctx).Raw(querySelect, userID, promoID).Scan(&total); total == 0 {
-		err := s.db.WithContext(ctx).Exec(queryInsert, userID, promoID).Error
-		if err != nil {
-			return err
+		changed := updated.RowsAffected > 0
+		if !changed {
+			inserted := tx.Exec(queryInsert, userID, promoID, like, like, userID, promoID)
+			if inserted.Error != nil {
+				return inserted.Error
+			}
+			// Запись без лайка не меняет счетчик
+			changed = inserted.RowsAffected > 0 && like
 		}
-		if err = s.db.WithContext(ctx).Exec(queryIncrement, promoID).Error; err != nil {
-			return err
+
+		if !changed {
+			return nil
 		}
-	} else {
-		err := s.db.WithContext(ctx).Exec(queryUpdate, userID, promoID).Error
-		if err != nil {
+
+		delta := 1
+		if !like {
+			delta = -1
+		}
+		if err := tx.Exec(queryCounter, delta, promoID).Error; err != nil {
 			return err
 		}
-	}
 
-	return nil
+		return insertEvent(tx, dto.EventLikeToggled, promoID, dto.LikeToggledEvent{UserID: userID, Liked: like, ToggledAt: time.Now()})
+	})
 }
 
-func (s *actionsStorage) DeleteLike(ctx context.Context, userID, promoID string) error {
-	querySelect := `
-		SELECT COUNT(*)
-		FROM likes l
-				 INNER JOIN promos p ON p.promo_id = l.promo_id
-				 INNER JOIN users u ON u.id = l.user_id
-		WHERE u.id = ?
-		  AND p.promo_id = ?
-		  AND l."like" = true`
+// commentSelect is a query of comments (alias c) with their authors, a user or the company of an official reply.
+const commentSelect = `
+		SELECT COALESCE(u.name, b.name, '')    AS name,
+			   COALESCE(u.surname, '')         AS surname,
+			   COALESCE(u.avatar_url, '')      AS avatar_url,
+			   c.comment_id,
+			   c.parent_id,
+			   c.official,
+			   c.reply_count,
+			   c.status,
+			   c.text,
+			   c.created_at,
+			   c.promo_id,
+			   c.moderation_reason,
+			   c.report_count
+		FROM comments c
+				 LEFT JOIN users u ON u.id = c.user_id
+				 LEFT JOIN businesses b ON b.id = c.company_id`
+
+// commentRow is a row of commentSelect.
+type commentRow struct {
+	Name       string
+	Surname    string
+	AvatarURL  string
+	CommentID  string
+	ParentID   *string
+	Official   bool
+	ReplyCount int
+	Status     string
+	Text       string
+	CreatedAt  time.Time
+
+	PromoID          string
+	ModerationReason string
+	ReportCount      int
+}
 
-	queryInsert := `INSERT INTO likes (user_id, promo_id, "like") VALUES (?, ?, false)`
+func (r commentRow) toDTO() dto.Comment {
+	return dto.Comment{
+		ID:         r.CommentID,
+		Text:       r.Text,
+		Date:       r.CreatedAt.Format(time.RFC3339),
+		ParentID:   r.ParentID,
+		Official:   r.Official,
+		ReplyCount: r.ReplyCount,
+		Status:     r.Status,
+		Author: dto.Author{
+			Name:      r.Name,
+			Surname:   r.Surname,
+			AvatarURL: r.AvatarURL,
+		},
+	}
+}
 
-	queryUpdate := `UPDATE likes SET "like" = false WHERE user_id = ? AND promo_id = ?`
+// AddComment is a method that adds a comment of the user to the promo, a reply if parentID is not nil.
+/*
+ * A non-empty moderationReason holds the comment in the moderation queue instead of publishing it.
+ * Returns errorz.LimitError if the user already wrote rateLimit comments within rateWindow.
+ */
+func (s *actionsStorage) AddComment(ctx context.Context, userID, promoID string, parentID *string, text, moderationReason string, rateLimit int64, rateWindow time.Duration) (string, error) {
+	return s.addComment(ctx, promoID, parentID, &userID, nil, text, moderationReason, func(tx *gorm.DB) error {
+		return checkCommentRate(tx, userID, rateLimit, rateWindow)
+	})
+}
 
-	queryDecrement := `UPDATE promos SET like_count = like_count - 1 WHERE promo_id = ?`
+// AddOfficialReply is a method that adds a reply of the promo's company to a comment.
+func (s *actionsStorage) AddOfficialReply(ctx context.Context, companyID, promoID, parentID, text string) (string, error) {
+	return s.addComment(ctx, promoID, &parentID, nil, &companyID, text, "", nil)
+}
 
-	var total int64
+// addComment is a method that adds a comment with the counters of the promo and the thread and CommentAdded in one transaction.
+/*
+ * A reply to a reply is added to the same thread. Returns errorz.NotFound if the parent is not a published comment of the promo.
+ * A held comment doesn't change the counters and writes no event until it is approved.
+ * check, if not nil, runs first in the same transaction.
+ */
+func (s *actionsStorage) addComment(ctx context.Context, promoID string, parentID, userID, companyID *string, text, moderationReason string, check func(tx *gorm.DB) error) (string, error) {
+	query := `
+		INSERT INTO comments (created_at, promo_id, user_id, company_id, text, parent_id, official, status, moderation_reason, published_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING comment_id`
 
-	// if actions record doesn't exist
-	if _ = s.db.WithContext(ctx).Raw(querySelect, userID, promoID).Scan(&total); total == 0 {
-		err := s.db.WithContext(ctx).Exec(queryInsert, userID, promoID).Error
-		if err != nil {
-			return err
-		}
-	} else {
-		err := s.db.WithContext(ctx).Exec(queryUpdate, userID, promoID).Error
-		if err != nil {
-			return err
+	queryThread := `
+		SELECT comment_id
+		FROM comments
+		WHERE comment_id = (SELECT COALESCE(parent_id, comment_id) FROM comments WHERE comment_id = ? AND promo_id = ?)
+		  AND status = ?
+		FOR UPDATE`
+
+	var commentID string
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if check != nil {
+			if err := check(tx); err != nil {
+				return err
+			}
 		}
-		err = s.db.WithContext(ctx).Exec(queryDecrement, promoID).Error
-		if err != nil {
-			return err
+
+		var threadID *string
+		if parentID != nil {
+			var threads []string
+			if err := tx.Raw(queryThread, *parentID, promoID, dto.CommentPublished).Scan(&threads).Error; err != nil {
+				return err
+			}
+			if len(threads) == 0 {
+				return errorz.NotFound
+			}
+			threadID = &threads[0]
 		}
-	}
 
-	return nil
-}
+		status := dto.CommentPublished
+		if moderationReason != "" {
+			status = dto.CommentPending
+		}
 
-func (s *actionsStorage) AddComment(ctx context.Context, userID, promoID, text string) (string, error) {
-	query := `INSERT INTO comments (created_at, promo_id, user_id, text) VALUES (?, ?, ?, ?) RETURNING comment_id`
+		now := time.Now()
+		official := companyID != nil
 
-	queryIncrement := `UPDATE promos SET comment_count = comment_count + 1 WHERE promo_id = ?`
+		var publishedAt *time.Time
+		if status == dto.CommentPublished {
+			publishedAt = &now
+		}
+		if err := tx.Raw(query, now, promoID, userID, companyID, text, threadID, official, status, moderationReason, publishedAt).Scan(&commentID).Error; err != nil {
+			return err
+		}
 
-	var commentID string
+		if status != dto.CommentPublished {
+			return nil
+		}
 
-	err := s.db.WithContext(ctx).Raw(query, time.Now(), promoID, userID, text).Scan(&commentID).Error
-	if err != nil {
-		return "", err
-	}
+		state := commentState{ParentID: threadID, RootStatus: dto.CommentPublished}
+		if err := countComment(tx, promoID, state, 1); err != nil {
+			return err
+		}
 
-	err = s.db.WithContext(ctx).Exec(queryIncrement, promoID).Error
+		return insertEvent(tx, dto.EventCommentAdded, promoID, dto.CommentAddedEvent{
+			CommentID: commentID,
+			ParentID:  threadID,
+			UserID:    userID,
+			CompanyID: companyID,
+			Official:  official,
+			Text:      text,
+			CreatedAt: now,
+		})
+	})
 	if err != nil {
 		return "", err
 	}
@@ -137,211 +234,191 @@ func (s *actionsStorage) AddComment(ctx context.Context, userID, promoID, text s
 	return commentID, nil
 }
 
-func (s *actionsStorage) GetComments(ctx context.Context, promoID string, limit, offset int) ([]dto.Comment, int64, error) {
-	query := `
-		SELECT u.name,
-			   u.surname,
-			   u.avatar_url,
-			   c.comment_id,
-			   c.text,
-			   c.created_at
-		FROM comments c
-				 INNER JOIN users u ON u.id = c.user_id
-		WHERE c.promo_id = ?
-		ORDER BY c.created_at DESC
-		LIMIT ? OFFSET ?`
+// GetComments is a method that returns a page of top-level comments of the promo, newest first, or of replies of a thread, oldest first.
+/*
+ * Only published comments are listed. Returns errorz.NotFound if parentID is not a published top-level comment of the promo.
+ */
+func (s *actionsStorage) GetComments(ctx context.Context, promoID, parentID string, page dto.Page) ([]dto.Comment, string, int64, error) {
+	where := ` WHERE c.promo_id = ? AND c.status = ? AND c.parent_id IS NULL`
+	args := []interface{}{promoID, dto.CommentPublished}
+	order, before := "DESC", "<"
+
+	if parentID != "" {
+		var exists bool
+		if err := s.db.WithContext(ctx).Raw(`SELECT EXISTS(SELECT 1 FROM comments WHERE comment_id = ? AND promo_id = ? AND status = ? AND parent_id IS NULL)`, parentID, promoID, dto.CommentPublished).Scan(&exists).Error; err != nil {
+			return nil, "", 0, err
+		}
+		if !exists {
+			return nil, "", 0, errorz.NotFound
+		}
 
-	type result struct {
-		Name      string
-		Surname   string
-		AvatarURL string
-		CommentID string
-		Text      string
-		CreatedAt time.Time
+		// Ответы читаются как переписка, от старых к новым
+		where = ` WHERE c.promo_id = ? AND c.status = ? AND c.parent_id = ?`
+		args = append(args, parentID)
+		order, before = "ASC", ">"
 	}
 
-	var results []result
+	query := commentSelect + where
+	pageArgs := append([]interface{}{}, args...)
 
-	err := s.db.WithContext(ctx).Raw(query, promoID, limit, offset).Scan(&results).Error
+	if page.Cursor != nil {
+		query += ` AND (c.created_at, c.comment_id) ` + before + ` (?, ?)`
+		pageArgs = append(pageArgs, page.Cursor.Time, page.Cursor.ID)
+	}
+
+	query += `
+		ORDER BY c.created_at ` + order + `, c.comment_id ` + order + `
+		LIMIT ? OFFSET ?`
+	pageArgs = append(pageArgs, page.Limit+1, page.Offset)
+
+	var results []commentRow
+
+	err := s.db.WithContext(ctx).Raw(query, pageArgs...).Scan(&results).Error
 
 	if err != nil {
-		return nil, 0, err
+		return nil, "", 0, err
+	}
+
+	var nextCursor string
+	if len(results) > page.Limit {
+		results = results[:page.Limit]
+		last := results[len(results)-1]
+		nextCursor = cursor.Encode(cursor.Cursor{Time: last.CreatedAt, ID: last.CommentID})
 	}
 
 	comments := make([]dto.Comment, 0, len(results))
 
 	for _, r := range results {
-		comments = append(comments, dto.Comment{
-			ID:   r.CommentID,
-			Text: r.Text,
-			Date: r.CreatedAt.Format(time.RFC3339),
-			Author: dto.Author{
-				Name:      r.Name,
-				Surname:   r.Surname,
-				AvatarURL: r.AvatarURL,
-			},
-		})
+		comments = append(comments, r.toDTO())
+	}
+
+	if !page.NeedTotal() {
+		return comments, nextCursor, 0, nil
 	}
 
 	var total int64
 
-	if err = s.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM comments WHERE promo_id = ?`, promoID).Scan(&total).Error; err != nil {
-		return nil, 0, err
+	if err = s.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM comments c`+where, args...).Scan(&total).Error; err != nil {
+		return nil, "", 0, err
 	}
 
-	return comments, total, nil
+	return comments, nextCursor, total, nil
 }
 
 func (s *actionsStorage) GetCommentById(ctx context.Context, promoID, commentID string) (dto.Comment, error) {
-	query := `
-		SELECT u.name,
-			   u.surname,
-			   u.avatar_url,
-			   c.comment_id,
-			   c.text,
-			   c.created_at
-		FROM comments c
-				 INNER JOIN users u ON u.id = c.user_id
+	query := commentSelect + `
 		WHERE c.comment_id = ?
 		  AND c.promo_id = ?`
 
-	type result struct {
-		Name      string
-		Surname   string
-		AvatarURL string
-		CommentID string
-		Text      string
-		CreatedAt time.Time
-	}
-
-	var r result
+	var results []commentRow
 
-	err := s.db.WithContext(ctx).Raw(query, commentID, promoID).Scan(&r).Error
+	err := s.db.WithContext(ctx).Raw(query, commentID, promoID).Scan(&results).Error
 
 	if err != nil {
 		return dto.Comment{}, err
 	}
 
-	if r.CommentID == "" {
+	if len(results) == 0 {
 		return dto.Comment{}, errorz.NotFound
 	}
 
-	return dto.Comment{
-		ID:   r.CommentID,
-		Text: r.Text,
-		Date: r.CreatedAt.Format(time.RFC3339),
-		Author: dto.Author{
-			Name:      r.Name,
-			Surname:   r.Surname,
-			AvatarURL: r.AvatarURL,
-		},
-	}, nil
+	return results[0].toDTO(), nil
 }
 
-func (s *actionsStorage) UpdateComment(ctx context.Context, promoID, commentID, userID, text string) (dto.Comment, error) {
-	querySelect := `
-		SELECT u.name,
-			   u.surname,
-			   u.avatar_url,
-			   u.id,
-			   c.comment_id,
-			   c.text,
-			   c.created_at
-		FROM comments c
-				 INNER JOIN users u ON u.id = c.user_id
-		WHERE c.comment_id = ?
-		  AND c.promo_id = ?`
-
-	queryUpdate := `UPDATE comments SET text = ? WHERE comment_id = ? AND promo_id = ?`
-
-	type result struct {
-		Name      string
-		Surname   string
-		AvatarURL string
-		ID        string // UserID
-		CommentID string
-		Text      string
-		CreatedAt time.Time
-	}
+// UpdateComment is a method that changes the text of the user's comment, official replies can't be changed by users.
+/*
+ * A non-empty moderationReason holds a published comment in the moderation queue, like a new comment.
+ */
+func (s *actionsStorage) UpdateComment(ctx context.Context, promoID, commentID, userID, text, moderationReason string) (dto.Comment, error) {
+	queryUpdate := `UPDATE comments SET text = ? WHERE comment_id = ?`
+
+	queryHold := `
+		UPDATE comments
+		SET status            = ?,
+			moderation_reason = ?,
+			moderated_at      = NULL
+		WHERE comment_id = ?`
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		state, err := lockComment(tx, promoID, commentID)
+		if err != nil {
+			return err
+		}
 
-	var r result
+		if state.UserID == nil || *state.UserID != userID {
+			return errorz.Forbidden
+		}
 
-	err := s.db.WithContext(ctx).Raw(querySelect, commentID, promoID).Scan(&r).Error
-	if err != nil {
-		return dto.Comment{}, err
-	}
+		if err = tx.Exec(queryUpdate, text, commentID).Error; err != nil {
+			return err
+		}
 
-	if r.CommentID == "" {
-		return dto.Comment{}, errorz.NotFound
-	}
+		if moderationReason == "" || state.Status != dto.CommentPublished {
+			return nil
+		}
 
-	if r.ID != userID {
-		return dto.Comment{}, errorz.Forbidden
-	}
+		if err = tx.Exec(queryHold, dto.CommentPending, moderationReason, commentID).Error; err != nil {
+			return err
+		}
 
-	query := s.db.WithContext(ctx).Exec(queryUpdate, text, commentID, promoID)
-	if queryErr := query.Error; queryErr != nil {
+		return countComment(tx, promoID, state, -1)
+	})
+	if err != nil {
 		return dto.Comment{}, err
 	}
 
-	if query.RowsAffected == 0 {
-		return dto.Comment{}, errorz.NotFound
-	}
-
-	return dto.Comment{
-		ID:   r.CommentID,
-		Text: text,
-		Date: r.CreatedAt.Format(time.RFC3339),
-		Author: dto.Author{
-			Name:      r.Name,
-			Surname:   r.Surname,
-			AvatarURL: r.AvatarURL,
-		},
-	}, nil
+	return s.GetCommentById(ctx, promoID, commentID)
 }
 
+// DeleteComment is a method that deletes the user's comment with its replies and updates the counters in one transaction.
 func (s *actionsStorage) DeleteComment(ctx context.Context, promoID, commentID, userID string) error {
-	querySelect := `SELECT user_id FROM comments WHERE comment_id = ? AND promo_id = ?`
-
-	queryDelete := `DELETE FROM comments WHERE comment_id = ? AND promo_id = ?`
-
-	var authorID string
-
-	var existsPromo, existsComment bool
-
-	errExistsPromo := s.db.WithContext(ctx).Raw(`SELECT EXISTS(SELECT * FROM promos WHERE promo_id = ?)`, promoID).Scan(&existsPromo).Error
+	var existsPromo bool
 
-	errExistsComment := s.db.WithContext(ctx).Raw(`SELECT EXISTS(SELECT * FROM comments WHERE comment_id = ? AND promo_id = ?)`, commentID, promoID).Scan(&existsComment).Error
-
-	if errExistsPromo != nil || errExistsComment != nil {
-		return errExistsPromo
+	if err := s.db.WithContext(ctx).Raw(`SELECT EXISTS(SELECT * FROM promos WHERE promo_id = ?)`, promoID).Scan(&existsPromo).Error; err != nil {
+		return err
 	}
 
-	if !existsPromo || !existsComment {
+	if !existsPromo {
 		return errorz.NotFound
 	}
 
-	err := s.db.WithContext(ctx).Raw(querySelect, commentID, promoID).Scan(&authorID).Error
-	if err != nil {
-		return err
-	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		state, err := lockComment(tx, promoID, commentID)
+		if err != nil {
+			return err
+		}
 
-	if authorID != userID {
-		return errorz.Forbidden
-	}
+		if state.UserID == nil || *state.UserID != userID {
+			return errorz.Forbidden
+		}
 
-	query := s.db.WithContext(ctx).Exec(queryDelete, commentID, promoID)
-	if queryErr := query.Error; queryErr != nil {
-		return queryErr
-	}
+		queryReports := `DELETE FROM comment_reports WHERE comment_id IN (SELECT comment_id FROM comments WHERE comment_id = ? OR parent_id = ?)`
+		if err = tx.Exec(queryReports, commentID, commentID).Error; err != nil {
+			return err
+		}
 
-	if query.RowsAffected != 0 {
-		err = s.db.WithContext(ctx).Exec(`UPDATE promos SET comment_count = comment_count - 1 WHERE promo_id = ?`, promoID).Error
-		if err != nil {
+		if err = tx.Exec(`DELETE FROM comments WHERE promo_id = ? AND (comment_id = ? OR parent_id = ?)`, promoID, commentID, commentID).Error; err != nil {
 			return err
 		}
-	}
 
-	return nil
+		// Скрытые и ждущие модерации комментарии не входят в счетчики
+		if state.Status != dto.CommentPublished {
+			return nil
+		}
+
+		return countComment(tx, promoID, state, -1)
+	})
+}
+
+// GetPromoCompanyID is a method that returns the company of a promo that is not deleted.
+func (s *actionsStorage) GetPromoCompanyID(ctx context.Context, promoID string) (string, error) {
+	var companyIDs []string
+	if err := s.db.WithContext(ctx).Raw(`SELECT company_id FROM promos WHERE promo_id = ? AND deleted_at IS NULL`, promoID).Scan(&companyIDs).Error; err != nil {
+		return "", err
+	}
+	if len(companyIDs) == 0 {
+		return "", errorz.NotFound
+	}
 
+	return companyIDs[0], nil
 }
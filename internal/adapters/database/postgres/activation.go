@@ -2,9 +2,15 @@ package postgres
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/biter777/countries"
 	"gorm.io/gorm"
+	"prod/internal/adapters/logger"
 	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/utils/schedule"
+	"strings"
 	"time"
 )
 
@@ -16,37 +22,50 @@ func NewActivationStorage(db *gorm.DB) *activationStorage {
 	return &activationStorage{db: db}
 }
 
+// ActivatePromo is a method that issues a code of the promo to the user and writes PromoActivated to the outbox.
+/*
+ * The activation that took the last code also clears the active flag and writes PromoExhausted. For a UNIQUE promo
+ * "the last one" is judged by the codes committed before, so two concurrent activations taking the last two codes
+ * may both miss it, then the next activation that finds no code does it.
+ */
 func (s *activationStorage) ActivatePromo(ctx context.Context, age int, country countries.CountryCode, promoID, userID string) (string, error) {
-	queryCount := `SELECT count(*) FROM promos WHERE promo_id = ?`
+	queryCount := `SELECT count(*) FROM promos WHERE promo_id = ? AND deleted_at IS NULL`
 
 	queryActivate := `
 		WITH common_update AS (
-			UPDATE promos
+			UPDATE promos p
 				SET
 					used_count = used_count + 1,
 					active = CASE
-								 WHEN (used_count + 1 >= max_count) OR (active_until < NOW())
+								 WHEN used_count + 1 >= max_count
 									 THEN false
 								 ELSE active
 						END
 				WHERE
 					promo_id = ?
 						AND active = TRUE
-						AND age_from <= ?
-						AND age_until >= ?
-						AND (country = ? OR country = 0)
+						-- Активировать можно только live-промо в пределах дат
+						AND status = 'live'
+						AND active_from <= now()
+						AND active_until > now()
+						AND archived_at IS NULL
+						AND %s
+						AND %s
 						AND mode = 'COMMON'
 						AND max_count > used_count
-				RETURNING promo_common AS promocode),
+				RETURNING promo_common AS promocode, used_count >= max_count AS exhausted),
 			 selected_unique AS (
 				 -- Select UNIQUE promo code
 				 SELECT pu.promo_unique_id, pu.body
 				 FROM promo_uniques pu
 						  INNER JOIN promos p ON p.promo_id = pu.promo_id
 				 WHERE p.active = TRUE
-				   AND p.age_from <= ?
-				   AND p.age_until >= ?
-				   AND (p.country = ? OR p.country = 0)
+				   AND p.status = 'live'
+				   AND p.active_from <= now()
+				   AND p.active_until > now()
+				   AND p.archived_at IS NULL
+				   AND %s
+				   AND %s
 				   AND p.mode = 'UNIQUE'
 				   AND p.promo_id = ?
 				   AND pu.activated = FALSE
@@ -54,28 +73,31 @@ func (s *activationStorage) ActivatePromo(ctx context.Context, age int, country
 			 unique_update AS (
 				 -- Update UNIQUE promo code
 				 UPDATE promo_uniques
-					 SET activated = TRUE
+					 SET activated    = TRUE,
+						 activated_by = ?,
+						 activated_at = now()
 					 WHERE promo_unique_id IN (SELECT promo_unique_id FROM selected_unique)
-					 RETURNING body AS promocode),
-			 unique_active_update AS (
-				 -- Update active status for UNIQUE promos
-				 UPDATE promos
-					 SET active = CASE
-									  WHEN EXISTS (SELECT *
-												   FROM promo_uniques pu
-												   WHERE pu.promo_id = promos.promo_id
-													 AND pu.activated = FALSE) AND active_until > now() THEN active
-									  ELSE false
-						 END
-					 WHERE promo_id = ?
-						 AND mode = 'UNIQUE'
-					 RETURNING NULL -- Ensure query consistency
-			 )`
-
-	querySelect := `SELECT mode from promos WHERE promo_id = ?`
+					 RETURNING body AS promocode)`
+
+	queryActivate = fmt.Sprintf(queryActivate, targetExpression, scheduleExpression, targetExpression, scheduleExpression)
+
+	querySelect := `
+		SELECT mode,
+			   schedule,
+			   user_limit,
+			   user_limit_period,
+			   period_limit,
+			   limit_period
+		FROM promos
+		WHERE promo_id = ?`
 
 	type selectResult struct {
-		Mode string
+		Mode            string
+		Schedule        *string
+		UserLimit       int
+		UserLimitPeriod string
+		PeriodLimit     int
+		LimitPeriod     string
 	}
 
 	var selectRes selectResult
@@ -89,11 +111,19 @@ func (s *activationStorage) ActivatePromo(ctx context.Context, age int, country
 			SELECT * from common_update
 			LIMIT 1`
 	} else {
-		queryActivate += `SELECT * from unique_update`
+		queryActivate += `
+			SELECT uu.promocode,
+				   NOT EXISTS(SELECT 1
+							  FROM promo_uniques pu
+							  WHERE pu.promo_id = ?
+								AND pu.activated = FALSE
+								AND pu.promo_unique_id NOT IN (SELECT promo_unique_id FROM selected_unique)) AS exhausted
+			FROM unique_update uu`
 	}
 
 	type result struct {
 		Promocode string
+		Exhausted bool
 	}
 
 	var res result
@@ -105,18 +135,127 @@ func (s *activationStorage) ActivatePromo(ctx context.Context, age int, country
 		return "", errorz.NotFound
 	}
 
-	if err := s.db.Raw(queryActivate, promoID, age, age, country, age, age, country, promoID, promoID).Scan(&res).Error; err != nil {
-		return "", err
+	var args []interface{}
+	args = append(args, promoID)
+	args = append(args, targetArgs(age, country, userID)...)
+	args = append(args, targetArgs(age, country, userID)...)
+	args = append(args, promoID, userID)
+	if selectRes.Mode != "COMMON" {
+		args = append(args, promoID)
 	}
 
-	if res == (result{}) {
-		return "", errorz.Forbidden
-	}
+	// Лимиты проверяются и активация записывается в одной транзакции
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if selectRes.Mode == "COMMON" && (selectRes.UserLimit > 0 || selectRes.PeriodLimit > 0) {
+			if err := s.checkLimits(tx, promoID, userID, selectRes.Schedule, selectRes.UserLimit, selectRes.UserLimitPeriod, selectRes.PeriodLimit, selectRes.LimitPeriod); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Raw(queryActivate, args...).Scan(&res).Error; err != nil {
+			return err
+		}
+
+		if res == (result{}) {
+			return errorz.Forbidden
+		}
+
+		now := time.Now()
+		if err := tx.Exec(`INSERT INTO activations (user_id, promo_id, created_at, code) VALUES (?, ?, ?, ?)`, userID, promoID, now, res.Promocode).Error; err != nil {
+			return err
+		}
 
-	err := s.db.WithContext(ctx).Exec(`INSERT INTO activations (user_id, promo_id, created_at) VALUES (?, ?, ?)`, userID, promoID, time.Now()).Error
+		activated := dto.PromoActivatedEvent{UserID: userID, Country: strings.ToLower(country.Alpha2()), ActivatedAt: now}
+		if err := insertEvent(tx, dto.EventPromoActivated, promoID, activated); err != nil {
+			return err
+		}
+
+		if !res.Exhausted {
+			return nil
+		}
+
+		// У COMMON-промо active уже снят в common_update
+		if selectRes.Mode != "COMMON" {
+			if err := tx.Exec(`UPDATE promos SET active = FALSE WHERE promo_id = ?`, promoID).Error; err != nil {
+				return err
+			}
+		}
+
+		return insertEvent(tx, dto.EventPromoExhausted, promoID, dto.PromoExhaustedEvent{ExhaustedAt: now})
+	})
+	if errors.Is(err, errorz.Forbidden) && selectRes.Mode != "COMMON" {
+		s.deactivateExhausted(ctx, promoID)
+	}
 	if err != nil {
 		return "", err
 	}
 
 	return res.Promocode, nil
 }
+
+// deactivateExhausted is a method that clears the active flag of a UNIQUE promo without codes left and writes PromoExhausted.
+/*
+ * Concurrent activations taking the last codes each see the other's code as still available and all miss the exhaustion,
+ * the next activation finding no code catches it here.
+ */
+func (s *activationStorage) deactivateExhausted(ctx context.Context, promoID string) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Exec(`
+			UPDATE promos p
+			SET active = FALSE
+			WHERE p.promo_id = ?
+			  AND p.mode = 'UNIQUE'
+			  AND p.active
+			  AND NOT EXISTS(SELECT 1 FROM promo_uniques pu WHERE pu.promo_id = p.promo_id AND NOT pu.activated)`, promoID)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+
+		return insertEvent(tx, dto.EventPromoExhausted, promoID, dto.PromoExhaustedEvent{ExhaustedAt: time.Now()})
+	})
+	if err != nil {
+		logger.Log.Errorf("failed to deactivate exhausted promo %s: %v", promoID, err)
+	}
+}
+
+// checkLimits is a method that returns errorz.LimitError if the user can't activate the COMMON promo in the current periods.
+/*
+ * Locks the promo row until the end of tx, so concurrent activations of the promo are counted one after another.
+ */
+func (s *activationStorage) checkLimits(tx *gorm.DB, promoID, userID string, scheduleJSON *string, userLimit int, userLimitPeriod string, periodLimit int, limitPeriod string) error {
+	if err := tx.Exec(`SELECT 1 FROM promos WHERE promo_id = ? FOR UPDATE`, promoID).Error; err != nil {
+		return err
+	}
+
+	// Границы периодов считаются в часовом поясе расписания
+	promoSchedule, err := schedule.Unmarshal(scheduleJSON)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	userFrom, userRetry := schedule.Period(userLimitPeriod, promoSchedule, now)
+	periodFrom, periodRetry := schedule.Period(limitPeriod, promoSchedule, now)
+
+	var counts struct {
+		UserCount   int
+		PeriodCount int
+	}
+	query := `
+		SELECT COUNT(*) FILTER (WHERE a.user_id = ? AND a.created_at >= ?) AS user_count,
+			   COUNT(*) FILTER (WHERE a.created_at >= ?)                   AS period_count
+		FROM activations a
+		WHERE a.promo_id = ?`
+	if err := tx.Raw(query, userID, userFrom, periodFrom, promoID).Scan(&counts).Error; err != nil {
+		return err
+	}
+
+	if userLimit > 0 && counts.UserCount >= userLimit {
+		return &errorz.LimitError{Scope: "user", RetryAt: userRetry}
+	}
+
+	if periodLimit > 0 && counts.PeriodCount >= periodLimit {
+		return &errorz.LimitError{Scope: "period", RetryAt: periodRetry}
+	}
+
+	return nil
+}
@@ -0,0 +1,283 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/biter777/countries"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ageBands is an ordered list of age bands of analytics breakdowns, matches ageBandExpression.
+var ageBands = []string{"<18", "18-24", "25-34", "35-44", "45-54", "55+"}
+
+// ageBandExpression is an SQL expression of the age band of the user u.
+const ageBandExpression = `CASE
+	WHEN u.age < 18 THEN '<18'
+	WHEN u.age < 25 THEN '18-24'
+	WHEN u.age < 35 THEN '25-34'
+	WHEN u.age < 45 THEN '35-44'
+	WHEN u.age < 55 THEN '45-54'
+	ELSE '55+'
+	END`
+
+// GetAnalytics is a method that returns activations, likes, comments and views of a promo in [from, to) bucketed in location.
+/*
+ * bucket is hour, day or week (from Monday), buckets without events are returned with zero counts.
+ */
+func (s *promoStorage) GetAnalytics(ctx context.Context, promoID string, from, to time.Time, bucket string, location *time.Location) (dto.PromoAnalyticsResponse, error) {
+	// Активации с разбивкой: бакет, страна, возрастная группа, новый или вернувшийся к компании пользователь
+	activationsQuery := `
+		SELECT date_trunc(?, a.created_at AT TIME ZONE ?) AS bucket,
+			   u.country,
+			   %s                                    AS age_band,
+			   EXISTS(SELECT 1
+					  FROM activations pa
+							   INNER JOIN promos pp ON pp.promo_id = pa.promo_id
+					  WHERE pa.user_id = a.user_id
+						AND pp.company_id = p.company_id
+						AND pa.created_at < a.created_at) AS is_returning,
+			   COUNT(*)                                  AS count
+		FROM activations a
+				 INNER JOIN promos p ON p.promo_id = a.promo_id
+				 INNER JOIN users u ON u.id = a.user_id
+		WHERE a.promo_id = ?
+		  AND a.created_at >= ?
+		  AND a.created_at < ?
+		GROUP BY 1, 2, 3, 4`
+	activationsQuery = fmt.Sprintf(activationsQuery, ageBandExpression)
+
+	likesQuery := `
+		SELECT date_trunc(?, l.liked_at AT TIME ZONE ?) AS bucket,
+			   COUNT(*)                                AS count
+		FROM likes l
+		WHERE l.promo_id = ?
+		  AND l."like"
+		  AND l.liked_at >= ?
+		  AND l.liked_at < ?
+		GROUP BY 1`
+
+	commentsQuery := `
+		SELECT date_trunc(?, c.created_at AT TIME ZONE ?) AS bucket,
+			   COUNT(*)                                  AS count
+		FROM comments c
+		WHERE c.promo_id = ?
+		  AND c.status = 'published'
+		  AND c.created_at >= ?
+		  AND c.created_at < ?
+		GROUP BY 1`
+
+	viewsQuery := `
+		SELECT date_trunc(?, v.created_at AT TIME ZONE ?) AS bucket,
+			   v.kind,
+			   COUNT(*)                                  AS count
+		FROM promo_views v
+		WHERE v.promo_id = ?
+		  AND v.created_at >= ?
+		  AND v.created_at < ?
+		GROUP BY 1, 2`
+
+	// Воронка по уникальным пользователям: показ -> просмотр -> активация не раньше дня первого просмотра
+	funnelQuery := `
+		WITH seen AS (SELECT v.user_id,
+							 bool_or(v.kind = 'impression')           AS impressed,
+							 MIN(v.day) FILTER (WHERE v.kind = 'view') AS viewed_on
+					  FROM promo_views v
+					  WHERE v.promo_id = ?
+						AND v.created_at >= ?
+						AND v.created_at < ?
+					  GROUP BY v.user_id)
+		SELECT COUNT(*) FILTER (WHERE s.impressed) AS impressions,
+			   COUNT(s.viewed_on)                  AS views,
+			   COUNT(*) FILTER (WHERE s.viewed_on IS NOT NULL AND EXISTS(SELECT 1
+																		 FROM activations a
+																		 WHERE a.promo_id = ?
+																		   AND a.user_id = s.user_id
+																		   AND a.created_at >= s.viewed_on::timestamp AT TIME ZONE 'UTC'
+																		   AND a.created_at < ?)) AS activations
+		FROM seen s`
+
+	type activationRow struct {
+		Bucket      time.Time
+		Country     countries.CountryCode
+		AgeBand     string
+		IsReturning bool
+		Count       int
+	}
+
+	type countRow struct {
+		Bucket time.Time
+		Count  int
+	}
+
+	type viewRow struct {
+		Bucket time.Time
+		Kind   string
+		Count  int
+	}
+
+	tz := location.String()
+
+	var activations []activationRow
+	if err := s.db.WithContext(ctx).Raw(activationsQuery, bucket, tz, promoID, from, to).Scan(&activations).Error; err != nil {
+		return dto.PromoAnalyticsResponse{}, err
+	}
+
+	var likes, comments []countRow
+	if err := s.db.WithContext(ctx).Raw(likesQuery, bucket, tz, promoID, from, to).Scan(&likes).Error; err != nil {
+		return dto.PromoAnalyticsResponse{}, err
+	}
+	if err := s.db.WithContext(ctx).Raw(commentsQuery, bucket, tz, promoID, from, to).Scan(&comments).Error; err != nil {
+		return dto.PromoAnalyticsResponse{}, err
+	}
+
+	var views []viewRow
+	if err := s.db.WithContext(ctx).Raw(viewsQuery, bucket, tz, promoID, from, to).Scan(&views).Error; err != nil {
+		return dto.PromoAnalyticsResponse{}, err
+	}
+
+	var funnel dto.ViewFunnel
+	if err := s.db.WithContext(ctx).Raw(funnelQuery, promoID, from, to, promoID, to).Scan(&funnel).Error; err != nil {
+		return dto.PromoAnalyticsResponse{}, err
+	}
+	if funnel.Impressions > 0 {
+		viewRate := float64(funnel.Views) / float64(funnel.Impressions)
+		funnel.ViewRate = &viewRate
+	}
+	if funnel.Views > 0 {
+		activationRate := float64(funnel.Activations) / float64(funnel.Views)
+		funnel.ActivationRate = &activationRate
+	}
+
+	response := dto.PromoAnalyticsResponse{
+		From:      from,
+		To:        to,
+		Bucket:    bucket,
+		Timezone:  tz,
+		Funnel:    funnel,
+		Countries: []dto.AnalyticsBreakdown{},
+		AgeBands:  []dto.AnalyticsBreakdown{},
+	}
+
+	// Пустые бакеты тоже возвращаются, индекс - по местному времени начала бакета
+	index := make(map[time.Time]int)
+	for start := truncateBucket(from, bucket, location); start.Before(to); start = nextBucket(start, bucket) {
+		index[wallClock(start)] = len(response.Series)
+		response.Series = append(response.Series, dto.AnalyticsPoint{Time: start})
+	}
+
+	byCountry := make(map[string]*dto.AnalyticsBreakdown)
+	byAgeBand := make(map[string]*dto.AnalyticsBreakdown)
+	add := func(breakdowns map[string]*dto.AnalyticsBreakdown, key string, row activationRow) {
+		breakdown, ok := breakdowns[key]
+		if !ok {
+			breakdown = &dto.AnalyticsBreakdown{Key: key}
+			breakdowns[key] = breakdown
+		}
+		breakdown.Activations += row.Count
+		if row.IsReturning {
+			breakdown.ReturningUsers += row.Count
+		} else {
+			breakdown.NewUsers += row.Count
+		}
+	}
+
+	count := func(counts *dto.AnalyticsCounts, row activationRow) {
+		counts.Activations += row.Count
+		if row.IsReturning {
+			counts.ReturningUsers += row.Count
+		} else {
+			counts.NewUsers += row.Count
+		}
+	}
+
+	for _, row := range activations {
+		count(&response.Totals, row)
+		if i, ok := index[row.Bucket]; ok {
+			count(&response.Series[i].AnalyticsCounts, row)
+		}
+		add(byCountry, strings.ToLower(row.Country.Alpha2()), row)
+		add(byAgeBand, row.AgeBand, row)
+	}
+
+	for _, row := range likes {
+		response.Totals.Likes += row.Count
+		if i, ok := index[row.Bucket]; ok {
+			response.Series[i].Likes += row.Count
+		}
+	}
+
+	for _, row := range comments {
+		response.Totals.Comments += row.Count
+		if i, ok := index[row.Bucket]; ok {
+			response.Series[i].Comments += row.Count
+		}
+	}
+
+	countView := func(counts *dto.AnalyticsCounts, row viewRow) {
+		if row.Kind == entity.ViewKindImpression {
+			counts.Impressions += row.Count
+		} else {
+			counts.Views += row.Count
+		}
+	}
+
+	for _, row := range views {
+		countView(&response.Totals, row)
+		if i, ok := index[row.Bucket]; ok {
+			countView(&response.Series[i].AnalyticsCounts, row)
+		}
+	}
+
+	for _, breakdown := range byCountry {
+		response.Countries = append(response.Countries, *breakdown)
+	}
+	slices.SortFunc(response.Countries, func(a, b dto.AnalyticsBreakdown) int {
+		if a.Activations != b.Activations {
+			return b.Activations - a.Activations
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	for _, band := range ageBands {
+		if breakdown, ok := byAgeBand[band]; ok {
+			response.AgeBands = append(response.AgeBands, *breakdown)
+		}
+	}
+
+	return response, nil
+}
+
+// truncateBucket is a function that returns the start of the bucket containing t in location.
+func truncateBucket(t time.Time, bucket string, location *time.Location) time.Time {
+	local := t.In(location)
+	switch bucket {
+	case "hour":
+		return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, location)
+	case "week":
+		weekday := int(local.Weekday()+6) % 7 // дней с понедельника
+		return time.Date(local.Year(), local.Month(), local.Day()-weekday, 0, 0, 0, 0, location)
+	}
+
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+}
+
+// nextBucket is a function that returns the start of the bucket after the one starting at start.
+func nextBucket(start time.Time, bucket string) time.Time {
+	switch bucket {
+	case "hour":
+		return start.Add(time.Hour)
+	case "week":
+		return start.AddDate(0, 0, 7)
+	}
+
+	return start.AddDate(0, 0, 1)
+}
+
+// wallClock is a function that returns the local wall time of t labelled as UTC, the way timestamps without a zone are scanned.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
@@ -53,3 +53,18 @@ func (s *businessStorage) GetByEmail(ctx context.Context, email string) (*entity
 	err := s.db.WithContext(ctx).Where("email = ?", email).First(&business).Error
 	return business, err
 }
+
+// GetNames is a method that returns company names by ids.
+func (s *businessStorage) GetNames(ctx context.Context, ids []string) (map[string]string, error) {
+	var businesses []entity.Business
+	if err := s.db.WithContext(ctx).Model(&entity.Business{}).Select("id", "name").Where("id IN ?", ids).Find(&businesses).Error; err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(businesses))
+	for _, business := range businesses {
+		names[business.ID] = business.Name
+	}
+
+	return names, nil
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/entity"
+	"time"
+)
+
+// codeImportStorage is a struct that contains a pointer to a gorm.DB instance to track CSV imports of unique codes.
+type codeImportStorage struct {
+	db *gorm.DB
+}
+
+// NewCodeImportStorage is a function that returns a new instance of codeImportStorage.
+func NewCodeImportStorage(db *gorm.DB) *codeImportStorage {
+	return &codeImportStorage{db: db}
+}
+
+// Create is a method to create a new CodeImport in database.
+func (s *codeImportStorage) Create(ctx context.Context, codeImport entity.CodeImport) (*entity.CodeImport, error) {
+	err := s.db.WithContext(ctx).Create(&codeImport).Error
+	return &codeImport, err
+}
+
+// Update is a method that saves the status and progress of a CodeImport.
+func (s *codeImportStorage) Update(ctx context.Context, codeImport *entity.CodeImport) error {
+	return s.db.WithContext(ctx).Model(codeImport).Select(
+		"Status", "TotalRows", "Processed", "Added", "Duplicates", "Invalid", "RowErrors", "Error", "FinishedAt",
+	).Updates(codeImport).Error
+}
+
+// GetByID is a method that returns an import of the promo owned by the company.
+func (s *codeImportStorage) GetByID(ctx context.Context, importID, promoID, companyID string) (*entity.CodeImport, error) {
+	var codeImport entity.CodeImport
+	err := s.db.WithContext(ctx).
+		Where("import_id = ? AND promo_id = ? AND company_id = ?", importID, promoID, companyID).
+		First(&codeImport).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errorz.NotFound
+	}
+
+	return &codeImport, err
+}
+
+// Touch is a method that marks pending and running imports as saved now, so that FailStale doesn't take them for stale.
+func (s *codeImportStorage) Touch(ctx context.Context, importIDs []string) error {
+	return s.db.WithContext(ctx).Exec(`UPDATE code_imports SET updated_at = now() WHERE import_id IN ? AND status IN ('pending', 'running')`, importIDs).Error
+}
+
+// FailStale is a method that fails pending and running imports not saved since before and returns how many it failed.
+func (s *codeImportStorage) FailStale(ctx context.Context, before time.Time, reason string) (int64, error) {
+	query := `
+		UPDATE code_imports
+		SET status      = 'failed',
+			error       = ?,
+			finished_at = now(),
+			updated_at  = now()
+		WHERE status IN ('pending', 'running')
+		  AND updated_at < ?`
+
+	res := s.db.WithContext(ctx).Exec(query, reason, before)
+	return res.RowsAffected, res.Error
+}
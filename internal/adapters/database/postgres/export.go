@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"prod/internal/domain/dto"
+)
+
+// ExportActivations is a method that calls write for each activation matching the filter in order of time.
+/*
+ * Rows are read from a cursor, not loaded at once, so an export of any size takes constant memory.
+ * The code of an activation made before codes were stored is the current code of a COMMON promo and empty for a UNIQUE one.
+ */
+func (s *promoStorage) ExportActivations(ctx context.Context, filter dto.ActivationExportFilter, write func(row dto.ActivationExportRow) error) error {
+	query := `
+		SELECT a.activation_id,
+			   a.created_at,
+			   a.promo_id,
+			   a.user_id,
+			   u.country,
+			   %s AS age_band,
+			   COALESCE(a.code, CASE WHEN p.mode = 'COMMON' THEN p.promo_common END, '') AS code
+		FROM activations a
+				 INNER JOIN promos p ON p.promo_id = a.promo_id
+				 INNER JOIN users u ON u.id = a.user_id`
+	condition, args := exportCondition(filter)
+	query = fmt.Sprintf(query, ageBandExpression) + condition + ` ORDER BY a.created_at, a.activation_id`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.WithContext(ctx).Raw(query, args...).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row dto.ActivationExportRow
+		if err = s.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err = write(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// CountActivations is a method that returns the number of activations matching the filter, the limit is ignored.
+func (s *promoStorage) CountActivations(ctx context.Context, filter dto.ActivationExportFilter) (int64, error) {
+	condition, args := exportCondition(filter)
+
+	var count int64
+	err := s.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM activations a INNER JOIN promos p ON p.promo_id = a.promo_id`+condition, args...).Scan(&count).Error
+	return count, err
+}
+
+// exportCondition is a function that returns the WHERE clause of activations (alias a) of promos (alias p) matching the filter.
+func exportCondition(filter dto.ActivationExportFilter) (string, []interface{}) {
+	condition := ` WHERE p.company_id = ?`
+	args := []interface{}{filter.CompanyID}
+
+	if filter.PromoID != "" {
+		condition += ` AND a.promo_id = ?`
+		args = append(args, filter.PromoID)
+	}
+	if filter.From != nil {
+		condition += ` AND a.created_at >= ?`
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		condition += ` AND a.created_at < ?`
+		args = append(args, *filter.To)
+	}
+
+	return condition, args
+}
@@ -9,7 +9,116 @@ var Migrations = []interface{}{
 	&entity.Promo{},
 	&entity.PromoUnique{},
 	&entity.Category{},
+	&entity.PromoCountry{},
+	&entity.PromoAffinity{},
 	&entity.Likes{},
 	&entity.Comment{},
+	&entity.CommentReport{},
 	&entity.Activation{},
+	&entity.PromoView{},
+	&entity.CodeImport{},
+	&entity.PromoDailyStats{},
+	&entity.StatsRollup{},
+	&entity.Webhook{},
+	&entity.WebhookDelivery{},
+	&entity.OutboxEvent{},
+	&entity.Notification{},
+	&entity.NotificationPreference{},
+	&entity.FavoriteCompany{},
+}
+
+// RawMigrations is a list of SQL statements that gorm can't express, run after Migrations.
+/*
+ * Every statement must be idempotent. Data backfills that must not run again check and set a name in schema_markers.
+ */
+var RawMigrations = []string{
+	// Full-text search over description, company name and categories
+	`ALTER TABLE promos ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+	`CREATE INDEX IF NOT EXISTS idx_promos_search_vector ON promos USING GIN (search_vector)`,
+	`UPDATE promos p SET search_vector = ` + searchVectorExpression + ` FROM businesses b WHERE b.id = p.company_id AND p.search_vector IS NULL`,
+
+	// Keyset pagination: (sort column, id) must be covered by an index
+	`CREATE INDEX IF NOT EXISTS idx_promos_created_at_id ON promos (created_at DESC, promo_id DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_promos_company_created_at_id ON promos (company_id, created_at DESC, promo_id DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_promos_company_active_from_id ON promos (company_id, active_from DESC, promo_id DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_promos_company_active_until_id ON promos (company_id, active_until DESC, promo_id DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_activations_user_created_at_id ON activations (user_id, created_at DESC, activation_id DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_promo_created_at_id ON comments (promo_id, created_at DESC, comment_id DESC)`,
+
+	// Per-row lookups of the feed and history: likes, activations and categories of a promo
+	`CREATE INDEX IF NOT EXISTS idx_likes_user_promo ON likes (user_id, promo_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_activations_user_promo ON activations (user_id, promo_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_activations_promo_created_at ON activations (promo_id, created_at)`,
+	`CREATE INDEX IF NOT EXISTS idx_categories_promo_index ON categories (promo_id, index)`,
+
+	// Unique-code inventory: duplicate checks and available codes of a promo
+	`CREATE INDEX IF NOT EXISTS idx_promo_uniques_promo_body ON promo_uniques (promo_id, body)`,
+	`CREATE INDEX IF NOT EXISTS idx_promo_uniques_promo_activated_index ON promo_uniques (promo_id, activated, index)`,
+
+	// One-off data backfills record their names here and are skipped on the next boots
+	`CREATE TABLE IF NOT EXISTS schema_markers (name TEXT PRIMARY KEY, applied_at TIMESTAMPTZ NOT NULL DEFAULT now())`,
+
+	// active only reflects the stock now, dates are checked through the status
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM schema_markers WHERE name = 'promos_active_by_stock') THEN
+			UPDATE promos p
+			SET active = TRUE
+			WHERE NOT p.active
+			  AND p.archived_at IS NULL
+			  AND p.deleted_at IS NULL
+			  AND p.active_until > now()
+			  AND CASE
+					  WHEN p.mode = 'COMMON' THEN p.used_count < p.max_count
+					  ELSE EXISTS(SELECT 1 FROM promo_uniques pu WHERE pu.promo_id = p.promo_id AND NOT pu.activated)
+				  END;
+			INSERT INTO schema_markers (name) VALUES ('promos_active_by_stock') ON CONFLICT DO NOTHING;
+		END IF;
+	END $$`,
+
+	// Webhook deliveries keep only the status of a failed response, bodies saved before are dropped
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM schema_markers WHERE name = 'webhook_deliveries_no_body') THEN
+			UPDATE webhook_deliveries
+			SET last_error = 'unexpected status ' || last_status
+			WHERE last_status IS NOT NULL
+			  AND (last_status < 200 OR last_status > 299);
+			INSERT INTO schema_markers (name) VALUES ('webhook_deliveries_no_body') ON CONFLICT DO NOTHING;
+		END IF;
+	END $$`,
+
+	// Promo analytics: likes by the time they were set
+	`CREATE INDEX IF NOT EXISTS idx_likes_promo_liked_at ON likes (promo_id, liked_at) WHERE "like"`,
+
+	// Outbox: events waiting to be published and published ones to clean up
+	`CREATE INDEX IF NOT EXISTS idx_outbox_events_due ON outbox_events (next_attempt_at, seq) WHERE published_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_outbox_events_published_at ON outbox_events (published_at) WHERE published_at IS NOT NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_outbox_events_like_toggled ON outbox_events (created_at) WHERE type = 'LikeToggled'`,
+
+	// Notifications: the unread badge, and live promos by end date for the expiring sweep
+	`CREATE INDEX IF NOT EXISTS idx_notifications_user_unread ON notifications (user_id) WHERE read_at IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_promos_live_active_until ON promos (active_until) WHERE status = 'live' AND deleted_at IS NULL AND archived_at IS NULL`,
+
+	// Threaded comments: official replies have no user, threads are listed by their top-level comments and replies
+	`ALTER TABLE comments ALTER COLUMN user_id DROP NOT NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_promo_top_created_at_id ON comments (promo_id, created_at DESC, comment_id DESC) WHERE parent_id IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_parent_created_at_id ON comments (parent_id, created_at, comment_id)`,
+
+	// Comment moderation: the queue of a promo, and recent comments of a user for the rate limit
+	`CREATE INDEX IF NOT EXISTS idx_comments_promo_moderation ON comments (promo_id, status, created_at, comment_id) WHERE status <> 'published'`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_user_created_at ON comments (user_id, created_at) WHERE user_id IS NOT NULL`,
+
+	// Dashboard: comments are rolled up by the time they were first published, earlier ones were rolled up by the time they were written
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM schema_markers WHERE name = 'comments_published_at') THEN
+			UPDATE comments
+			SET published_at = created_at
+			WHERE status = 'published'
+			  AND published_at IS NULL;
+			INSERT INTO schema_markers (name) VALUES ('comments_published_at') ON CONFLICT DO NOTHING;
+		END IF;
+	END $$`,
+	`CREATE INDEX IF NOT EXISTS idx_comments_published_at ON comments (published_at) WHERE status = 'published'`,
 }
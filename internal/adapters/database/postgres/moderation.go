@@ -0,0 +1,298 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/utils/cursor"
+	"slices"
+	"time"
+)
+
+// commentState is a comment locked by lockComment.
+type commentState struct {
+	Status     string
+	ParentID   *string
+	ReplyCount int
+	UserID     *string
+	CompanyID  *string
+	Official   bool
+	Text       string
+	CreatedAt  time.Time
+	RootStatus string // of the top-level comment of the thread, of the comment itself if it is top-level
+}
+
+// lockComment is a function that locks the top-level comment of the thread and the comment until the end of the transaction.
+/*
+ * The thread is always locked before its replies, so changes of a reply and of its thread don't deadlock.
+ * Returns errorz.NotFound if there is no such comment of the promo.
+ */
+func lockComment(tx *gorm.DB, promoID, commentID string) (commentState, error) {
+	var parents []struct{ ParentID *string }
+	if err := tx.Raw(`SELECT parent_id FROM comments WHERE comment_id = ? AND promo_id = ?`, commentID, promoID).Scan(&parents).Error; err != nil {
+		return commentState{}, err
+	}
+	if len(parents) == 0 {
+		return commentState{}, errorz.NotFound
+	}
+
+	var rootStatuses []string
+	if parents[0].ParentID != nil {
+		if err := tx.Raw(`SELECT status FROM comments WHERE comment_id = ? FOR UPDATE`, *parents[0].ParentID).Scan(&rootStatuses).Error; err != nil {
+			return commentState{}, err
+		}
+	}
+
+	var states []commentState
+	query := `
+		SELECT status, parent_id, reply_count, user_id, company_id, official, text, created_at
+		FROM comments
+		WHERE comment_id = ?
+		FOR UPDATE`
+	if err := tx.Raw(query, commentID).Scan(&states).Error; err != nil {
+		return commentState{}, err
+	}
+	if len(states) == 0 {
+		return commentState{}, errorz.NotFound
+	}
+
+	state := states[0]
+	state.RootStatus = state.Status
+	if state.ParentID != nil {
+		state.RootStatus = ""
+		if len(rootStatuses) > 0 {
+			state.RootStatus = rootStatuses[0]
+		}
+	}
+
+	return state, nil
+}
+
+// countComment is a function that adds a comment becoming published to the counters, or subtracts it with delta -1.
+/*
+ * A reply is counted in ReplyCount of its thread, and in the promo only while the thread is published.
+ * A top-level comment brings its published replies to the promo with it.
+ */
+func countComment(tx *gorm.DB, promoID string, state commentState, delta int) error {
+	queryCounter := `UPDATE promos SET comment_count = comment_count + ? WHERE promo_id = ?`
+
+	if state.ParentID == nil {
+		return tx.Exec(queryCounter, delta*(1+state.ReplyCount), promoID).Error
+	}
+
+	if err := tx.Exec(`UPDATE comments SET reply_count = reply_count + ? WHERE comment_id = ?`, delta, *state.ParentID).Error; err != nil {
+		return err
+	}
+	if state.RootStatus != dto.CommentPublished {
+		return nil
+	}
+
+	return tx.Exec(queryCounter, delta, promoID).Error
+}
+
+// SetCommentStatus is a method that moves a comment of the promo from one of the statuses to another with its counters.
+/*
+ * Setting the current status again is a no-op, other statuses return errorz.Conflict.
+ * A published comment writes CommentAdded, so webhooks and notifications only see comments that passed moderation.
+ */
+func (s *actionsStorage) SetCommentStatus(ctx context.Context, promoID, commentID string, from []string, status, reason string) error {
+	queryUpdate := `
+		UPDATE comments
+		SET status            = ?,
+			moderation_reason = ?,
+			moderated_at      = now()
+		WHERE comment_id = ?`
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		state, err := lockComment(tx, promoID, commentID)
+		if err != nil {
+			return err
+		}
+
+		if state.Status == status {
+			return nil
+		}
+		if !slices.Contains(from, state.Status) {
+			return errorz.Conflict
+		}
+
+		if status == dto.CommentPublished {
+			reason = ""
+		}
+		if err = tx.Exec(queryUpdate, status, reason, commentID).Error; err != nil {
+			return err
+		}
+
+		// Одобренный комментарий снова уходит на модерацию только по жалобам новых пользователей.
+		// В статистику он попадает в день первой публикации, повторное одобрение его не пересчитывает
+		if status == dto.CommentPublished {
+			if err = tx.Exec(`UPDATE comments SET report_count = 0, published_at = COALESCE(published_at, now()) WHERE comment_id = ?`, commentID).Error; err != nil {
+				return err
+			}
+		}
+
+		if state.Status == dto.CommentPublished {
+			return countComment(tx, promoID, state, -1)
+		}
+		if status != dto.CommentPublished {
+			return nil
+		}
+
+		if err = countComment(tx, promoID, state, 1); err != nil {
+			return err
+		}
+
+		return insertEvent(tx, dto.EventCommentAdded, promoID, dto.CommentAddedEvent{
+			CommentID: commentID,
+			ParentID:  state.ParentID,
+			UserID:    state.UserID,
+			CompanyID: state.CompanyID,
+			Official:  state.Official,
+			Text:      state.Text,
+			CreatedAt: state.CreatedAt,
+		})
+	})
+}
+
+// ReportComment is a method that reports a published comment, it goes to the moderation queue once threshold users reported it.
+/*
+ * A repeated report of the user is a no-op. Returns errorz.NotFound if the comment is not published.
+ */
+func (s *actionsStorage) ReportComment(ctx context.Context, userID, promoID, commentID, reason string, threshold int) error {
+	queryInsert := `
+		INSERT INTO comment_reports (comment_id, user_id, reason, created_at)
+		VALUES (?, ?, ?, now())
+		ON CONFLICT (comment_id, user_id) DO NOTHING`
+
+	queryCount := `UPDATE comments SET report_count = report_count + 1 WHERE comment_id = ? RETURNING report_count`
+
+	queryHold := `
+		UPDATE comments
+		SET status            = ?,
+			moderation_reason = ?,
+			moderated_at      = now()
+		WHERE comment_id = ?`
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		state, err := lockComment(tx, promoID, commentID)
+		if err != nil {
+			return err
+		}
+		if state.Status != dto.CommentPublished {
+			return errorz.NotFound
+		}
+
+		inserted := tx.Exec(queryInsert, commentID, userID, reason)
+		if inserted.Error != nil {
+			return inserted.Error
+		}
+		if inserted.RowsAffected == 0 {
+			return nil
+		}
+
+		var reportCount int
+		if err = tx.Raw(queryCount, commentID).Scan(&reportCount).Error; err != nil {
+			return err
+		}
+		if reportCount < threshold {
+			return nil
+		}
+
+		if err = tx.Exec(queryHold, dto.CommentPending, dto.ModerationReasonReports, commentID).Error; err != nil {
+			return err
+		}
+
+		return countComment(tx, promoID, state, -1)
+	})
+}
+
+// checkCommentRate is a function that returns errorz.LimitError if the user wrote limit comments within window.
+/*
+ * Comments of the user are serialized by an advisory lock until the end of tx, so parallel requests
+ * can't all pass the count before any of them inserts its comment.
+ */
+func checkCommentRate(tx *gorm.DB, userID string, limit int64, window time.Duration) error {
+	if err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('comment-rate:' || ?))`, userID).Error; err != nil {
+		return err
+	}
+
+	var result struct {
+		Count  int64
+		Oldest *time.Time
+	}
+
+	query := `SELECT COUNT(*) AS count, MIN(created_at) AS oldest FROM comments WHERE user_id = ? AND created_at > ?`
+	if err := tx.Raw(query, userID, time.Now().Add(-window)).Scan(&result).Error; err != nil {
+		return err
+	}
+
+	if result.Count < limit || result.Oldest == nil {
+		return nil
+	}
+
+	retryAt := result.Oldest.Add(window).UTC()
+	return &errorz.LimitError{Scope: "comment", RetryAt: &retryAt}
+}
+
+// GetModerationQueue is a method that returns a page of comments of the company's promos with the status, oldest first.
+func (s *actionsStorage) GetModerationQueue(ctx context.Context, companyID, promoID, status string, page dto.Page) ([]dto.ModerationComment, string, int64, error) {
+	where := `
+		WHERE c.status = ?
+		  AND EXISTS(SELECT 1 FROM promos p WHERE p.promo_id = c.promo_id AND p.company_id = ? AND p.deleted_at IS NULL)`
+	args := []interface{}{status, companyID}
+
+	if promoID != "" {
+		where += ` AND c.promo_id = ?`
+		args = append(args, promoID)
+	}
+
+	query := commentSelect + where
+	pageArgs := append([]interface{}{}, args...)
+
+	if page.Cursor != nil {
+		query += ` AND (c.created_at, c.comment_id) > (?, ?)`
+		pageArgs = append(pageArgs, page.Cursor.Time, page.Cursor.ID)
+	}
+
+	query += `
+		ORDER BY c.created_at, c.comment_id
+		LIMIT ? OFFSET ?`
+	pageArgs = append(pageArgs, page.Limit+1, page.Offset)
+
+	var results []commentRow
+
+	if err := s.db.WithContext(ctx).Raw(query, pageArgs...).Scan(&results).Error; err != nil {
+		return nil, "", 0, err
+	}
+
+	var nextCursor string
+	if len(results) > page.Limit {
+		results = results[:page.Limit]
+		last := results[len(results)-1]
+		nextCursor = cursor.Encode(cursor.Cursor{Time: last.CreatedAt, ID: last.CommentID})
+	}
+
+	comments := make([]dto.ModerationComment, 0, len(results))
+
+	for _, r := range results {
+		comments = append(comments, dto.ModerationComment{
+			Comment:          r.toDTO(),
+			PromoID:          r.PromoID,
+			ModerationReason: r.ModerationReason,
+			ReportCount:      r.ReportCount,
+		})
+	}
+
+	if !page.NeedTotal() {
+		return comments, nextCursor, 0, nil
+	}
+
+	var total int64
+
+	if err := s.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM comments c`+where, args...).Scan(&total).Error; err != nil {
+		return nil, "", 0, err
+	}
+
+	return comments, nextCursor, total, nil
+}
@@ -0,0 +1,293 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/utils/cursor"
+	"time"
+)
+
+// notificationEnabled is an SQL condition of whether the user (alias u) hasn't turned off notifications of the type.
+const notificationEnabled = `NOT EXISTS(SELECT 1
+			 FROM notification_preferences np
+			 WHERE np.user_id = u.id
+			   AND np.type = ?
+			   AND NOT np.enabled)`
+
+// notificationStorage is a struct that contains a pointer to a gorm.DB instance to work with notifications.
+type notificationStorage struct {
+	db *gorm.DB
+}
+
+// NewNotificationStorage is a function that returns a new instance of notificationStorage.
+func NewNotificationStorage(db *gorm.DB) *notificationStorage {
+	return &notificationStorage{db: db}
+}
+
+// List is a method that returns a page of the user's notifications, newest first, with their promos and companies.
+func (s *notificationStorage) List(ctx context.Context, userID string, unread bool, page dto.Page) ([]dto.NotificationDTO, string, int64, error) {
+	where := ` WHERE n.user_id = ?`
+	args := []interface{}{userID}
+
+	if unread {
+		where += ` AND n.read_at IS NULL`
+	}
+
+	query := `
+		SELECT n.notification_id,
+			   n.type,
+			   n.created_at,
+			   n.read_at IS NOT NULL AS is_read,
+			   n.comment_id,
+			   p.promo_id,
+			   p.description,
+			   p.image_url,
+			   p.active_until,
+			   b.id   AS company_id,
+			   b.name AS company_name
+		FROM notifications n
+				 LEFT JOIN promos p ON p.promo_id = n.promo_id
+				 LEFT JOIN businesses b ON b.id = n.company_id` + where
+
+	pageArgs := append([]interface{}{}, args...)
+	if page.Cursor != nil {
+		query += ` AND (n.created_at, n.notification_id) < (?, ?)`
+		pageArgs = append(pageArgs, page.Cursor.Time, page.Cursor.ID)
+	}
+
+	query += `
+		ORDER BY n.created_at DESC, n.notification_id DESC
+		LIMIT ? OFFSET ?`
+	pageArgs = append(pageArgs, page.Limit+1, page.Offset)
+
+	type result struct {
+		NotificationID string
+		Type           string
+		CreatedAt      time.Time
+		IsRead         bool
+		CommentID      *string
+		PromoID        *string
+		Description    string
+		ImageURL       string
+		ActiveUntil    time.Time
+		CompanyID      *string
+		CompanyName    string
+	}
+
+	var results []result
+	if err := s.db.WithContext(ctx).Raw(query, pageArgs...).Scan(&results).Error; err != nil {
+		return nil, "", 0, err
+	}
+
+	var nextCursor string
+	if len(results) > page.Limit {
+		results = results[:page.Limit]
+		last := results[len(results)-1]
+		nextCursor = cursor.Encode(cursor.Cursor{Time: last.CreatedAt, ID: last.NotificationID})
+	}
+
+	notifications := make([]dto.NotificationDTO, 0, len(results))
+	for _, r := range results {
+		notification := dto.NotificationDTO{
+			ID:        r.NotificationID,
+			Type:      r.Type,
+			CreatedAt: r.CreatedAt,
+			Read:      r.IsRead,
+			CommentID: r.CommentID,
+		}
+		// Промо и компания могли быть удалены после уведомления
+		if r.PromoID != nil {
+			notification.Promo = &dto.NotificationPromo{ID: *r.PromoID, Description: r.Description, ImageURL: r.ImageURL, ActiveUntil: r.ActiveUntil}
+		}
+		if r.CompanyID != nil {
+			notification.Company = &dto.NotificationCompany{ID: *r.CompanyID, Name: r.CompanyName}
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if !page.NeedTotal() {
+		return notifications, nextCursor, 0, nil
+	}
+
+	var total int64
+	if err := s.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM notifications n`+where, args...).Scan(&total).Error; err != nil {
+		return nil, "", 0, err
+	}
+
+	return notifications, nextCursor, total, nil
+}
+
+// UnreadCount is a method that returns the number of the user's unread notifications.
+func (s *notificationStorage) UnreadCount(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL`, userID).Scan(&count).Error
+
+	return count, err
+}
+
+// MarkRead is a method that marks a notification of the user as read, reading it again keeps the first time.
+func (s *notificationStorage) MarkRead(ctx context.Context, userID, notificationID string) error {
+	query := `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, now())
+		WHERE notification_id = ?
+		  AND user_id = ?`
+
+	res := s.db.WithContext(ctx).Exec(query, notificationID, userID)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errorz.NotFound
+	}
+
+	return nil
+}
+
+// MarkAllRead is a method that marks all unread notifications of the user as read, returns how many were marked.
+func (s *notificationStorage) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	res := s.db.WithContext(ctx).Exec(`UPDATE notifications SET read_at = now() WHERE user_id = ? AND read_at IS NULL`, userID)
+
+	return res.RowsAffected, res.Error
+}
+
+// GetPreferences is a method that returns the user's preferences by type, types without one are not included.
+func (s *notificationStorage) GetPreferences(ctx context.Context, userID string) (map[string]bool, error) {
+	type result struct {
+		Type    string
+		Enabled bool
+	}
+
+	var results []result
+	if err := s.db.WithContext(ctx).Raw(`SELECT type, enabled FROM notification_preferences WHERE user_id = ?`, userID).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+
+	preferences := make(map[string]bool, len(results))
+	for _, r := range results {
+		preferences[r.Type] = r.Enabled
+	}
+
+	return preferences, nil
+}
+
+// SetPreferences is a method that saves the user's preferences of the given types.
+func (s *notificationStorage) SetPreferences(ctx context.Context, userID string, preferences map[string]bool) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+
+	rows := make([][]interface{}, 0, len(preferences))
+	for notificationType, enabled := range preferences {
+		rows = append(rows, []interface{}{userID, notificationType, enabled})
+	}
+
+	query := `
+		INSERT INTO notification_preferences (user_id, type, enabled)
+		VALUES ?
+		ON CONFLICT (user_id, type) DO UPDATE SET enabled = excluded.enabled`
+
+	return s.db.WithContext(ctx).Exec(query, rows).Error
+}
+
+// AddFavoriteCompany is a method that adds a company to the user's favorites, adding it again is a no-op.
+func (s *notificationStorage) AddFavoriteCompany(ctx context.Context, userID, companyID string) error {
+	query := `
+		INSERT INTO favorite_companies (user_id, company_id)
+		SELECT ?, b.id
+		FROM businesses b
+		WHERE b.id = ?
+		ON CONFLICT DO NOTHING`
+
+	res := s.db.WithContext(ctx).Exec(query, userID, companyID)
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		var exists bool
+		if err := s.db.WithContext(ctx).Raw(`SELECT EXISTS(SELECT 1 FROM businesses WHERE id = ?)`, companyID).Scan(&exists).Error; err != nil {
+			return err
+		}
+		if !exists {
+			return errorz.NotFound
+		}
+	}
+
+	return nil
+}
+
+// DeleteFavoriteCompany is a method that removes a company from the user's favorites.
+func (s *notificationStorage) DeleteFavoriteCompany(ctx context.Context, userID, companyID string) error {
+	return s.db.WithContext(ctx).Exec(`DELETE FROM favorite_companies WHERE user_id = ? AND company_id = ?`, userID, companyID).Error
+}
+
+// NotifyCompanyPromo is a method that notifies the users who have the company of the promo in favorites and are targeted by it.
+func (s *notificationStorage) NotifyCompanyPromo(ctx context.Context, promoID string) (int64, error) {
+	query := fmt.Sprintf(`
+		INSERT INTO notifications (user_id, type, key, promo_id, company_id)
+		SELECT u.id, ?, p.promo_id::text, p.promo_id, p.company_id
+		FROM promos p
+				 INNER JOIN favorite_companies f ON f.company_id = p.company_id
+				 INNER JOIN users u ON u.id = f.user_id
+		WHERE p.promo_id = ?
+		  AND p.deleted_at IS NULL
+		  AND p.archived_at IS NULL
+		  AND %s
+		  AND %s
+		ON CONFLICT DO NOTHING`, targetExpressionOf("u.age", "u.country", "u.id"), notificationEnabled)
+
+	res := s.db.WithContext(ctx).Exec(query, dto.NotificationCompanyPromo, promoID, dto.NotificationCompanyPromo)
+
+	return res.RowsAffected, res.Error
+}
+
+// NotifyCommentReply is a method that notifies the author of the thread a reply was added to, unless they replied themselves.
+func (s *notificationStorage) NotifyCommentReply(ctx context.Context, replyID string) (int64, error) {
+	query := fmt.Sprintf(`
+		INSERT INTO notifications (user_id, type, key, promo_id, company_id, comment_id)
+		SELECT u.id, ?, r.comment_id::text, p.promo_id, p.company_id, r.comment_id
+		FROM comments r
+				 INNER JOIN comments pc ON pc.comment_id = r.parent_id
+				 INNER JOIN users u ON u.id = pc.user_id
+				 INNER JOIN promos p ON p.promo_id = r.promo_id
+		WHERE r.comment_id = ?
+		  AND (r.user_id IS NULL OR r.user_id <> pc.user_id)
+		  AND %s
+		ON CONFLICT DO NOTHING`, notificationEnabled)
+
+	res := s.db.WithContext(ctx).Exec(query, dto.NotificationCommentReply, replyID, dto.NotificationCommentReply)
+
+	return res.RowsAffected, res.Error
+}
+
+// NotifyExpiringLikes is a method that notifies users of the live promos they liked and haven't activated that end within the duration.
+/*
+ * Each promo is notified of once per user, so the method can run as often as needed.
+ */
+func (s *notificationStorage) NotifyExpiringLikes(ctx context.Context, within time.Duration) (int64, error) {
+	query := fmt.Sprintf(`
+		INSERT INTO notifications (user_id, type, key, promo_id, company_id)
+		SELECT u.id, ?, p.promo_id::text, p.promo_id, p.company_id
+		FROM likes l
+				 INNER JOIN promos p ON p.promo_id = l.promo_id
+				 INNER JOIN users u ON u.id = l.user_id
+		WHERE l."like"
+		  AND p.active
+		  AND p.status = 'live'
+		  AND p.deleted_at IS NULL
+		  AND p.archived_at IS NULL
+		  AND p.active_from <= now()
+		  AND p.active_until > now()
+		  AND p.active_until <= ?
+		  AND NOT EXISTS(SELECT 1 FROM activations a WHERE a.user_id = u.id AND a.promo_id = p.promo_id)
+		  AND %s
+		ON CONFLICT DO NOTHING`, notificationEnabled)
+
+	res := s.db.WithContext(ctx).Exec(query, dto.NotificationPromoExpiring, time.Now().Add(within), dto.NotificationPromoExpiring)
+
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"gorm.io/gorm"
+	"prod/internal/domain/dto"
+	"slices"
+	"time"
+)
+
+// insertEvent is a function that writes a domain event of the promo to the outbox within tx.
+func insertEvent(tx *gorm.DB, eventType, promoID string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return tx.Exec(`INSERT INTO outbox_events (type, promo_id, payload) VALUES (?, ?, ?)`, eventType, promoID, string(body)).Error
+}
+
+// outboxStorage is a struct that contains a pointer to a gorm.DB instance to read the outbox.
+type outboxStorage struct {
+	db *gorm.DB
+}
+
+// NewOutboxStorage is a function that returns a new instance of outboxStorage.
+func NewOutboxStorage(db *gorm.DB) *outboxStorage {
+	return &outboxStorage{db: db}
+}
+
+// ClaimDue is a method that takes up to limit unpublished events whose time has come, in Seq order.
+/*
+ * Claimed events have next_attempt_at moved by lease, so other instances skip them while they are published,
+ * and events of a crashed instance are published again after the lease.
+ */
+func (s *outboxStorage) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]dto.DomainEvent, error) {
+	query := `
+		UPDATE outbox_events
+		SET next_attempt_at = ?
+		WHERE seq IN (SELECT seq
+					  FROM outbox_events
+					  WHERE published_at IS NULL
+						AND next_attempt_at <= now()
+					  ORDER BY seq
+					  LIMIT ? FOR UPDATE SKIP LOCKED)
+		RETURNING seq, event_id AS id, type, promo_id, created_at, payload, attempts`
+
+	var events []dto.DomainEvent
+	if err := s.db.WithContext(ctx).Raw(query, time.Now().Add(lease), limit).Scan(&events).Error; err != nil {
+		return nil, err
+	}
+
+	// RETURNING не сохраняет порядок подзапроса
+	slices.SortFunc(events, func(a, b dto.DomainEvent) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+
+	return events, nil
+}
+
+// MarkPublished is a method that marks an event as published.
+func (s *outboxStorage) MarkPublished(ctx context.Context, seq int64) error {
+	return s.db.WithContext(ctx).Exec(`UPDATE outbox_events SET published_at = now(), attempts = attempts + 1 WHERE seq = ?`, seq).Error
+}
+
+// MarkFailed is a method that records a failed attempt to publish an event, it is given up if nextAttemptAt is nil.
+func (s *outboxStorage) MarkFailed(ctx context.Context, seq int64, nextAttemptAt *time.Time, lastError string) error {
+	if nextAttemptAt == nil {
+		query := `UPDATE outbox_events SET attempts = attempts + 1, last_error = ?, published_at = now() WHERE seq = ?`
+		return s.db.WithContext(ctx).Exec(query, lastError, seq).Error
+	}
+
+	query := `UPDATE outbox_events SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE seq = ?`
+	return s.db.WithContext(ctx).Exec(query, lastError, *nextAttemptAt, seq).Error
+}
+
+// DeletePublished is a method that deletes events published before the time, returns how many were deleted.
+/*
+ * LikeToggled events are kept until the likes rollup of the dashboard has counted them.
+ */
+func (s *outboxStorage) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM outbox_events
+		WHERE published_at < ?
+		  AND NOT (type = ? AND created_at > COALESCE((SELECT refreshed_until FROM stats_rollups WHERE source = 'likes'), '-infinity'))`
+
+	result := s.db.WithContext(ctx).Exec(query, before, dto.EventLikeToggled)
+	return result.RowsAffected, result.Error
+}
@@ -11,7 +11,9 @@ import (
 	"prod/internal/domain/common/errorz"
 	"prod/internal/domain/dto"
 	"prod/internal/domain/entity"
+	"prod/internal/domain/utils/cursor"
 	"prod/internal/domain/utils/pointers"
+	"prod/internal/domain/utils/schedule"
 	"slices"
 	"strings"
 	"time"
@@ -19,42 +21,85 @@ import (
 
 // promoStorage is a struct that contains a pointer to a gorm.DB instance to interact with promo repository.
 type promoStorage struct {
-	db             *gorm.DB
-	actionsStorage *actionsStorage
+	db *gorm.DB
 }
 
 // NewPromoStorage is a function that returns a new instance of promoStorage.
 func NewPromoStorage(db *gorm.DB) *promoStorage {
-	return &promoStorage{
-		db:             db,
-		actionsStorage: NewActionsStorage(db),
-	}
+	return &promoStorage{db: db}
 }
 
+// visibleCondition is an SQL condition on promos (alias p) shown to users: live, not deleted or archived.
+const visibleCondition = `p.deleted_at IS NULL AND p.archived_at IS NULL AND p.status = 'live' AND p.active_from <= now() AND p.active_until > now()`
+
+// activeExpression is an SQL expression of whether a promo (alias p) can be activated right now.
+/*
+ * The active column only tracks the stock, the status and the dates are checked here.
+ */
+const activeExpression = `(p.active AND p.status = 'live' AND p.active_from <= now() AND p.active_until > now())`
+
+// scheduleExpression is an SQL condition of whether a promo (alias p) is within its recurring availability windows right now.
+/*
+ * The schedule is dto.Schedule as JSON, "until" of "24:00" is the end of the day: '24:00'::time is greater than any time of day.
+ */
+const scheduleExpression = `(p.schedule IS NULL
+	OR EXISTS(SELECT 1
+			  FROM JSONB_ARRAY_ELEMENTS(p.schedule -> 'windows') w
+			  WHERE w -> 'weekdays' @> TO_JSONB(EXTRACT(ISODOW FROM now() AT TIME ZONE (p.schedule ->> 'timezone'))::int)
+				AND (w ->> 'from')::time <= (now() AT TIME ZONE (p.schedule ->> 'timezone'))::time
+				AND (w ->> 'until')::time > (now() AT TIME ZONE (p.schedule ->> 'timezone'))::time))`
+
 // Create is a method to create a new Promo in database.
+/*
+ * The promo with its codes and targeting is written in one transaction with PromoPublished, if it is created live.
+ */
 func (s *promoStorage) Create(ctx context.Context, promo entity.Promo) (*entity.Promo, error) {
-	// Insert a promo (parent)'s entity
-	insertPromoQuery := s.db.WithContext(ctx).Raw(
-		"INSERT INTO promos (company_id, created_at, updated_at, active_from, active_until, description, image_url, max_count, mode, promo_common, age_from, age_until, country, country_original, active) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING promo_id;",
-		promo.CompanyID, time.Now(), promo.UpdatedAt, promo.ActiveFrom, promo.ActiveUntil, promo.Description, promo.ImageURL, promo.MaxCount, promo.Mode, promo.PromoCommon, promo.AgeFrom, promo.AgeUntil, promo.Country, promo.CountryOriginal, promo.Active).Scan(&promo.PromoID)
-	if err := insertPromoQuery.Error; err != nil {
-		return nil, err
-	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txStorage := &promoStorage{db: tx}
+
+		// Insert a promo (parent)'s entity
+		insertPromoQuery := tx.Raw(
+			"INSERT INTO promos (company_id, created_at, updated_at, active_from, active_until, description, image_url, max_count, mode, promo_common, age_from, age_until, country, country_original, active, status, schedule, user_limit, user_limit_period, period_limit, limit_period) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING promo_id;",
+			promo.CompanyID, time.Now(), promo.UpdatedAt, promo.ActiveFrom, promo.ActiveUntil, promo.Description, promo.ImageURL, promo.MaxCount, promo.Mode, promo.PromoCommon, promo.AgeFrom, promo.AgeUntil, promo.Country, promo.CountryOriginal, promo.Active, promo.Status, promo.Schedule, promo.UserLimit, promo.UserLimitPeriod, promo.PeriodLimit, promo.LimitPeriod).Scan(&promo.PromoID)
+		if err := insertPromoQuery.Error; err != nil {
+			return err
+		}
 
-	// Insert categories
-	for i, category := range promo.Categories {
-		insertCategoryQuery := s.db.WithContext(ctx).Exec("INSERT INTO categories (promo_id, name, index) VALUES (?, ?, ?);", promo.PromoID, category.Name, i)
-		if err := insertCategoryQuery.Error; err != nil {
-			return nil, err
+		// Insert categories
+		for i, category := range promo.Categories {
+			insertCategoryQuery := tx.Exec("INSERT INTO categories (promo_id, name, index) VALUES (?, ?, ?);", promo.PromoID, category.Name, i)
+			if err := insertCategoryQuery.Error; err != nil {
+				return err
+			}
 		}
-	}
 
-	// Insert promo_uniques
-	for i, promoUnique := range promo.PromoUnique {
-		insertPromoUniqueQuery := s.db.WithContext(ctx).Exec("INSERT INTO promo_uniques (promo_id, body, activated, index) VALUES (?, ?, ?, ?);", promo.PromoID, promoUnique.Body, promoUnique.Activated, i)
-		if err := insertPromoUniqueQuery.Error; err != nil {
-			return nil, err
+		if err := txStorage.insertTargeting(ctx, promo); err != nil {
+			return err
+		}
+
+		// Insert promo_uniques in batches, generated promos can have 100k codes
+		for i := range promo.PromoUnique {
+			promo.PromoUnique[i].PromoID = promo.PromoID
+			promo.PromoUnique[i].Index = i
+		}
+		if len(promo.PromoUnique) > 0 {
+			if err := tx.CreateInBatches(&promo.PromoUnique, 1000).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := txStorage.RefreshSearchVector(ctx, promo.PromoID); err != nil {
+			return err
+		}
+
+		if promo.Status != entity.PromoStatusLive {
+			return nil
 		}
+
+		return insertEvent(tx, dto.EventPromoPublished, promo.PromoID, dto.PromoPublishedEvent{CompanyID: promo.CompanyID, PublishedAt: time.Now()})
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &promo, nil
@@ -81,6 +126,14 @@ func (s *promoStorage) GetByID(ctx context.Context, promoId string) (*entity.Pro
                p.age_until,
                p.country,
                p.country_original,
+               p.status,
+               p.archived_at,
+               p.deleted_at,
+               p.schedule,
+               p.user_limit,
+               p.user_limit_period,
+               p.period_limit,
+               p.limit_period,
                COALESCE(
                            JSONB_AGG(
                            jsonb_build_object(
@@ -91,21 +144,10 @@ func (s *promoStorage) GetByID(ctx context.Context, promoId string) (*entity.Pro
                                    ) FILTER (WHERE c.category_id IS NOT NULL),
                            '[]'::jsonb
                ) AS categories,
-        
-               COALESCE(
-                           JSONB_AGG(
-                           jsonb_build_object(
-                                   'promo_unique_id', pu.promo_unique_id,
-                                   'promo_unique_body', pu.body,
-                                   'promo_unique_activated', pu.activated,
-                                   'index', pu.index
-                           ) ORDER BY pu.index
-                                   ) FILTER (WHERE pu.promo_unique_id IS NOT NULL),
-                           '[]'::jsonb
-               ) AS promo_uniques
+               -- Сами коды не загружаются: их десятки тысяч, список отдает /codes
+               EXISTS(SELECT 1 FROM promo_uniques pu WHERE pu.promo_id = p.promo_id AND NOT pu.activated) AS has_codes
         FROM promos p
                  LEFT JOIN categories c ON p.promo_id = c.promo_id
-                 LEFT JOIN promo_uniques pu ON p.promo_id = pu.promo_id
         WHERE p.promo_id = ?
         GROUP BY
             p.promo_id,
@@ -125,7 +167,15 @@ func (s *promoStorage) GetByID(ctx context.Context, promoId string) (*entity.Pro
             p.age_from,
             p.age_until,
             p.country,
-			p.country_original`
+			p.country_original,
+			p.status,
+			p.archived_at,
+			p.deleted_at,
+			p.schedule,
+			p.user_limit,
+			p.user_limit_period,
+			p.period_limit,
+			p.limit_period`
 
 	type result struct {
 		PromoID         string
@@ -146,8 +196,16 @@ func (s *promoStorage) GetByID(ctx context.Context, promoId string) (*entity.Pro
 		AgeUntil        int
 		Country         countries.CountryCode
 		CountryOriginal string
+		Status          string
+		ArchivedAt      *time.Time
+		DeletedAt       *time.Time
+		Schedule        *string
+		UserLimit       int
+		UserLimitPeriod string
+		PeriodLimit     int
+		LimitPeriod     string
 		Categories      *string
-		PromoUniques    *string
+		HasCodes        bool
 	}
 
 	var res result
@@ -171,19 +229,6 @@ func (s *promoStorage) GetByID(ctx context.Context, promoId string) (*entity.Pro
 		}
 	}
 
-	var promoUniques []struct {
-		PromoUniqueID        string `json:"promo_unique_id"`
-		PromoUniqueBody      string `json:"promo_unique_body"`
-		PromoUniqueActivated bool   `json:"promo_unique_activated"`
-		Index                int    `json:"index"`
-	}
-
-	if res.PromoUniques != nil {
-		if err := json.Unmarshal([]byte(*res.PromoUniques), &promoUniques); err != nil {
-			return nil, err
-		}
-	}
-
 	promo := &entity.Promo{
 		PromoID:         res.PromoID,
 		CompanyID:       res.CompanyID,
@@ -203,6 +248,15 @@ func (s *promoStorage) GetByID(ctx context.Context, promoId string) (*entity.Pro
 		AgeUntil:        res.AgeUntil,
 		Country:         res.Country,
 		CountryOriginal: res.CountryOriginal,
+		Status:          res.Status,
+		ArchivedAt:      res.ArchivedAt,
+		DeletedAt:       res.DeletedAt,
+		Schedule:        res.Schedule,
+		UserLimit:       res.UserLimit,
+		UserLimitPeriod: res.UserLimitPeriod,
+		PeriodLimit:     res.PeriodLimit,
+		LimitPeriod:     res.LimitPeriod,
+		HasCodes:        res.HasCodes,
 	}
 
 	for _, category := range categories {
@@ -212,18 +266,43 @@ func (s *promoStorage) GetByID(ctx context.Context, promoId string) (*entity.Pro
 		})
 	}
 
-	for _, pu := range promoUniques {
-		promo.PromoUnique = append(promo.PromoUnique, entity.PromoUnique{
-			PromoUniqueID: pu.PromoUniqueID,
-			Body:          pu.PromoUniqueBody,
-			Activated:     pu.PromoUniqueActivated,
-		})
+	if err := s.loadTargeting(ctx, []*entity.Promo{promo}); err != nil {
+		return nil, err
 	}
 
 	return promo, nil
 }
 
-func (s *promoStorage) GetWithPagination(ctx context.Context, limit, offset int, sortBy, companyId string, countriesSlice []countries.CountryCode) ([]entity.Promo, int64, error) {
+// GetCompanyID is a method that returns the company of a promo, soft deleted promos included.
+func (s *promoStorage) GetCompanyID(ctx context.Context, promoID string) (string, error) {
+	var companyIDs []string
+	if err := s.db.WithContext(ctx).Raw(`SELECT company_id FROM promos WHERE promo_id = ?`, promoID).Scan(&companyIDs).Error; err != nil {
+		return "", err
+	}
+	if len(companyIDs) == 0 {
+		return "", errorz.NotFound
+	}
+
+	return companyIDs[0], nil
+}
+
+func (s *promoStorage) GetWithPagination(ctx context.Context, page dto.Page, sortBy, companyId string, countriesSlice []countries.CountryCode) ([]entity.Promo, string, int64, error) {
+	// Колонка сортировки, она же первая часть ключа курсора
+	sortColumn := "p.created_at"
+	switch sortBy {
+	case "active_from":
+		sortColumn = "p.active_from"
+	case "active_until":
+		sortColumn = "p.active_until"
+	default:
+		sortBy = "created_at"
+	}
+
+	// Курсор, выданный для другой сортировки, не имеет смысла
+	if page.Cursor != nil && page.Cursor.Sort != sortBy {
+		return nil, "", 0, errorz.BadRequest
+	}
+
 	query := `
 		SELECT p.promo_id,
 			   p.company_id,
@@ -243,6 +322,13 @@ func (s *promoStorage) GetWithPagination(ctx context.Context, limit, offset int,
 			   p.age_until,
 			   p.country,
 			   p.country_original,
+			   p.status,
+			   p.archived_at,
+			   p.schedule,
+			   p.user_limit,
+			   p.user_limit_period,
+			   p.period_limit,
+			   p.limit_period,
 			   COALESCE(
 							   JSONB_AGG(
 							   jsonb_build_object(
@@ -252,27 +338,22 @@ func (s *promoStorage) GetWithPagination(ctx context.Context, limit, offset int,
 							   ) ORDER BY c.index
 									   ) FILTER (WHERE c.category_id IS NOT NULL),
 							   '[]'::jsonb
-			   ) AS categories,
-		
-			   -- Уникальные промо с сортировкой по index
-			   COALESCE(
-							   JSONB_AGG(
-							   jsonb_build_object(
-									   'promo_unique_id', pu.promo_unique_id,
-									   'promo_unique_body', pu.body,
-									   'promo_unique_activated', pu.activated,
-									   'index', pu.index
-							   ) ORDER BY pu.index
-									   ) FILTER (WHERE pu.promo_unique_id IS NOT NULL),
-							   '[]'::jsonb
-			   ) AS promo_uniques
+			   ) AS categories
 		FROM promos p
 				 LEFT JOIN categories c ON p.promo_id = c.promo_id
-				 LEFT JOIN promo_uniques pu ON p.promo_id = pu.promo_id
-		WHERE p.company_id = ?`
+		WHERE p.company_id = ?
+		  AND p.deleted_at IS NULL`
+
+	args := []interface{}{companyId}
 
 	if len(countriesSlice) > 0 {
 		query += ` AND (p.country IN ? OR p.country = 0)`
+		args = append(args, countriesSlice)
+	}
+
+	if page.Cursor != nil {
+		query += ` AND (` + sortColumn + `, p.promo_id) < (?, ?)`
+		args = append(args, page.Cursor.Time, page.Cursor.ID)
 	}
 
 	query += `
@@ -294,18 +375,18 @@ func (s *promoStorage) GetWithPagination(ctx context.Context, limit, offset int,
 			p.age_from,
 			p.age_until,
 			p.country,
-			p.country_original`
-
-	switch sortBy {
-	case "active_from":
-		query += ` ORDER BY p.active_from DESC`
-	case "active_until":
-		query += ` ORDER BY p.active_until DESC`
-	default:
-		query += ` ORDER BY p.created_at DESC`
-	}
-
-	query += ` LIMIT ? OFFSET ?`
+			p.country_original,
+			p.status,
+			p.archived_at,
+			p.schedule,
+			p.user_limit,
+			p.user_limit_period,
+			p.period_limit,
+			p.limit_period`
+
+	query += ` ORDER BY ` + sortColumn + ` DESC, p.promo_id DESC LIMIT ? OFFSET ?`
+	// Берём на одну запись больше, чтобы понять, есть ли следующая страница
+	args = append(args, page.Limit+1, page.Offset)
 
 	type result struct {
 		PromoID         string
@@ -326,8 +407,15 @@ func (s *promoStorage) GetWithPagination(ctx context.Context, limit, offset int,
 		AgeUntil        int
 		Country         countries.CountryCode
 		CountryOriginal string
+		Status          string
+		ArchivedAt      *time.Time
+		DeletedAt       *time.Time
+		Schedule        *string
+		UserLimit       int
+		UserLimitPeriod string
+		PeriodLimit     int
+		LimitPeriod     string
 		Categories      *string
-		PromoUniques    *string
 	}
 
 	type Category struct {
@@ -336,40 +424,33 @@ func (s *promoStorage) GetWithPagination(ctx context.Context, limit, offset int,
 		Index        int    `json:"index"`
 	}
 
-	type PromoUnique struct {
-		PromoUniqueID        string `json:"promo_unique_id"`
-		PromoUniqueBody      string `json:"promo_unique_body"`
-		PromoUniqueActivated bool   `json:"promo_unique_activated"`
-		Index                int    `json:"index"`
-	}
-
 	var results []result
+	if err := s.db.WithContext(ctx).Raw(query, args...).Scan(&results).Error; err != nil {
+		return nil, "", 0, err
+	}
 
-	if len(countriesSlice) > 0 {
-		if err := s.db.WithContext(ctx).Raw(query, companyId, countriesSlice, limit, offset).Scan(&results).Error; err != nil {
-			return nil, 0, err
-		}
-	} else {
-		if err := s.db.WithContext(ctx).Raw(query, companyId, limit, offset).Scan(&results).Error; err != nil {
-			return nil, 0, err
+	var nextCursor string
+	if len(results) > page.Limit {
+		results = results[:page.Limit]
+		last := results[len(results)-1]
+		next := cursor.Cursor{Sort: sortBy, Time: last.CreatedAt, ID: last.PromoID}
+		switch sortBy {
+		case "active_from":
+			next.Time = last.ActiveFrom
+		case "active_until":
+			next.Time = last.ActiveUntil
 		}
+		nextCursor = cursor.Encode(next)
 	}
 
 	var promos []entity.Promo
 
 	for _, r := range results {
 		var categories []Category
-		var promoUniques []PromoUnique
 
 		if r.Categories != nil {
 			if err := json.Unmarshal([]byte(*r.Categories), &categories); err != nil {
-				return nil, 0, err
-			}
-		}
-
-		if r.PromoUniques != nil {
-			if err := json.Unmarshal([]byte(*r.PromoUniques), &promoUniques); err != nil {
-				return nil, 0, err
+				return nil, "", 0, err
 			}
 		}
 
@@ -392,6 +473,13 @@ func (s *promoStorage) GetWithPagination(ctx context.Context, limit, offset int,
 			AgeUntil:        r.AgeUntil,
 			Country:         r.Country,
 			CountryOriginal: r.CountryOriginal,
+			Status:          r.Status,
+			ArchivedAt:      r.ArchivedAt,
+			Schedule:        r.Schedule,
+			UserLimit:       r.UserLimit,
+			UserLimitPeriod: r.UserLimitPeriod,
+			PeriodLimit:     r.PeriodLimit,
+			LimitPeriod:     r.LimitPeriod,
 		}
 
 		for _, category := range categories {
@@ -401,30 +489,34 @@ func (s *promoStorage) GetWithPagination(ctx context.Context, limit, offset int,
 			})
 		}
 
-		for _, promoUnique := range promoUniques {
-			promo.PromoUnique = append(promo.PromoUnique, entity.PromoUnique{
-				PromoUniqueID: promoUnique.PromoUniqueID,
-				Body:          promoUnique.PromoUniqueBody,
-				Activated:     promoUnique.PromoUniqueActivated,
-			})
-		}
-
 		promos = append(promos, promo)
 	}
 
+	targeted := make([]*entity.Promo, 0, len(promos))
+	for i := range promos {
+		targeted = append(targeted, &promos[i])
+	}
+	if err := s.loadTargeting(ctx, targeted); err != nil {
+		return nil, "", 0, err
+	}
+
+	if !page.NeedTotal() {
+		return promos, nextCursor, 0, nil
+	}
+
 	// Получаем общее количество записей
 	var total int64
 	if len(countriesSlice) > 0 {
-		if err := s.db.WithContext(ctx).Raw("SELECT COUNT(*) FROM promos WHERE company_id = ? AND (country IN ? OR country = 0)", companyId, countriesSlice).Scan(&total).Error; err != nil {
-			return nil, 0, err
+		if err := s.db.WithContext(ctx).Raw("SELECT COUNT(*) FROM promos WHERE company_id = ? AND deleted_at IS NULL AND (country IN ? OR country = 0)", companyId, countriesSlice).Scan(&total).Error; err != nil {
+			return nil, "", 0, err
 		}
 	} else {
-		if err := s.db.WithContext(ctx).Raw("SELECT COUNT(*) FROM promos WHERE company_id = ?", companyId).Scan(&total).Error; err != nil {
-			return nil, 0, err
+		if err := s.db.WithContext(ctx).Raw("SELECT COUNT(*) FROM promos WHERE company_id = ? AND deleted_at IS NULL", companyId).Scan(&total).Error; err != nil {
+			return nil, "", 0, err
 		}
 	}
 
-	return promos, total, nil
+	return promos, nextCursor, total, nil
 }
 
 // Update is a method to update an existing Promo in database.
@@ -444,12 +536,28 @@ func (s *promoStorage) Update(ctx context.Context, fiberCtx fiber.Ctx, promo dto
 			age_until = COALESCE(?, age_until),
 			active = COALESCE(?, active)`
 
+	// Расписание заменяется целиком, пустой список окон его удаляет
+	var promoSchedule *string
+	if promo.Schedule != nil {
+		var err error
+		if promoSchedule, err = schedule.Marshal(promo.Schedule); err != nil {
+			return nil, err
+		}
+		queryUpdate += `, schedule = ?`
+	}
+
+	var limits dto.PromoLimits
+	if promo.Limits != nil {
+		limits = promo.Limits.Normalized()
+		queryUpdate += `, user_limit = ?, user_limit_period = ?, period_limit = ?, limit_period = ?`
+	}
+
 	if promo.Target != nil && promo.Target.Country != "" {
 		queryUpdate += `, country = COALESCE(?, country)`
 		queryUpdate += `, country_original = COALESCE(?, country_original)`
 	}
 
-	queryUpdate += `WHERE promo_id = ?`
+	queryUpdate += ` WHERE promo_id = ?`
 
 	queryUpdateCategories := `
 		INSERT INTO categories (promo_id, name, index)
@@ -461,7 +569,7 @@ func (s *promoStorage) Update(ctx context.Context, fiberCtx fiber.Ctx, promo dto
 	//	VALUES
 	//		(?, ?, ?, ?)`
 
-	findOldPromoQuery := s.db.WithContext(ctx).Where("promo_id = ?", id).First(&oldPromo)
+	findOldPromoQuery := s.db.WithContext(ctx).Where("promo_id = ? AND deleted_at IS NULL", id).First(&oldPromo)
 
 	if findOldPromoQuery.Error != nil {
 		return nil, errorz.NotFound
@@ -471,6 +579,11 @@ func (s *promoStorage) Update(ctx context.Context, fiberCtx fiber.Ctx, promo dto
 		return nil, errorz.Forbidden
 	}
 
+	// Лимиты активаций есть только у COMMON-промо
+	if promo.Limits != nil && oldPromo.Mode == "UNIQUE" {
+		return nil, errorz.BadRequest
+	}
+
 	if (promo.MaxCount != nil) && oldPromo.Mode == "UNIQUE" && (*promo.MaxCount != 1) {
 		logger.Log.Error("unique max count")
 		return nil, errorz.BadRequest
@@ -481,14 +594,15 @@ func (s *promoStorage) Update(ctx context.Context, fiberCtx fiber.Ctx, promo dto
 	currentActive := oldPromo.Active
 	active = &currentActive
 
-	if oldPromo.ActiveFrom.After(time.Now()) || oldPromo.ActiveUntil.Before(time.Now()) {
-		*active = false
+	// active отражает только остаток, даты проверяются через статус
+	if promo.MaxCount != nil && oldPromo.Mode == "COMMON" && oldPromo.ArchivedAt == nil {
+		*active = oldPromo.UsedCount < *promo.MaxCount
 	}
 
 	var activeFrom, activeUntil *time.Time
 	var timeError error
 	if promo.ActiveFrom != nil {
-		activeFrom, timeError = pointers.Time(time.Parse("2006-01-02", *promo.ActiveFrom))
+		activeFrom, timeError = pointers.Time(schedule.ParseBound(*promo.ActiveFrom))
 		if timeError != nil {
 			//activeFrom, timeError = pointers.Time(time.Parse("2006-01-02 15:04:05", *promo.ActiveFrom))
 			//if timeError != nil {
@@ -499,7 +613,7 @@ func (s *promoStorage) Update(ctx context.Context, fiberCtx fiber.Ctx, promo dto
 		}
 	}
 	if promo.ActiveUntil != nil {
-		activeUntil, timeError = pointers.Time(time.Parse("2006-01-02", *promo.ActiveUntil))
+		activeUntil, timeError = pointers.Time(schedule.ParseBound(*promo.ActiveUntil))
 		if timeError != nil {
 			//activeUntil, timeError = pointers.Time(time.Parse("2006-01-02 15:04:05", *promo.ActiveUntil))
 			//if timeError != nil {
@@ -510,14 +624,6 @@ func (s *promoStorage) Update(ctx context.Context, fiberCtx fiber.Ctx, promo dto
 		}
 	}
 
-	if activeFrom != nil && activeFrom.After(time.Now()) {
-		*active = false
-	}
-
-	if activeUntil != nil && activeUntil.Before(time.Now()) {
-		*active = false
-	}
-
 	var ageFrom, ageUntil *int
 
 	if promo.Target != nil {
@@ -534,43 +640,47 @@ func (s *promoStorage) Update(ctx context.Context, fiberCtx fiber.Ctx, promo dto
 		}
 	}
 
+	args := []interface{}{activeFrom, activeUntil, promo.Description, promo.ImageURL, promo.MaxCount, ageFrom, ageUntil, active}
+	if promo.Schedule != nil {
+		args = append(args, promoSchedule)
+	}
+	if promo.Limits != nil {
+		args = append(args, limits.PerUser, limits.PerUserPeriod, limits.PerPeriod, limits.Period)
+	}
 	if promo.Target != nil && promo.Target.Country != "" {
-		if err := s.db.WithContext(ctx).Exec(queryUpdate,
-			activeFrom,
-			activeUntil,
-			promo.Description,
-			promo.ImageURL,
-			promo.MaxCount,
-			ageFrom,
-			ageUntil,
-			active,
-			countries.ByName(promo.Target.Country),
-			promo.Target.Country,
-			id).Error; err != nil {
-			return nil, err
+		args = append(args, countries.ByName(promo.Target.Country), promo.Target.Country)
+	}
+	args = append(args, id)
+
+	// Поля, категории, таргетинг и поисковый вектор меняются вместе: промо не бывает видно без стран или категорий
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txStorage := &promoStorage{db: tx}
+
+		if err := tx.Exec(queryUpdate, args...).Error; err != nil {
+			return err
 		}
-	} else {
-		if err := s.db.WithContext(ctx).Exec(queryUpdate,
-			activeFrom,
-			activeUntil,
-			promo.Description,
-			promo.ImageURL,
-			promo.MaxCount,
-			ageFrom,
-			ageUntil,
-			active,
-			id).Error; err != nil {
-			return nil, err
+
+		if promo.Target != nil && promo.Target.Categories != nil {
+			if err := tx.Exec(`DELETE FROM categories WHERE promo_id = ?`, id).Error; err != nil {
+				return err
+			}
+			for i, category := range promo.Target.Categories {
+				if err := tx.Exec(queryUpdateCategories, id, category, i).Error; err != nil {
+					return err
+				}
+			}
 		}
-	}
 
-	if promo.Target != nil && promo.Target.Categories != nil {
-		s.db.WithContext(ctx).Exec(`DELETE FROM categories WHERE promo_id = ?`, id)
-		for i, category := range promo.Target.Categories {
-			if err := s.db.WithContext(ctx).Exec(queryUpdateCategories, id, category, i).Error; err != nil {
-				return nil, err
+		if promo.Target != nil {
+			if err := txStorage.replaceTargeting(ctx, id, *promo.Target); err != nil {
+				return err
 			}
 		}
+
+		return txStorage.RefreshSearchVector(ctx, id)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	//if promo.PromoUnique != nil {
@@ -583,7 +693,6 @@ func (s *promoStorage) Update(ctx context.Context, fiberCtx fiber.Ctx, promo dto
 	//}
 
 	newPromo, err := s.GetByID(ctx, id)
-
 	if err != nil {
 		return nil, err
 	}
@@ -591,201 +700,365 @@ func (s *promoStorage) Update(ctx context.Context, fiberCtx fiber.Ctx, promo dto
 	return newPromo, nil
 }
 
-func (s *promoStorage) GetFeed(ctx context.Context, age, limit, offset int, country countries.CountryCode, category *string, active, userID string) ([]dto.PromoForUser, int64, error) {
+// Archive is a method that moves a promo to the archive, archived promos can't be activated and are hidden from users.
+func (s *promoStorage) Archive(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Exec(`
+		UPDATE promos
+		SET archived_at = COALESCE(archived_at, now()),
+			active      = FALSE,
+			updated_at  = now()
+		WHERE promo_id = ?
+		  AND deleted_at IS NULL`, id).Error
+}
+
+// Unarchive is a method that returns a promo from the archive, it becomes active again if it has codes left.
+func (s *promoStorage) Unarchive(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Exec(`
+		UPDATE promos
+		SET archived_at = NULL,
+			active      = CASE
+						WHEN mode = 'COMMON' THEN used_count < max_count
+						ELSE EXISTS(SELECT 1 FROM promo_uniques pu WHERE pu.promo_id = promos.promo_id AND NOT pu.activated)
+				END,
+			updated_at  = now()
+		WHERE promo_id = ?
+		  AND archived_at IS NOT NULL
+		  AND deleted_at IS NULL`, id).Error
+}
+
+// Delete is a method that soft deletes a promo: it disappears everywhere but the stats, activations stay.
+func (s *promoStorage) Delete(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Exec(`
+		UPDATE promos
+		SET deleted_at = now(),
+			active     = FALSE,
+			updated_at = now()
+		WHERE promo_id = ?
+		  AND deleted_at IS NULL`, id).Error
+}
+
+// SetStatus is a method that moves a promo from one stored status to another, used_count and the stock are left as is.
+/*
+ * Returns errorz.Conflict if the promo's status is no longer from. Publishing a draft also writes PromoPublished.
+ */
+func (s *promoStorage) SetStatus(ctx context.Context, id, from, to string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var companyID string
+		res := tx.Raw(`
+			UPDATE promos
+			SET status     = ?,
+				updated_at = now()
+			WHERE promo_id = ?
+			  AND status = ?
+			  AND deleted_at IS NULL
+			RETURNING company_id`, to, id, from).Scan(&companyID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errorz.Conflict
+		}
+
+		if from != entity.PromoStatusDraft || to != entity.PromoStatusLive {
+			return nil
+		}
+
+		return insertEvent(tx, dto.EventPromoPublished, id, dto.PromoPublishedEvent{CompanyID: companyID, PublishedAt: time.Now()})
+	})
+}
+
+// GetFeed is a method that returns an ordered page of the user's feed with the user's flags, details are loaded by GetDetails.
+func (s *promoStorage) GetFeed(ctx context.Context, age int, country countries.CountryCode, category *string, active, userID, sortBy string, ranking dto.FeedRanking, page dto.Page) ([]dto.PromoUserState, string, int64, error) {
 	baseQuery := `
-        SELECT 
-            p.promo_id,
-            p.company_id,
-            p.description,
-            p.image_url,
-            p.active,
-            p.like_count,
-            p.comment_count,
-            EXISTS(SELECT 1 FROM activations a WHERE a.user_id = ? AND a.promo_id = p.promo_id) AS is_activated,
-            c.name AS category_name,
-            b.name AS business_name,
-            b.id AS business_id
-        FROM promos p
-        %s JOIN categories c ON c.promo_id = p.promo_id %s
-        INNER JOIN businesses b ON b.id = p.company_id
-        WHERE p.age_from <= ?
-          AND p.age_until >= ?
-          AND (p.country = ? OR p.country = 0)
-          %s  -- Условие active
-        ORDER BY p.created_at DESC
+        %s  -- CTE для ранжирования
+        SELECT f.*,
+               EXISTS(SELECT 1 FROM activations a WHERE a.user_id = ? AND a.promo_id = f.promo_id)        AS is_activated,
+               EXISTS(SELECT 1 FROM likes l WHERE l.user_id = ? AND l.promo_id = f.promo_id AND l."like") AS is_liked
+        FROM (SELECT 
+                  p.promo_id,
+                  p.created_at,
+                  %s AS score
+              FROM promos p
+              WHERE %s
+                AND %s
+                %s  -- Условие категории
+                %s  -- Условие active
+             ) f
+        WHERE TRUE
+          %s  -- Условие курсора
+        ORDER BY %s
         LIMIT ? OFFSET ?`
 
 	baseCountQuery := `
         SELECT COUNT(*)
         FROM promos p
-        %s JOIN categories c ON c.promo_id = p.promo_id %s
-        INNER JOIN businesses b ON b.id = p.company_id
-        WHERE p.age_from <= ?
-          AND p.age_until >= ?
-          AND (p.country = ? OR p.country = 0)
+        WHERE %s
+          AND %s
+          %s --Category
           %s --Active`
 
-	// Определяем тип JOIN и условия для категории
-	joinType := "LEFT"
+	// Курсор, выданный для другой сортировки, не имеет смысла
+	if page.Cursor != nil && page.Cursor.Sort != sortBy {
+		return nil, "", 0, errorz.BadRequest
+	}
+
+	// Фильтр по категории через EXISTS, чтобы промо с несколькими категориями не дублировались
 	categoryCondition := ""
 	if category != nil {
-		joinType = "INNER"
-		categoryCondition = "AND LOWER(c.name) = LOWER(?)"
+		categoryCondition = "AND EXISTS(SELECT 1 FROM categories fc WHERE fc.promo_id = p.promo_id AND LOWER(fc.name) = LOWER(?))"
 	}
 
 	// Добавляем условие active, если нужно
 	activeCondition := ""
 	if active != "" {
-		activeCondition = "AND p.active = ?"
+		activeCondition = "AND " + activeExpression + " = ?"
+	}
+
+	// Ранжирование: new - по дате создания, popular и relevance - по score.
+	// Следующие страницы считаются на момент первой, чтобы score не плыл между запросами
+	cte, score, orderBy := "", "0::float", "f.created_at DESC, f.promo_id DESC"
+	var cteArgs, scoreArgs []interface{}
+	scoredAt := time.Now()
+	if sortBy != "new" {
+		if page.Cursor != nil {
+			scoredAt = page.Cursor.Time
+		}
+
+		var withCTE string
+		withCTE, cteArgs, score, scoreArgs = feedScore(sortBy, ranking, userID, scoredAt)
+		if withCTE != "" {
+			cte = "WITH " + withCTE
+		}
+		orderBy = "f.score DESC, f.promo_id DESC"
+	}
+
+	// Keyset: продолжаем после последнего промо предыдущей страницы.
+	// score считается по живым счетчикам и между запросами меняется, поэтому ранжированные страницы идут по смещению:
+	// промо, чей score изменился, может повториться или пропасть
+	cursorCondition, offset := "", page.Offset
+	if page.Cursor != nil {
+		if sortBy == "new" {
+			cursorCondition = "AND (f.created_at, f.promo_id) < (?, ?)"
+		} else {
+			offset = page.Cursor.Offset
+		}
 	}
 
 	// Формируем итоговые запросы
-	query := fmt.Sprintf(baseQuery, joinType, categoryCondition, activeCondition)
-	queryCount := fmt.Sprintf(baseCountQuery, joinType, categoryCondition, activeCondition)
+	query := fmt.Sprintf(baseQuery, cte, score, targetExpression, visibleCondition, categoryCondition, activeCondition, cursorCondition, orderBy)
+	queryCount := fmt.Sprintf(baseCountQuery, targetExpression, visibleCondition, categoryCondition, activeCondition)
 
 	type result struct {
-		PromoID      string
-		BusinessID   string
-		BusinessName string
-		Description  string
-		ImageURL     string
-		Active       bool
-		LikeCount    int
-		CommentCount int
-		IsActivated  bool
-		CategoryName string
+		PromoID     string
+		CreatedAt   time.Time
+		IsActivated bool
+		IsLiked     bool
 	}
 
 	var results []result
 	var args []interface{}
 
 	// Параметры запроса
-	args = append(args, userID)
+	args = append(args, cteArgs...)
+	args = append(args, userID, userID)
+	args = append(args, scoreArgs...)
+	args = append(args, targetArgs(age, country, userID)...)
 	if category != nil {
 		args = append(args, *category)
 	}
-	args = append(args, age, age, country)
 	if active != "" {
 		args = append(args, active)
 	}
-	args = append(args, limit, offset)
+	if cursorCondition != "" {
+		args = append(args, page.Cursor.Time, page.Cursor.ID)
+	}
+	// Берём на одну запись больше, чтобы понять, есть ли следующая страница
+	args = append(args, page.Limit+1, offset)
 
 	// Выполнение основного запроса
 	if err := s.db.WithContext(ctx).Raw(query, args...).Scan(&results).Error; err != nil {
-		return nil, 0, err
+		return nil, "", 0, err
+	}
+
+	var nextCursor string
+	if len(results) > page.Limit {
+		results = results[:page.Limit]
+		last := results[len(results)-1]
+		next := cursor.Cursor{Sort: sortBy, Time: last.CreatedAt, ID: last.PromoID}
+		if sortBy != "new" {
+			next.Time, next.Offset = scoredAt, offset+page.Limit
+		}
+		nextCursor = cursor.Encode(next)
 	}
 
 	// Преобразование результатов
-	var promos []dto.PromoForUser
+	promos := make([]dto.PromoUserState, 0, len(results))
 	for _, r := range results {
-		promos = append(promos, dto.PromoForUser{
-			PromoID:           r.PromoID,
-			CompanyID:         r.BusinessID,
-			CompanyName:       r.BusinessName,
-			Description:       r.Description,
-			ImageURL:          r.ImageURL,
-			Active:            r.Active,
-			IsLikedByUser:     s.actionsStorage.IsLikedByUser(ctx, userID, r.PromoID),
-			IsActivatedByUser: r.IsActivated,
-			LikeCount:         r.LikeCount,
-			CommentCount:      r.CommentCount,
+		promos = append(promos, dto.PromoUserState{
+			PromoID:     r.PromoID,
+			IsLiked:     r.IsLiked,
+			IsActivated: r.IsActivated,
 		})
 	}
 
+	if !page.NeedTotal() {
+		return promos, nextCursor, 0, nil
+	}
+
 	var countArgs []interface{}
+	countArgs = append(countArgs, targetArgs(age, country, userID)...)
 	if category != nil {
 		countArgs = append(countArgs, *category)
 	}
-	countArgs = append(countArgs, age, age, country)
 	if active != "" {
 		countArgs = append(countArgs, active)
 	}
 
 	var total int64
 	if err := s.db.WithContext(ctx).Raw(queryCount, countArgs...).Scan(&total).Error; err != nil {
-		return nil, 0, err
+		return nil, "", 0, err
 	}
 
-	return promos, total, nil
+	return promos, nextCursor, total, nil
 }
 
-// GetByIdUser Get promo by ID for Users
-func (s *promoStorage) GetByIdUser(ctx context.Context, promoID, userID string) (dto.PromoForUser, error) {
-	var promo dto.PromoForUser
+// GetDetails is a method that returns promos by ids without the user's flags and the company name.
+func (s *promoStorage) GetDetails(ctx context.Context, promoIDs []string) ([]dto.PromoForUser, error) {
+	if len(promoIDs) == 0 {
+		return nil, nil
+	}
 
 	query := `
 		SELECT p.promo_id,
 			   p.company_id,
 			   p.description,
 			   p.image_url,
-			   p.active,
+			   p.active AS in_stock,
+			   p.status,
+			   p.active_from,
+			   p.active_until,
 			   p.like_count,
 			   p.comment_count,
-			   EXISTS(SELECT * from activations a WHERE a.user_id = ? AND a.promo_id = ?) AS is_activated, -- is_activated_by_user
-			   b.name AS business_name,
-			   b.id   AS business_id
+			   p.used_count,
+			   COALESCE((SELECT JSONB_AGG(c.name ORDER BY c.index) FROM categories c WHERE c.promo_id = p.promo_id),
+						'[]'::jsonb) AS categories,
+			   p.schedule
 		FROM promos p
-				 INNER JOIN businesses b ON b.id = p.company_id
-		WHERE p.promo_id = ?;`
+		WHERE p.promo_id IN ?
+		  AND p.deleted_at IS NULL`
 
 	type result struct {
 		PromoID      string
-		BusinessID   string
-		BusinessName string
+		CompanyID    string
 		Description  string
 		ImageURL     string
-		Active       bool
+		InStock      bool
+		Status       string
+		ActiveFrom   time.Time
+		ActiveUntil  time.Time
 		LikeCount    int
 		CommentCount int
-		IsActivated  bool
+		UsedCount    int
+		Categories   string
+		Schedule     *string
 	}
 
-	var queryResult result
+	var results []result
+	if err := s.db.WithContext(ctx).Raw(query, promoIDs).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+
+	promos := make([]dto.PromoForUser, 0, len(results))
+	for _, r := range results {
+		var categories []string
+		if err := json.Unmarshal([]byte(r.Categories), &categories); err != nil {
+			return nil, err
+		}
 
-	if err := s.db.WithContext(ctx).Raw(query, userID, promoID, promoID).Scan(&queryResult).Error; err != nil {
-		return promo, err
+		promoSchedule, err := schedule.Unmarshal(r.Schedule)
+		if err != nil {
+			return nil, err
+		}
+
+		promos = append(promos, dto.PromoForUser{
+			PromoID:      r.PromoID,
+			CompanyID:    r.CompanyID,
+			Description:  r.Description,
+			ImageURL:     r.ImageURL,
+			Categories:   categories,
+			LikeCount:    r.LikeCount,
+			CommentCount: r.CommentCount,
+			UsedCount:    r.UsedCount,
+			Schedule:     promoSchedule,
+			InStock:      r.InStock,
+			Status:       r.Status,
+			ActiveFrom:   r.ActiveFrom,
+			ActiveUntil:  r.ActiveUntil,
+		})
 	}
 
-	promo = dto.PromoForUser{
-		PromoID:           queryResult.PromoID,
-		CompanyID:         queryResult.BusinessID,
-		CompanyName:       queryResult.BusinessName,
-		Description:       queryResult.Description,
-		ImageURL:          queryResult.ImageURL,
-		Active:            queryResult.Active,
-		LikeCount:         queryResult.LikeCount,
-		CommentCount:      queryResult.CommentCount,
-		IsLikedByUser:     s.actionsStorage.IsLikedByUser(ctx, userID, promoID),
-		IsActivatedByUser: queryResult.IsActivated,
-		UsedCount:         queryResult.CommentCount,
+	return promos, nil
+}
+
+// GetUserStates is a method that returns the user's flags for those of the given promos the user can see: live and targeted at the user.
+func (s *promoStorage) GetUserStates(ctx context.Context, age int, country countries.CountryCode, userID string, promoIDs []string) ([]dto.PromoUserState, error) {
+	if len(promoIDs) == 0 {
+		return nil, nil
 	}
 
-	if promo.PromoID == "" {
-		return promo, errorz.NotFound
+	query := `
+		SELECT p.promo_id,
+			   EXISTS(SELECT 1 FROM activations a WHERE a.user_id = ? AND a.promo_id = p.promo_id)        AS is_activated,
+			   EXISTS(SELECT 1 FROM likes l WHERE l.user_id = ? AND l.promo_id = p.promo_id AND l."like") AS is_liked
+		FROM promos p
+		WHERE p.promo_id IN ?
+		  AND ` + targetExpression + `
+		  AND ` + visibleCondition
+
+	args := append([]interface{}{userID, userID, promoIDs}, targetArgs(age, country, userID)...)
+
+	var states []dto.PromoUserState
+	if err := s.db.WithContext(ctx).Raw(query, args...).Scan(&states).Error; err != nil {
+		return nil, err
 	}
 
-	return promo, nil
+	return states, nil
 }
 
-func (s *promoStorage) GetHistory(ctx context.Context, userID string, limit, offset int) ([]dto.PromoForUser, int64, error) {
+func (s *promoStorage) GetHistory(ctx context.Context, userID string, page dto.Page) ([]dto.PromoForUser, string, int64, error) {
 	query := `
 		SELECT p.promo_id,
 			   p.company_id,
 			   p.description,
 			   p.image_url,
-			   p.active,
+			   ` + activeExpression + ` AS active,
 			   p.like_count,
 			   p.comment_count,
-			   EXISTS(SELECT * from activations a WHERE a.user_id = ? AND a.promo_id = p.promo_id) AS is_activated, -- is_activated_by_user
-			   b.name                                                                     AS business_name,
-			   b.id                                                                       AS business_id,
-			   COUNT(*) OVER() AS total_count
+			   p.used_count,
+			   TRUE                                                                                       AS is_activated, -- is_activated_by_user
+			   EXISTS(SELECT 1 FROM likes l WHERE l.user_id = a.user_id AND l.promo_id = p.promo_id AND l."like")   AS is_liked,
+			   COALESCE((SELECT JSONB_AGG(c.name ORDER BY c.index) FROM categories c WHERE c.promo_id = p.promo_id),
+						'[]'::jsonb)                                                                      AS categories,
+			   b.name                                                                                     AS business_name,
+			   b.id                                                                                       AS business_id,
+			   a.activation_id,
+			   a.created_at                                                                               AS activated_at
 		FROM activations a
 				 INNER JOIN promos p on p.promo_id = a.promo_id
 				 INNER JOIN businesses b ON b.id = p.company_id
-		WHERE user_id = ?
-		ORDER BY a.created_at DESC
+		WHERE a.user_id = ?`
+
+	args := []interface{}{userID}
+
+	if page.Cursor != nil {
+		query += ` AND (a.created_at, a.activation_id) < (?, ?)`
+		args = append(args, page.Cursor.Time, page.Cursor.ID)
+	}
+
+	query += `
+		ORDER BY a.created_at DESC, a.activation_id DESC
 		LIMIT ? OFFSET ?`
+	args = append(args, page.Limit+1, page.Offset)
 
 	type result struct {
 		PromoID      string
@@ -796,17 +1069,33 @@ func (s *promoStorage) GetHistory(ctx context.Context, userID string, limit, off
 		Active       bool
 		LikeCount    int
 		CommentCount int
+		UsedCount    int
 		IsActivated  bool
-		TotalCount   int64
+		IsLiked      bool
+		Categories   string
+		ActivationID string
+		ActivatedAt  time.Time
 	}
 
 	var results []result
-	if err := s.db.WithContext(ctx).Raw(query, userID, userID, limit, offset).Scan(&results).Error; err != nil {
-		return nil, 0, err
+	if err := s.db.WithContext(ctx).Raw(query, args...).Scan(&results).Error; err != nil {
+		return nil, "", 0, err
+	}
+
+	var nextCursor string
+	if len(results) > page.Limit {
+		results = results[:page.Limit]
+		last := results[len(results)-1]
+		nextCursor = cursor.Encode(cursor.Cursor{Time: last.ActivatedAt, ID: last.ActivationID})
 	}
 
 	var promos []dto.PromoForUser
 	for _, r := range results {
+		var categories []string
+		if err := json.Unmarshal([]byte(r.Categories), &categories); err != nil {
+			return nil, "", 0, err
+		}
+
 		promos = append(promos, dto.PromoForUser{
 			PromoID:           r.PromoID,
 			CompanyID:         r.BusinessID,
@@ -814,15 +1103,25 @@ func (s *promoStorage) GetHistory(ctx context.Context, userID string, limit, off
 			Description:       r.Description,
 			ImageURL:          r.ImageURL,
 			Active:            r.Active,
+			Categories:        categories,
 			LikeCount:         r.LikeCount,
 			CommentCount:      r.CommentCount,
-			IsLikedByUser:     s.actionsStorage.IsLikedByUser(ctx, userID, r.PromoID),
+			IsLikedByUser:     r.IsLiked,
 			IsActivatedByUser: r.IsActivated,
-			UsedCount:         r.CommentCount,
+			UsedCount:         r.UsedCount,
 		})
 	}
 
-	return promos, results[0].TotalCount, nil
+	if !page.NeedTotal() {
+		return promos, nextCursor, 0, nil
+	}
+
+	var total int64
+	if err := s.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM activations WHERE user_id = ?`, userID).Scan(&total).Error; err != nil {
+		return nil, "", 0, err
+	}
+
+	return promos, nextCursor, total, nil
 }
 
 func (s *promoStorage) GetStats(ctx context.Context, promoID, companyID string) (dto.PromoStatsResponse, error) {
@@ -850,13 +1149,10 @@ func (s *promoStorage) GetStats(ctx context.Context, promoID, companyID string)
 		return dto.PromoStatsResponse{}, err
 	}
 
-	if len(results) == 0 {
-		return dto.PromoStatsResponse{}, errorz.NotFound
-	}
-
-	var stats dto.PromoStatsResponse
+	// Промо без активаций - нулевая статистика, существование и владелец проверяются до вызова
+	stats := dto.PromoStatsResponse{Countries: []dto.ActivationsByCountry{}}
 	for _, r := range results {
-		stats.ActivationsCount += results[0].ActivationsCount
+		stats.ActivationsCount += r.ActivationsCount
 		stats.Countries = append(stats.Countries, dto.ActivationsByCountry{
 			Country: strings.ToLower(countries.CountryCode(r.Country).Alpha2()),
 			Count:   r.ActivationsCount,
@@ -0,0 +1,281 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"time"
+)
+
+// promoCodeStorage is a struct that contains a pointer to a gorm.DB instance to manage unique codes of UNIQUE promos.
+type promoCodeStorage struct {
+	db *gorm.DB
+}
+
+// NewPromoCodeStorage is a function that returns a new instance of promoCodeStorage.
+func NewPromoCodeStorage(db *gorm.DB) *promoCodeStorage {
+	return &promoCodeStorage{db: db}
+}
+
+type uniquePromo struct {
+	CompanyID  string
+	Mode       string
+	Active     bool
+	ArchivedAt *time.Time
+}
+
+// getUniquePromo is a function that returns a promo owned by the company and locks it until the end of the transaction.
+/*
+ * Returns errorz.NotFound if there is no such promo, errorz.Forbidden if it's owned by another company
+ * and errorz.BadRequest if the promo is not in UNIQUE mode.
+ */
+func getUniquePromo(tx *gorm.DB, promoID, companyID string, lock bool) (uniquePromo, error) {
+	query := `SELECT company_id, mode, active, archived_at FROM promos WHERE promo_id = ? AND deleted_at IS NULL`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+
+	var promos []uniquePromo
+	if err := tx.Raw(query, promoID).Scan(&promos).Error; err != nil {
+		return uniquePromo{}, err
+	}
+
+	if len(promos) == 0 {
+		return uniquePromo{}, errorz.NotFound
+	}
+	if promos[0].CompanyID != companyID {
+		return uniquePromo{}, errorz.Forbidden
+	}
+	if promos[0].Mode != "UNIQUE" {
+		return uniquePromo{}, errorz.BadRequest
+	}
+
+	return promos[0], nil
+}
+
+func countAvailableCodes(tx *gorm.DB, promoID string) (int, error) {
+	var available int64
+	err := tx.Model(&entity.PromoUnique{}).Where("promo_id = ? AND activated = FALSE", promoID).Count(&available).Error
+	return int(available), err
+}
+
+// AddCodes is a method that appends new codes to a UNIQUE promo, skipping the ones it already has.
+/*
+ * A promo that was deactivated because its codes ran out is activated again unless it's archived,
+ * its status and dates decide whether users see it.
+ */
+func (s *promoCodeStorage) AddCodes(ctx context.Context, promoID, companyID string, codes []string) (dto.PromoCodesAddResponse, error) {
+	var res dto.PromoCodesAddResponse
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		promo, err := getUniquePromo(tx, promoID, companyID, true)
+		if err != nil {
+			return err
+		}
+
+		availableBefore, err := countAvailableCodes(tx, promoID)
+		if err != nil {
+			return err
+		}
+
+		var existing []string
+		if err = tx.Model(&entity.PromoUnique{}).Where("promo_id = ? AND body IN ?", promoID, codes).Pluck("body", &existing).Error; err != nil {
+			return err
+		}
+
+		var maxIndex int
+		if err = tx.Raw(`SELECT COALESCE(MAX(index), -1) FROM promo_uniques WHERE promo_id = ?`, promoID).Scan(&maxIndex).Error; err != nil {
+			return err
+		}
+
+		// Дубликаты отбрасываются как среди уже загруженных кодов, так и внутри запроса
+		seen := make(map[string]bool, len(codes)+len(existing))
+		for _, code := range existing {
+			seen[code] = true
+		}
+
+		newCodes := make([]entity.PromoUnique, 0, len(codes))
+		for _, code := range codes {
+			if seen[code] {
+				continue
+			}
+			seen[code] = true
+			maxIndex++
+			newCodes = append(newCodes, entity.PromoUnique{PromoID: promoID, Body: code, Index: maxIndex})
+		}
+
+		if len(newCodes) > 0 {
+			if err = tx.CreateInBatches(&newCodes, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		res.Added = len(newCodes)
+		res.Available = availableBefore + len(newCodes)
+
+		if !promo.Active && promo.ArchivedAt == nil && availableBefore == 0 && res.Added > 0 {
+			if err = tx.Exec(`UPDATE promos SET active = TRUE, updated_at = now() WHERE promo_id = ?`, promoID).Error; err != nil {
+				return err
+			}
+			res.Reactivated = true
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+// GetCodes is a method that returns codes of a UNIQUE promo with their redeemers and the total number of matching codes.
+/*
+ * status is "available", "activated" or empty for all codes.
+ */
+func (s *promoCodeStorage) GetCodes(ctx context.Context, promoID, companyID, status string, limit, offset int) ([]dto.PromoCode, int64, error) {
+	db := s.db.WithContext(ctx)
+
+	if _, err := getUniquePromo(db, promoID, companyID, false); err != nil {
+		return nil, 0, err
+	}
+
+	filter := ""
+	switch status {
+	case "available":
+		filter = ` AND pu.activated = FALSE`
+	case "activated":
+		filter = ` AND pu.activated = TRUE`
+	}
+
+	query := `
+		SELECT pu.promo_unique_id,
+			   pu.body,
+			   pu.activated,
+			   pu.activated_at,
+			   u.id AS user_id,
+			   u.name,
+			   u.surname
+		FROM promo_uniques pu
+				 LEFT JOIN users u ON u.id = pu.activated_by
+		WHERE pu.promo_id = ?` + filter + `
+		ORDER BY pu.index
+		LIMIT ? OFFSET ?`
+
+	type result struct {
+		PromoUniqueID string
+		Body          string
+		Activated     bool
+		ActivatedAt   *time.Time
+		UserID        *string
+		Name          *string
+		Surname       *string
+	}
+
+	var results []result
+	if err := db.Raw(query, promoID, limit, offset).Scan(&results).Error; err != nil {
+		return nil, 0, err
+	}
+
+	codes := make([]dto.PromoCode, 0, len(results))
+	for _, r := range results {
+		code := dto.PromoCode{
+			ID:     r.PromoUniqueID,
+			Code:   r.Body,
+			Status: "available",
+		}
+
+		if r.Activated {
+			code.Status = "activated"
+		}
+		if r.ActivatedAt != nil {
+			code.ActivatedAt = r.ActivatedAt.UTC().Format(time.RFC3339)
+		}
+		// Коды, активированные до появления activated_by, остаются без владельца
+		if r.UserID != nil {
+			code.ActivatedBy = &dto.PromoCodeOwner{UserID: *r.UserID}
+			if r.Name != nil {
+				code.ActivatedBy.Name = *r.Name
+			}
+			if r.Surname != nil {
+				code.ActivatedBy.Surname = *r.Surname
+			}
+		}
+
+		codes = append(codes, code)
+	}
+
+	var total int64
+	if err := db.Raw(`SELECT COUNT(*) FROM promo_uniques pu WHERE pu.promo_id = ?`+filter, promoID).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return codes, total, nil
+}
+
+// RevokeCodes is a method that deletes not yet activated codes of a UNIQUE promo, all of them if codes is empty.
+/*
+ * Activated codes are kept for history. A promo left without available codes is deactivated.
+ */
+func (s *promoCodeStorage) RevokeCodes(ctx context.Context, promoID, companyID string, codes []string) (dto.PromoCodesRevokeResponse, error) {
+	var res dto.PromoCodesRevokeResponse
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := getUniquePromo(tx, promoID, companyID, true); err != nil {
+			return err
+		}
+
+		query := tx.Where("promo_id = ? AND activated = FALSE", promoID)
+		if len(codes) > 0 {
+			query = query.Where("body IN ?", codes)
+		}
+
+		deleted := query.Delete(&entity.PromoUnique{})
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		res.Revoked = int(deleted.RowsAffected)
+
+		available, err := countAvailableCodes(tx, promoID)
+		if err != nil {
+			return err
+		}
+		res.Available = available
+
+		if available == 0 && res.Revoked > 0 {
+			return tx.Exec(`UPDATE promos SET active = FALSE, updated_at = now() WHERE promo_id = ?`, promoID).Error
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+// GetLowInventory is a method that returns UNIQUE promos of the company with at most threshold available codes.
+func (s *promoCodeStorage) GetLowInventory(ctx context.Context, companyID string, threshold int) ([]dto.PromoInventory, error) {
+	query := `
+		SELECT p.promo_id,
+			   p.description,
+			   p.active,
+			   COUNT(pu.promo_unique_id) FILTER (WHERE pu.activated = FALSE) AS available,
+			   COUNT(pu.promo_unique_id) FILTER (WHERE pu.activated = TRUE)  AS activated
+		FROM promos p
+				 LEFT JOIN promo_uniques pu ON pu.promo_id = p.promo_id
+		WHERE p.company_id = ?
+		  AND p.mode = 'UNIQUE'
+		  AND p.deleted_at IS NULL
+		GROUP BY p.promo_id
+		HAVING COUNT(pu.promo_unique_id) FILTER (WHERE pu.activated = FALSE) <= ?
+		ORDER BY available, p.created_at DESC`
+
+	promos := make([]dto.PromoInventory, 0)
+	err := s.db.WithContext(ctx).Raw(query, companyID, threshold).Scan(&promos).Error
+
+	return promos, err
+}
+
+// CheckPromo is a method that checks that the promo exists, is owned by the company and is in UNIQUE mode.
+func (s *promoCodeStorage) CheckPromo(ctx context.Context, promoID, companyID string) error {
+	_, err := getUniquePromo(s.db.WithContext(ctx), promoID, companyID, false)
+	return err
+}
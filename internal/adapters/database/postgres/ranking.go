@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"prod/internal/domain/dto"
+	"time"
+)
+
+// userCategoriesExpression is a CTE with the categories of promos the user liked or activated, weighted by interactions.
+const userCategoriesExpression = `
+	user_categories AS (
+		SELECT LOWER(c.name) AS name, COUNT(*) AS weight
+		FROM categories c
+				 INNER JOIN (SELECT l.promo_id FROM likes l WHERE l.user_id = ? AND l."like"
+							 UNION ALL
+							 SELECT a.promo_id FROM activations a WHERE a.user_id = ?) i ON i.promo_id = c.promo_id
+		GROUP BY LOWER(c.name)
+	)`
+
+// popularityExpression is an SQL expression of the non-personal part of the score, expects alias p (promos).
+/*
+ * Takes weights of likes, comments and velocity and the velocity window bounds.
+ */
+const popularityExpression = `
+	? * LN(1 + p.like_count) +
+	? * LN(1 + p.comment_count) +
+	? * LN(1 + (SELECT COUNT(*) FROM activations va WHERE va.promo_id = p.promo_id AND va.created_at > ? AND va.created_at <= ?))`
+
+// relevanceExpression is an SQL expression of the personal part of the score, expects alias p and the user_categories CTE.
+/*
+ * Takes weight of recency, the score time and the half-life in seconds, weight of affinity and weight of stock.
+ * Affinity is the share of the user's interactions in the promo's categories,
+ * stock is the share of codes left (remaining uses for COMMON, not activated codes for UNIQUE).
+ */
+const relevanceExpression = `
+	? * POWER(0.5, GREATEST(EXTRACT(EPOCH FROM (CAST(? AS timestamptz) - p.created_at)), 0) / ?) +
+	? * COALESCE((SELECT SUM(uc.weight) FROM categories pc INNER JOIN user_categories uc ON uc.name = LOWER(pc.name) WHERE pc.promo_id = p.promo_id)::float /
+				 NULLIF((SELECT SUM(weight) FROM user_categories), 0), 0) +
+	? * CASE
+			WHEN p.mode = 'COMMON' THEN GREATEST(p.max_count - p.used_count, 0)::float / GREATEST(p.max_count, 1)
+			ELSE COALESCE((SELECT (COUNT(*) FILTER (WHERE NOT pu.activated))::float / NULLIF(COUNT(*), 0) FROM promo_uniques pu WHERE pu.promo_id = p.promo_id), 0)
+		END`
+
+// feedScore is a function that returns the score expression of a ranked feed and its args.
+/*
+ * sortBy is "popular" or "relevance", scoredAt is the moment the score is computed as of.
+ * The returned CTE (empty for "popular") must be placed in the WITH clause, its args go first.
+ */
+func feedScore(sortBy string, ranking dto.FeedRanking, userID string, scoredAt time.Time) (cte string, cteArgs []interface{}, score string, scoreArgs []interface{}) {
+	score = popularityExpression
+	scoreArgs = []interface{}{
+		ranking.Likes,
+		ranking.Comments,
+		ranking.Velocity, scoredAt.Add(-ranking.VelocityWindow), scoredAt,
+	}
+
+	if sortBy != "relevance" {
+		return "", nil, score, scoreArgs
+	}
+
+	score += " +" + relevanceExpression
+	scoreArgs = append(scoreArgs,
+		ranking.Recency, scoredAt, ranking.RecencyHalfLife.Seconds(),
+		ranking.Affinity,
+		ranking.Stock,
+	)
+
+	return userCategoriesExpression, []interface{}{userID, userID}, score, scoreArgs
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/biter777/countries"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+)
+
+// GetCounters is a method that returns the current like, comment and stock counters of a promo.
+func (s *promoStorage) GetCounters(ctx context.Context, promoID string) (dto.PromoLiveUpdate, error) {
+	query := `
+		SELECT p.promo_id,
+			   p.like_count,
+			   p.comment_count,
+			   p.used_count,
+			   CASE
+				   WHEN p.mode = 'COMMON' THEN GREATEST(p.max_count - p.used_count, 0)
+				   ELSE (SELECT COUNT(*) FROM promo_uniques pu WHERE pu.promo_id = p.promo_id AND NOT pu.activated)
+				   END AS remaining,
+			   p.active
+		FROM promos p
+		WHERE p.promo_id = ?
+		  AND p.deleted_at IS NULL`
+
+	var updates []dto.PromoLiveUpdate
+	if err := s.db.WithContext(ctx).Raw(query, promoID).Scan(&updates).Error; err != nil {
+		return dto.PromoLiveUpdate{}, err
+	}
+	if len(updates) == 0 {
+		return dto.PromoLiveUpdate{}, errorz.NotFound
+	}
+
+	return updates[0], nil
+}
+
+// GetVisiblePromoIDs is a method that returns those of the promos the user can see in the feed: live and targeted at the user.
+func (s *promoStorage) GetVisiblePromoIDs(ctx context.Context, age int, country countries.CountryCode, userID string, promoIDs []string) ([]string, error) {
+	query := fmt.Sprintf(`
+		SELECT p.promo_id
+		FROM promos p
+		WHERE p.promo_id IN ?
+		  AND %s
+		  AND %s`, targetExpression, visibleCondition)
+
+	args := append([]interface{}{promoIDs}, targetArgs(age, country, userID)...)
+
+	var visible []string
+	if err := s.db.WithContext(ctx).Raw(query, args...).Scan(&visible).Error; err != nil {
+		return nil, err
+	}
+
+	return visible, nil
+}
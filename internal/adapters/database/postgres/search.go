@@ -0,0 +1,248 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/biter777/countries"
+	"prod/internal/domain/dto"
+	"strings"
+)
+
+// searchVectorExpression is an SQL expression that builds promos.search_vector, expects aliases p (promos) and b (businesses).
+/*
+ * Company name and categories are weighted higher than the description,
+ * the description is indexed with both russian and english stemming.
+ */
+const searchVectorExpression = `
+	setweight(to_tsvector('simple', COALESCE(b.name, '')), 'A') ||
+	setweight(to_tsvector('simple', COALESCE((SELECT STRING_AGG(c.name, ' ') FROM categories c WHERE c.promo_id = p.promo_id), '')), 'A') ||
+	setweight(to_tsvector('russian', p.description), 'B') ||
+	setweight(to_tsvector('english', p.description), 'B')`
+
+// searchQueryExpression is an SQL expression that builds tsquery from the user's input, takes the same input three times.
+const searchQueryExpression = `websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?) || websearch_to_tsquery('simple', ?)`
+
+// facetLimit is a max number of values returned for each facet.
+const facetLimit = 20
+
+// RefreshSearchVector is a method to rebuild the full-text search vector of a promo.
+func (s *promoStorage) RefreshSearchVector(ctx context.Context, promoID string) error {
+	query := `UPDATE promos p SET search_vector = ` + searchVectorExpression + ` FROM businesses b WHERE b.id = p.company_id AND p.promo_id = ?`
+	return s.db.WithContext(ctx).Exec(query, promoID).Error
+}
+
+// searchFilter is a struct that builds the WHERE clause shared by search, count and facet queries.
+type searchFilter struct {
+	age     int
+	country countries.CountryCode
+	userID  string
+	search  dto.PromoSearchRequest
+}
+
+// where is a method that returns conditions and their args, skipping the facet given in exclude.
+/*
+ * exclude is one of "category", "company", "mode" or "" - facet counts ignore their own filter.
+ */
+func (f searchFilter) where(exclude string) (string, []interface{}) {
+	conditions := []string{
+		"p.search_vector @@ sq.query",
+		targetExpression,
+		visibleCondition,
+	}
+	args := targetArgs(f.age, f.country, f.userID)
+
+	if len(f.search.Categories) > 0 && exclude != "category" {
+		lowered := make([]string, 0, len(f.search.Categories))
+		for _, category := range f.search.Categories {
+			lowered = append(lowered, strings.ToLower(category))
+		}
+		conditions = append(conditions, "EXISTS(SELECT 1 FROM categories fc WHERE fc.promo_id = p.promo_id AND LOWER(fc.name) IN ?)")
+		args = append(args, lowered)
+	}
+
+	if len(f.search.CompanyIDs) > 0 && exclude != "company" {
+		conditions = append(conditions, "p.company_id IN ?")
+		args = append(args, f.search.CompanyIDs)
+	}
+
+	if f.search.Mode != "" && exclude != "mode" {
+		conditions = append(conditions, "p.mode = ?")
+		args = append(args, f.search.Mode)
+	}
+
+	if f.search.Active != "" {
+		conditions = append(conditions, activeExpression+" = ?")
+		args = append(args, f.search.Active)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// queryArgs is a method that returns args for searchQueryExpression.
+func (f searchFilter) queryArgs() []interface{} {
+	return []interface{}{f.search.Query, f.search.Query, f.search.Query}
+}
+
+// Search is a method to run a full-text search over promos visible to the user with facet counts.
+func (s *promoStorage) Search(ctx context.Context, age int, country countries.CountryCode, userID string, search dto.PromoSearchRequest) ([]dto.PromoSearchResult, dto.PromoSearchFacets, int64, error) {
+	filter := searchFilter{
+		age:     age,
+		country: country,
+		userID:  userID,
+		search:  search,
+	}
+
+	baseQuery := `
+		WITH sq AS (SELECT ` + searchQueryExpression + ` AS query)
+		SELECT p.promo_id,
+			   p.description,
+			   p.image_url,
+			   ` + activeExpression + ` AS active,
+			   p.like_count,
+			   p.comment_count,
+			   p.used_count,
+			   EXISTS(SELECT 1 FROM activations a WHERE a.user_id = ? AND a.promo_id = p.promo_id)        AS is_activated,
+			   EXISTS(SELECT 1 FROM likes l WHERE l.user_id = ? AND l.promo_id = p.promo_id AND l."like") AS is_liked,
+			   ts_rank(p.search_vector, sq.query)                                                            AS rank,
+			   ts_headline('russian', p.description, sq.query,
+						   'StartSel=<b>, StopSel=</b>, MaxWords=25, MinWords=10, MaxFragments=2')            AS headline,
+			   b.name                                                                                        AS business_name,
+			   b.id                                                                                          AS business_id
+		FROM promos p
+				 CROSS JOIN sq
+				 INNER JOIN businesses b ON b.id = p.company_id
+		WHERE %s
+		ORDER BY rank DESC, p.created_at DESC
+		LIMIT ? OFFSET ?`
+
+	type result struct {
+		PromoID      string
+		BusinessID   string
+		BusinessName string
+		Description  string
+		ImageURL     string
+		Active       bool
+		LikeCount    int
+		CommentCount int
+		UsedCount    int
+		IsActivated  bool
+		IsLiked      bool
+		Rank         float64
+		Headline     string
+	}
+
+	where, whereArgs := filter.where("")
+
+	var args []interface{}
+	args = append(args, filter.queryArgs()...)
+	args = append(args, userID, userID)
+	args = append(args, whereArgs...)
+	args = append(args, search.Limit, search.Offset)
+
+	var results []result
+	if err := s.db.WithContext(ctx).Raw(fmt.Sprintf(baseQuery, where), args...).Scan(&results).Error; err != nil {
+		return nil, dto.PromoSearchFacets{}, 0, err
+	}
+
+	promos := make([]dto.PromoSearchResult, 0, len(results))
+	for _, r := range results {
+		promos = append(promos, dto.PromoSearchResult{
+			PromoForUser: dto.PromoForUser{
+				PromoID:           r.PromoID,
+				CompanyID:         r.BusinessID,
+				CompanyName:       r.BusinessName,
+				Description:       r.Description,
+				ImageURL:          r.ImageURL,
+				Active:            r.Active,
+				LikeCount:         r.LikeCount,
+				CommentCount:      r.CommentCount,
+				IsLikedByUser:     r.IsLiked,
+				IsActivatedByUser: r.IsActivated,
+				UsedCount:         r.UsedCount,
+			},
+			Headline: r.Headline,
+			Rank:     r.Rank,
+		})
+	}
+
+	countQuery := `
+		WITH sq AS (SELECT ` + searchQueryExpression + ` AS query)
+		SELECT COUNT(*)
+		FROM promos p
+				 CROSS JOIN sq
+		WHERE ` + where
+
+	var total int64
+	if err := s.db.WithContext(ctx).Raw(countQuery, append(filter.queryArgs(), whereArgs...)...).Scan(&total).Error; err != nil {
+		return nil, dto.PromoSearchFacets{}, 0, err
+	}
+
+	facets, err := s.searchFacets(ctx, filter)
+	if err != nil {
+		return nil, dto.PromoSearchFacets{}, 0, err
+	}
+
+	return promos, facets, total, nil
+}
+
+// searchFacets is a method that counts matching promos by category, company and mode.
+func (s *promoStorage) searchFacets(ctx context.Context, filter searchFilter) (dto.PromoSearchFacets, error) {
+	facetQueries := []struct {
+		exclude string
+		query   string
+		target  *[]dto.Facet
+	}{
+		{
+			exclude: "category",
+			query: `
+				SELECT LOWER(c.name) AS value, '' AS name, COUNT(DISTINCT p.promo_id) AS count
+				FROM promos p
+						 CROSS JOIN sq
+						 INNER JOIN categories c ON c.promo_id = p.promo_id
+				WHERE %s
+				GROUP BY LOWER(c.name)`,
+		},
+		{
+			exclude: "company",
+			query: `
+				SELECT b.id AS value, b.name AS name, COUNT(*) AS count
+				FROM promos p
+						 CROSS JOIN sq
+						 INNER JOIN businesses b ON b.id = p.company_id
+				WHERE %s
+				GROUP BY b.id, b.name`,
+		},
+		{
+			exclude: "mode",
+			query: `
+				SELECT p.mode AS value, '' AS name, COUNT(*) AS count
+				FROM promos p
+						 CROSS JOIN sq
+				WHERE %s
+				GROUP BY p.mode`,
+		},
+	}
+
+	var facets dto.PromoSearchFacets
+	facetQueries[0].target = &facets.Categories
+	facetQueries[1].target = &facets.Companies
+	facetQueries[2].target = &facets.Modes
+
+	for _, facet := range facetQueries {
+		where, whereArgs := filter.where(facet.exclude)
+		query := `WITH sq AS (SELECT ` + searchQueryExpression + ` AS query)` +
+			fmt.Sprintf(facet.query, where) +
+			` ORDER BY count DESC, value LIMIT ?`
+
+		args := append(filter.queryArgs(), whereArgs...)
+		args = append(args, facetLimit)
+
+		values := make([]dto.Facet, 0)
+		if err := s.db.WithContext(ctx).Raw(query, args...).Scan(&values).Error; err != nil {
+			return dto.PromoSearchFacets{}, err
+		}
+		*facet.target = values
+	}
+
+	return facets, nil
+}
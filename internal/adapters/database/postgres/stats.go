@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"prod/internal/domain/dto"
+	"time"
+)
+
+// rollupLag is a delay before events are rolled up, so that rows of transactions still in flight aren't skipped.
+const rollupLag = time.Minute
+
+// rollupSource is a source of events counted in a column of promo_daily_stats.
+/*
+ * query returns promo_id, day and count of events in (?, ?].
+ */
+type rollupSource struct {
+	name   string
+	column string
+	query  string
+}
+
+var rollupSources = []rollupSource{
+	{
+		name:   "activations",
+		column: "activations",
+		query:  `SELECT a.promo_id, (a.created_at AT TIME ZONE 'UTC')::date AS day, COUNT(*) AS count FROM activations a WHERE a.created_at > ? AND a.created_at <= ? GROUP BY 1, 2`,
+	},
+	{
+		name:   "likes",
+		column: "likes",
+		// liked_at сбрасывается повторным лайком, а снятый лайк его не меняет: считаются ±1 по событиям LikeToggled
+		query: `SELECT e.promo_id, (e.created_at AT TIME ZONE 'UTC')::date AS day, SUM(CASE WHEN (e.payload ->> 'liked')::boolean THEN 1 ELSE -1 END) AS count
+				FROM outbox_events e WHERE e.type = '` + dto.EventLikeToggled + `' AND e.created_at > ? AND e.created_at <= ? GROUP BY 1, 2`,
+	},
+	{
+		name:   "comments",
+		column: "comments",
+		// Комментарий с модерации попадает в день одобрения, а не написания: к тому времени его created_at уже позади
+		query: `SELECT c.promo_id, (c.published_at AT TIME ZONE 'UTC')::date AS day, COUNT(*) AS count FROM comments c WHERE c.status = 'published' AND c.published_at > ? AND c.published_at <= ? GROUP BY 1, 2`,
+	},
+	{
+		name:   "impressions",
+		column: "impressions",
+		query:  `SELECT v.promo_id, v.day, COUNT(*) AS count FROM promo_views v WHERE v.kind = 'impression' AND v.created_at > ? AND v.created_at <= ? GROUP BY 1, 2`,
+	},
+	{
+		name:   "views",
+		column: "views",
+		query:  `SELECT v.promo_id, v.day, COUNT(*) AS count FROM promo_views v WHERE v.kind = 'view' AND v.created_at > ? AND v.created_at <= ? GROUP BY 1, 2`,
+	},
+}
+
+// statsStorage is a struct that contains a pointer to a gorm.DB instance to interact with dashboard rollups.
+type statsStorage struct {
+	db *gorm.DB
+}
+
+// NewStatsStorage is a function that returns a new instance of statsStorage.
+func NewStatsStorage(db *gorm.DB) *statsStorage {
+	return &statsStorage{db: db}
+}
+
+// Refresh is a method that adds events since the last refresh to the daily rollups.
+/*
+ * Each source is locked by its watermark row, so concurrent refreshes of several instances don't count events twice.
+ */
+func (s *statsStorage) Refresh(ctx context.Context) error {
+	upsert := `
+		INSERT INTO promo_daily_stats (promo_id, day, company_id, %[1]s)
+		SELECT e.promo_id, e.day, p.company_id, e.count
+		FROM (%[2]s) e
+				 INNER JOIN promos p ON p.promo_id = e.promo_id
+		ON CONFLICT (promo_id, day) DO UPDATE SET %[1]s = promo_daily_stats.%[1]s + EXCLUDED.%[1]s`
+
+	until := time.Now().Add(-rollupLag)
+
+	for _, source := range rollupSources {
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(`INSERT INTO stats_rollups (source, refreshed_until) VALUES (?, ?) ON CONFLICT (source) DO NOTHING`, source.name, time.Unix(0, 0)).Error; err != nil {
+				return err
+			}
+
+			var from time.Time
+			if err := tx.Raw(`SELECT refreshed_until FROM stats_rollups WHERE source = ? FOR UPDATE`, source.name).Scan(&from).Error; err != nil {
+				return err
+			}
+			if !from.Before(until) {
+				return nil
+			}
+
+			if err := tx.Exec(fmt.Sprintf(upsert, source.column, source.query), from, until).Error; err != nil {
+				return err
+			}
+
+			return tx.Exec(`UPDATE stats_rollups SET refreshed_until = ? WHERE source = ?`, until, source.name).Error
+		})
+		if err != nil {
+			return fmt.Errorf("refresh %s rollup: %w", source.name, err)
+		}
+	}
+
+	return nil
+}
+
+// GetCompanyStats is a method that returns the dashboard of the company over days [from, to).
+func (s *statsStorage) GetCompanyStats(ctx context.Context, companyID string, from, to time.Time, top int) (dto.CompanyStatsResponse, error) {
+	stats := dto.CompanyStatsResponse{
+		From: from,
+		To:   to,
+	}
+
+	totalsQuery := `
+		SELECT COALESCE(SUM(s.activations) FILTER (WHERE s.day >= ? AND s.day < ?), 0) AS activations,
+			   COALESCE(SUM(s.activations), 0)                                        AS activations_total,
+			   COALESCE(SUM(s.likes) FILTER (WHERE s.day >= ? AND s.day < ?), 0)       AS likes,
+			   COALESCE(SUM(s.comments) FILTER (WHERE s.day >= ? AND s.day < ?), 0)    AS comments,
+			   COALESCE(SUM(s.impressions) FILTER (WHERE s.day >= ? AND s.day < ?), 0) AS impressions,
+			   COALESCE(SUM(s.views) FILTER (WHERE s.day >= ? AND s.day < ?), 0)       AS views
+		FROM promo_daily_stats s
+		WHERE s.company_id = ?`
+
+	var totals struct {
+		Activations      int64
+		ActivationsTotal int64
+		Likes            int64
+		Comments         int64
+		Impressions      int64
+		Views            int64
+	}
+	if err := s.db.WithContext(ctx).Raw(totalsQuery, from, to, from, to, from, to, from, to, from, to, companyID).Scan(&totals).Error; err != nil {
+		return dto.CompanyStatsResponse{}, err
+	}
+	stats.Activations, stats.ActivationsTotal = totals.Activations, totals.ActivationsTotal
+	stats.Likes, stats.Comments = totals.Likes, totals.Comments
+	stats.Impressions, stats.Views = totals.Impressions, totals.Views
+	if totals.Impressions > 0 {
+		viewRate := float64(totals.Views) / float64(totals.Impressions)
+		stats.ViewRate = &viewRate
+	}
+	if totals.Views > 0 {
+		conversion := float64(totals.Activations) / float64(totals.Views)
+		stats.Conversion = &conversion
+	}
+
+	// Удалённые промо в топы не попадают, но их активации остаются в итогах
+	topQuery := `
+		SELECT s.promo_id,
+			   p.description,
+			   SUM(s.activations)                                 AS activations,
+			   SUM(s.likes)                                       AS likes,
+			   SUM(s.impressions)                                 AS impressions,
+			   SUM(s.views)                                       AS views,
+			   SUM(s.views)::float / NULLIF(SUM(s.impressions), 0) AS view_rate,
+			   SUM(s.activations)::float / NULLIF(SUM(s.views), 0) AS activation_rate,
+			   SUM(s.likes)::float / NULLIF(SUM(s.views), 0)       AS like_rate
+		FROM promo_daily_stats s
+				 INNER JOIN promos p ON p.promo_id = s.promo_id
+		WHERE s.company_id = ?
+		  AND s.day >= ?
+		  AND s.day < ?
+		  AND p.deleted_at IS NULL
+		GROUP BY s.promo_id, p.description
+		ORDER BY %s
+		LIMIT ?`
+
+	stats.TopByActivations = []dto.CompanyPromoStats{}
+	if err := s.db.WithContext(ctx).Raw(fmt.Sprintf(topQuery, "activations DESC, s.promo_id"), companyID, from, to, top).Scan(&stats.TopByActivations).Error; err != nil {
+		return dto.CompanyStatsResponse{}, err
+	}
+
+	stats.TopByLikeRate = []dto.CompanyPromoStats{}
+	if err := s.db.WithContext(ctx).Raw(fmt.Sprintf(topQuery, "like_rate DESC NULLS LAST, likes DESC, s.promo_id"), companyID, from, to, top).Scan(&stats.TopByLikeRate).Error; err != nil {
+		return dto.CompanyStatsResponse{}, err
+	}
+
+	exhaustedQuery := `
+		SELECT p.promo_id, p.description, p.mode, p.used_count
+		FROM promos p
+		WHERE p.company_id = ?
+		  AND p.deleted_at IS NULL
+		  AND p.archived_at IS NULL
+		  AND CASE
+				  WHEN p.mode = 'COMMON' THEN p.used_count >= p.max_count
+				  ELSE NOT EXISTS(SELECT 1 FROM promo_uniques pu WHERE pu.promo_id = p.promo_id AND NOT pu.activated)
+			  END
+		ORDER BY p.updated_at DESC, p.promo_id
+		LIMIT 100`
+
+	stats.Exhausted = []dto.ExhaustedPromo{}
+	if err := s.db.WithContext(ctx).Raw(exhaustedQuery, companyID).Scan(&stats.Exhausted).Error; err != nil {
+		return dto.CompanyStatsResponse{}, err
+	}
+
+	inventoryQuery := `
+		SELECT COUNT(DISTINCT p.promo_id)                                AS promos,
+			   COUNT(pu.promo_unique_id)                                 AS total,
+			   COUNT(pu.promo_unique_id) FILTER (WHERE NOT pu.activated) AS available
+		FROM promos p
+				 LEFT JOIN promo_uniques pu ON pu.promo_id = p.promo_id
+		WHERE p.company_id = ?
+		  AND p.mode = 'UNIQUE'
+		  AND p.deleted_at IS NULL`
+
+	if err := s.db.WithContext(ctx).Raw(inventoryQuery, companyID).Scan(&stats.UniqueInventory).Error; err != nil {
+		return dto.CompanyStatsResponse{}, err
+	}
+
+	// Свежесть дашборда - по самому отстающему источнику
+	if err := s.db.WithContext(ctx).Raw(`SELECT COALESCE(MIN(refreshed_until), to_timestamp(0)) FROM stats_rollups`).Scan(&stats.RefreshedUntil).Error; err != nil {
+		return dto.CompanyStatsResponse{}, err
+	}
+
+	return stats, nil
+}
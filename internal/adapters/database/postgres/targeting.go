@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/biter777/countries"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"strings"
+)
+
+// targetTemplate is an SQL condition of whether a promo (alias p) is targeted at the user: %[1]s - age, %[2]s - country, %[3]s - user id.
+const targetTemplate = `(p.age_from <= %[1]s
+	AND p.age_until >= %[1]s
+	AND (p.country = %[2]s OR p.country = 0)
+	AND (NOT EXISTS(SELECT 1 FROM promo_countries tc WHERE tc.promo_id = p.promo_id AND NOT tc.excluded)
+		OR EXISTS(SELECT 1 FROM promo_countries tc WHERE tc.promo_id = p.promo_id AND NOT tc.excluded AND tc.country = %[2]s))
+	AND NOT EXISTS(SELECT 1 FROM promo_countries tc WHERE tc.promo_id = p.promo_id AND tc.excluded AND tc.country = %[2]s)
+	AND (NOT EXISTS(SELECT 1 FROM promo_affinities ta WHERE ta.promo_id = p.promo_id)
+		OR EXISTS(SELECT 1
+				  FROM promo_affinities ta
+						   INNER JOIN categories c ON LOWER(c.name) = ta.name
+						   INNER JOIN (SELECT l.promo_id FROM likes l WHERE l.user_id = %[3]s AND l."like"
+									   UNION ALL
+									   SELECT a.promo_id FROM activations a WHERE a.user_id = %[3]s) i ON i.promo_id = c.promo_id
+				  WHERE ta.promo_id = p.promo_id)))`
+
+// targetExpression is an SQL condition of whether a promo (alias p) is targeted at the user.
+/*
+ * Takes age twice, country three times and the user id twice.
+ * Included countries narrow the audience to the listed ones, excluded countries are never shown the promo,
+ * affinity categories require the user to have liked or activated a promo in one of them.
+ */
+var targetExpression = fmt.Sprintf(targetTemplate, "?", "?", "?")
+
+// targetArgs is a function that returns args for targetExpression.
+func targetArgs(age int, country countries.CountryCode, userID string) []interface{} {
+	return []interface{}{age, age, country, country, country, userID, userID}
+}
+
+// targetExpressionOf is a function that returns targetExpression over SQL expressions of the user instead of args.
+func targetExpressionOf(age, country, userID string) string {
+	return fmt.Sprintf(targetTemplate, age, country, userID)
+}
+
+// insertTargeting is a method that saves included and excluded countries and affinity categories of a promo.
+func (s *promoStorage) insertTargeting(ctx context.Context, promo entity.Promo) error {
+	for _, country := range promo.Countries {
+		if err := s.db.WithContext(ctx).Exec("INSERT INTO promo_countries (promo_id, country, excluded) VALUES (?, ?, ?);", promo.PromoID, country.Country, country.Excluded).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, affinity := range promo.Affinities {
+		if err := s.db.WithContext(ctx).Exec("INSERT INTO promo_affinities (promo_id, name) VALUES (?, ?);", promo.PromoID, strings.ToLower(affinity.Name)).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceTargeting is a method that replaces the lists of the promo's targeting given in target, nil lists are kept.
+/*
+ * A single country and the included countries replace each other.
+ * Lists are deleted and inserted again, so it's called on a transaction storage, see Update.
+ */
+func (s *promoStorage) replaceTargeting(ctx context.Context, promoID string, target dto.Target) error {
+	replaceCountries := func(names []string, excluded bool) error {
+		if names == nil {
+			return nil
+		}
+
+		if err := s.db.WithContext(ctx).Exec("DELETE FROM promo_countries WHERE promo_id = ? AND excluded = ?", promoID, excluded).Error; err != nil {
+			return err
+		}
+
+		promo := entity.Promo{PromoID: promoID}
+		for _, name := range names {
+			promo.Countries = append(promo.Countries, entity.PromoCountry{Country: countries.ByName(strings.ToUpper(name)), Excluded: excluded})
+		}
+
+		return s.insertTargeting(ctx, promo)
+	}
+
+	// Одна страна и список стран взаимоисключающие: заданное последним заменяет другое
+	if target.Country != "" {
+		if err := s.db.WithContext(ctx).Exec("DELETE FROM promo_countries WHERE promo_id = ? AND NOT excluded", promoID).Error; err != nil {
+			return err
+		}
+	}
+	if len(target.Countries) > 0 {
+		if err := s.db.WithContext(ctx).Exec("UPDATE promos SET country = 0, country_original = '' WHERE promo_id = ?", promoID).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := replaceCountries(target.Countries, false); err != nil {
+		return err
+	}
+
+	if err := replaceCountries(target.ExcludedCountries, true); err != nil {
+		return err
+	}
+
+	if target.AffinityCategories != nil {
+		if err := s.db.WithContext(ctx).Exec("DELETE FROM promo_affinities WHERE promo_id = ?", promoID).Error; err != nil {
+			return err
+		}
+
+		promo := entity.Promo{PromoID: promoID}
+		for _, name := range target.AffinityCategories {
+			promo.Affinities = append(promo.Affinities, entity.PromoAffinity{Name: strings.ToLower(name)})
+		}
+
+		return s.insertTargeting(ctx, promo)
+	}
+
+	return nil
+}
+
+// loadTargeting is a method that fills included and excluded countries and affinity categories of the promos.
+func (s *promoStorage) loadTargeting(ctx context.Context, promos []*entity.Promo) error {
+	if len(promos) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(promos))
+	byID := make(map[string]*entity.Promo, len(promos))
+	for _, promo := range promos {
+		ids = append(ids, promo.PromoID)
+		byID[promo.PromoID] = promo
+	}
+
+	var promoCountries []entity.PromoCountry
+	if err := s.db.WithContext(ctx).Where("promo_id IN ?", ids).Order("country").Find(&promoCountries).Error; err != nil {
+		return err
+	}
+
+	var affinities []entity.PromoAffinity
+	if err := s.db.WithContext(ctx).Where("promo_id IN ?", ids).Order("name").Find(&affinities).Error; err != nil {
+		return err
+	}
+
+	for _, country := range promoCountries {
+		byID[country.PromoID].Countries = append(byID[country.PromoID].Countries, country)
+	}
+
+	for _, affinity := range affinities {
+		byID[affinity.PromoID].Affinities = append(byID[affinity.PromoID].Affinities, affinity)
+	}
+
+	return nil
+}
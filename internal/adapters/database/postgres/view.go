@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"prod/internal/domain/entity"
+)
+
+// viewBatchSize is a number of rows in one INSERT of views.
+const viewBatchSize = 500
+
+// viewStorage is a struct that contains a pointer to a gorm.DB instance to record promo views.
+type viewStorage struct {
+	db *gorm.DB
+}
+
+// NewViewStorage is a function that returns a new instance of viewStorage.
+func NewViewStorage(db *gorm.DB) *viewStorage {
+	return &viewStorage{db: db}
+}
+
+// InsertViews is a method that records views, a view already recorded for the user, promo, kind and day is skipped.
+func (s *viewStorage) InsertViews(ctx context.Context, views []entity.PromoView) error {
+	for start := 0; start < len(views); start += viewBatchSize {
+		end := min(start+viewBatchSize, len(views))
+
+		rows := make([][]interface{}, 0, end-start)
+		for _, view := range views[start:end] {
+			rows = append(rows, []interface{}{view.PromoID, view.UserID, view.Day, view.Kind})
+		}
+
+		// created_at (по умолчанию now()) - время записи, а не просмотра: по нему дозаполняются агрегаты дашборда
+		query := `INSERT INTO promo_views (promo_id, user_id, day, kind) VALUES ? ON CONFLICT DO NOTHING`
+		if err := s.db.WithContext(ctx).Exec(query, rows).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"prod/internal/domain/utils/cursor"
+	"time"
+)
+
+// webhookStorage is a struct that contains a pointer to a gorm.DB instance to manage webhooks and their deliveries.
+type webhookStorage struct {
+	db *gorm.DB
+}
+
+// NewWebhookStorage is a function that returns a new instance of webhookStorage.
+func NewWebhookStorage(db *gorm.DB) *webhookStorage {
+	return &webhookStorage{db: db}
+}
+
+// Create is a method to create a new Webhook in database.
+func (s *webhookStorage) Create(ctx context.Context, webhook entity.Webhook) (*entity.Webhook, error) {
+	err := s.db.WithContext(ctx).Create(&webhook).Error
+	return &webhook, err
+}
+
+// List is a method that returns all webhooks of the company, the oldest first.
+func (s *webhookStorage) List(ctx context.Context, companyID string) ([]entity.Webhook, error) {
+	webhooks := []entity.Webhook{}
+	err := s.db.WithContext(ctx).Where("company_id = ?", companyID).Order("created_at, webhook_id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// GetByID is a method that returns a webhook owned by the company.
+func (s *webhookStorage) GetByID(ctx context.Context, webhookID, companyID string) (*entity.Webhook, error) {
+	var webhook entity.Webhook
+	err := s.db.WithContext(ctx).Where("webhook_id = ? AND company_id = ?", webhookID, companyID).First(&webhook).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errorz.NotFound
+	}
+
+	return &webhook, err
+}
+
+// Update is a method that saves the url, secret, events and active flag of a Webhook.
+func (s *webhookStorage) Update(ctx context.Context, webhook *entity.Webhook) error {
+	return s.db.WithContext(ctx).Model(webhook).Select("URL", "Secret", "Events", "Active", "UpdatedAt").Updates(webhook).Error
+}
+
+// Delete is a method that deletes a webhook with its delivery log.
+func (s *webhookStorage) Delete(ctx context.Context, webhookID string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, webhookID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`DELETE FROM webhooks WHERE webhook_id = ?`, webhookID).Error
+	})
+}
+
+// Enqueue is a method that queues the event for every active webhook of the promo's company subscribed to it.
+func (s *webhookStorage) Enqueue(ctx context.Context, promoID string, payload dto.WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, created_at, event_id, event, payload, status, next_attempt_at)
+		SELECT w.webhook_id, now(), ?, ?, ?, 'pending', now()
+		FROM webhooks w
+				 INNER JOIN promos p ON p.company_id = w.company_id
+		WHERE p.promo_id = ?
+		  AND w.active
+		  AND ? = ANY (string_to_array(w.events, ','))
+		-- Событие, опубликованное повторно, не ставится в очередь второй раз
+		ON CONFLICT (webhook_id, event_id) DO NOTHING`
+
+	return s.db.WithContext(ctx).Exec(query, payload.ID, payload.Type, string(body), promoID, payload.Type).Error
+}
+
+// ClaimDue is a method that takes up to limit pending deliveries whose time has come.
+/*
+ * Claimed deliveries have next_attempt_at moved by lease, so other instances skip them while they are sent,
+ * and a delivery of a crashed instance is retried after the lease.
+ */
+func (s *webhookStorage) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]dto.WebhookDispatch, error) {
+	query := `
+		WITH due AS (SELECT d.delivery_id
+					 FROM webhook_deliveries d
+					 WHERE d.status = 'pending'
+					   AND d.next_attempt_at <= now()
+					 ORDER BY d.next_attempt_at
+					 LIMIT ? FOR UPDATE SKIP LOCKED),
+			 claimed AS (
+				 UPDATE webhook_deliveries d
+					 SET next_attempt_at = ?
+					 FROM due
+					 WHERE d.delivery_id = due.delivery_id
+					 RETURNING d.delivery_id, d.webhook_id, d.event_id, d.event, d.payload, d.attempts)
+		SELECT c.delivery_id, c.event_id, c.event, c.payload, c.attempts, w.url, w.secret
+		FROM claimed c
+				 INNER JOIN webhooks w ON w.webhook_id = c.webhook_id`
+
+	var dispatches []dto.WebhookDispatch
+	err := s.db.WithContext(ctx).Raw(query, limit, time.Now().Add(lease)).Scan(&dispatches).Error
+	return dispatches, err
+}
+
+// RecordAttempt is a method that saves the result of an attempt to deliver.
+/*
+ * status is delivered, dead or pending, a pending delivery is retried at nextAttemptAt.
+ */
+func (s *webhookStorage) RecordAttempt(ctx context.Context, deliveryID, status string, nextAttemptAt time.Time, lastStatus *int, lastError *string) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status          = ?,
+			attempts        = attempts + 1,
+			next_attempt_at = ?,
+			last_attempt_at = now(),
+			last_status     = ?,
+			last_error      = ?,
+			delivered_at    = CASE WHEN ? = 'delivered' THEN now() END
+		WHERE delivery_id = ?`
+
+	return s.db.WithContext(ctx).Exec(query, status, nextAttemptAt, lastStatus, lastError, status, deliveryID).Error
+}
+
+// ListDeliveries is a method that returns the delivery log of a webhook, the newest first, optionally only with the status.
+func (s *webhookStorage) ListDeliveries(ctx context.Context, webhookID, status string, page dto.Page) ([]entity.WebhookDelivery, string, int64, error) {
+	where := `webhook_id = ?`
+	args := []interface{}{webhookID}
+	if status != "" {
+		where += ` AND status = ?`
+		args = append(args, status)
+	}
+
+	query := `SELECT * FROM webhook_deliveries WHERE ` + where
+	pageArgs := append([]interface{}{}, args...)
+	if page.Cursor != nil {
+		query += ` AND (created_at, delivery_id) < (?, ?)`
+		pageArgs = append(pageArgs, page.Cursor.Time, page.Cursor.ID)
+	}
+	query += `
+		ORDER BY created_at DESC, delivery_id DESC
+		LIMIT ? OFFSET ?`
+	pageArgs = append(pageArgs, page.Limit+1, page.Offset)
+
+	var deliveries []entity.WebhookDelivery
+	if err := s.db.WithContext(ctx).Raw(query, pageArgs...).Scan(&deliveries).Error; err != nil {
+		return nil, "", 0, err
+	}
+
+	var nextCursor string
+	if len(deliveries) > page.Limit {
+		deliveries = deliveries[:page.Limit]
+		last := deliveries[len(deliveries)-1]
+		nextCursor = cursor.Encode(cursor.Cursor{Time: last.CreatedAt, ID: last.DeliveryID})
+	}
+
+	if !page.NeedTotal() {
+		return deliveries, nextCursor, 0, nil
+	}
+
+	var total int64
+	if err := s.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM webhook_deliveries WHERE `+where, args...).Scan(&total).Error; err != nil {
+		return nil, "", 0, err
+	}
+
+	return deliveries, nextCursor, total, nil
+}
+
+// Redeliver is a method that queues a delivery of the webhook again with a fresh set of attempts.
+func (s *webhookStorage) Redeliver(ctx context.Context, webhookID, deliveryID string) (*entity.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status          = 'pending',
+			attempts        = 0,
+			next_attempt_at = now(),
+			delivered_at    = NULL
+		WHERE delivery_id = ?
+		  AND webhook_id = ?
+		RETURNING *`
+
+	var deliveries []entity.WebhookDelivery
+	if err := s.db.WithContext(ctx).Raw(query, deliveryID, webhookID).Scan(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, errorz.NotFound
+	}
+
+	return &deliveries[0], nil
+}
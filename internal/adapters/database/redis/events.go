@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"prod/internal/domain/dto"
+	"strconv"
+)
+
+// eventStreamStorage is a sink of the event bus that appends events to a Redis stream.
+type eventStreamStorage struct {
+	db     *redis.Client
+	stream string
+	maxLen int64
+}
+
+func NewEventStreamStorage(db *redis.Client, stream string, maxLen int64) *eventStreamStorage {
+	return &eventStreamStorage{db: db, stream: stream, maxLen: maxLen}
+}
+
+// Publish is a method that appends the event to the stream, trimmed to about maxLen entries.
+/*
+ * An event published again after a failure of another subscriber is appended again, consumers dedupe by id.
+ */
+func (s *eventStreamStorage) Publish(ctx context.Context, event dto.DomainEvent) error {
+	return s.db.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		MaxLen: s.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"id":         event.ID,
+			"seq":        strconv.FormatInt(event.Seq, 10),
+			"type":       event.Type,
+			"promo_id":   event.PromoID,
+			"created_at": event.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+			"payload":    event.Payload,
+		},
+	}).Err()
+}
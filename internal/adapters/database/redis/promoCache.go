@@ -0,0 +1,229 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+	"prod/internal/domain/dto"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	promoCacheTTL   = 10 * time.Minute
+	companyCacheTTL = time.Hour
+
+	// promoVersionTTL is how long a version of promo details outlives its last invalidation, far longer than any cache fill.
+	promoVersionTTL = 24 * time.Hour
+)
+
+// setPromoScript caches promo details only if they were not invalidated since the version was read.
+/*
+ * KEYS[1] - promo details, KEYS[2] - their version; ARGV[1] - details, ARGV[2] - the version read before the load, ARGV[3] - TTL in seconds.
+ */
+var setPromoScript = redis.NewScript(`
+if tonumber(redis.call('GET', KEYS[2]) or '0') ~= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[3])
+return 1
+`)
+
+// cachedPromo is promo details as they are cached: the fields active is computed from are hidden in the response.
+type cachedPromo struct {
+	dto.PromoForUser
+	InStock     bool      `json:"in_stock"`
+	Status      string    `json:"status"`
+	ActiveFrom  time.Time `json:"active_from"`
+	ActiveUntil time.Time `json:"active_until"`
+}
+
+// cacheCounter is a struct that counts hits and misses of one cache, shared by all storage instances.
+type cacheCounter struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func (c *cacheCounter) add(hits, misses int) {
+	c.hits.Add(int64(hits))
+	c.misses.Add(int64(misses))
+}
+
+func (c *cacheCounter) snapshot() dto.CacheCounter {
+	counter := dto.CacheCounter{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+	if total := counter.Hits + counter.Misses; total > 0 {
+		counter.HitRatio = float64(counter.Hits) / float64(total)
+	}
+	return counter
+}
+
+var (
+	promoCounter   cacheCounter
+	companyCounter cacheCounter
+)
+
+// CacheMetrics is a function that returns hit and miss counters of the promo caches of this instance.
+func CacheMetrics() dto.CacheMetrics {
+	return dto.CacheMetrics{
+		Promos:    promoCounter.snapshot(),
+		Companies: companyCounter.snapshot(),
+	}
+}
+
+// promoCacheStorage is a struct that caches promo details (without per-user flags) and company names.
+type promoCacheStorage struct {
+	db *redis.Client
+}
+
+func NewPromoCacheStorage(db *redis.Client) *promoCacheStorage {
+	return &promoCacheStorage{db: db}
+}
+
+func promoKey(promoID string) string {
+	return "promo:" + promoID
+}
+
+// promoVersionKey is a key of the counter of invalidations of promo details.
+func promoVersionKey(promoID string) string {
+	return "promo:" + promoID + ":version"
+}
+
+func companyNameKey(companyID string) string {
+	return "business:" + companyID + ":name"
+}
+
+// GetPromos is a method that returns cached promo details by id, missing ids are absent from the map.
+/*
+ * It also returns the versions of all ids, the missing ones are to be passed to SetPromos after they are loaded.
+ */
+func (s *promoCacheStorage) GetPromos(ctx context.Context, promoIDs []string) (map[string]dto.PromoForUser, map[string]int64, error) {
+	promos := make(map[string]dto.PromoForUser, len(promoIDs))
+	versions := make(map[string]int64, len(promoIDs))
+	if len(promoIDs) == 0 {
+		return promos, versions, nil
+	}
+
+	keys := make([]string, 0, 2*len(promoIDs))
+	for _, promoID := range promoIDs {
+		keys = append(keys, promoKey(promoID), promoVersionKey(promoID))
+	}
+
+	values, err := s.db.MGet(ctx, keys...).Result()
+	if err != nil {
+		promoCounter.add(0, len(promoIDs))
+		return promos, nil, err
+	}
+
+	for i, promoID := range promoIDs {
+		if raw, ok := values[2*i+1].(string); ok {
+			versions[promoID], _ = strconv.ParseInt(raw, 10, 64)
+		}
+
+		raw, ok := values[2*i].(string)
+		if !ok {
+			continue
+		}
+
+		// Записи без status остались от старого формата, где кэшировался сам active
+		var cached cachedPromo
+		if err = json.Unmarshal([]byte(raw), &cached); err != nil || cached.Status == "" {
+			continue
+		}
+
+		promo := cached.PromoForUser
+		promo.InStock, promo.Status, promo.ActiveFrom, promo.ActiveUntil = cached.InStock, cached.Status, cached.ActiveFrom, cached.ActiveUntil
+		promos[promoID] = promo
+	}
+
+	promoCounter.add(len(promos), len(promoIDs)-len(promos))
+	return promos, versions, nil
+}
+
+// SetPromos is a method that caches promo details, per-user flags must not be set.
+/*
+ * Details are written only if their version is still the one read by GetPromos before they were loaded:
+ * otherwise an invalidation landed in between and the loaded details may already be stale.
+ * Without versions (Redis was unavailable on read) nothing is cached.
+ */
+func (s *promoCacheStorage) SetPromos(ctx context.Context, promos []dto.PromoForUser, versions map[string]int64) error {
+	if versions == nil {
+		return nil
+	}
+
+	pipe := s.db.Pipeline()
+	for _, promo := range promos {
+		raw, err := json.Marshal(cachedPromo{
+			PromoForUser: promo,
+			InStock:      promo.InStock,
+			Status:       promo.Status,
+			ActiveFrom:   promo.ActiveFrom,
+			ActiveUntil:  promo.ActiveUntil,
+		})
+		if err != nil {
+			return err
+		}
+
+		keys := []string{promoKey(promo.PromoID), promoVersionKey(promo.PromoID)}
+		setPromoScript.Eval(ctx, pipe, keys, raw, versions[promo.PromoID], int(promoCacheTTL.Seconds()))
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// InvalidatePromo is a method that drops cached details of a promo after it or its counters changed.
+/*
+ * The version is bumped too, so details loaded before the change can't be cached after it.
+ */
+func (s *promoCacheStorage) InvalidatePromo(ctx context.Context, promoID string) error {
+	pipe := s.db.TxPipeline()
+	pipe.Incr(ctx, promoVersionKey(promoID))
+	pipe.Expire(ctx, promoVersionKey(promoID), promoVersionTTL)
+	pipe.Del(ctx, promoKey(promoID))
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetCompanyNames is a method that returns cached company names by id, missing ids are absent from the map.
+func (s *promoCacheStorage) GetCompanyNames(ctx context.Context, companyIDs []string) (map[string]string, error) {
+	names := make(map[string]string, len(companyIDs))
+	if len(companyIDs) == 0 {
+		return names, nil
+	}
+
+	keys := make([]string, 0, len(companyIDs))
+	for _, companyID := range companyIDs {
+		keys = append(keys, companyNameKey(companyID))
+	}
+
+	values, err := s.db.MGet(ctx, keys...).Result()
+	if err != nil {
+		companyCounter.add(0, len(companyIDs))
+		return names, err
+	}
+
+	for i, value := range values {
+		if name, ok := value.(string); ok {
+			names[companyIDs[i]] = name
+		}
+	}
+
+	companyCounter.add(len(names), len(companyIDs)-len(names))
+	return names, nil
+}
+
+// SetCompanyNames is a method that caches company names by id.
+func (s *promoCacheStorage) SetCompanyNames(ctx context.Context, names map[string]string) error {
+	pipe := s.db.Pipeline()
+	for companyID, name := range names {
+		pipe.Set(ctx, companyNameKey(companyID), name, companyCacheTTL)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/dto"
+)
+
+// promoUpdateStorage is a Redis pub/sub channel of promo updates shared by all instances.
+type promoUpdateStorage struct {
+	db      *redis.Client
+	channel string
+}
+
+func NewPromoUpdateStorage(db *redis.Client, channel string) *promoUpdateStorage {
+	return &promoUpdateStorage{db: db, channel: channel}
+}
+
+// Publish is a method that sends an update to every instance subscribed to the channel.
+func (s *promoUpdateStorage) Publish(ctx context.Context, update dto.PromoLiveUpdate) error {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Publish(ctx, s.channel, body).Err()
+}
+
+// Subscribe is a method that passes updates from the channel to handle until ctx is done.
+/*
+ * Pub/sub doesn't keep messages: updates sent while the connection is re-established are lost,
+ * the next update of the promo carries all its counters again.
+ */
+func (s *promoUpdateStorage) Subscribe(ctx context.Context, handle func(update dto.PromoLiveUpdate)) error {
+	pubSub := s.db.Subscribe(ctx, s.channel)
+	defer pubSub.Close()
+
+	messages := pubSub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return errors.New("promo updates subscription closed")
+			}
+
+			var update dto.PromoLiveUpdate
+			if err := json.Unmarshal([]byte(message.Payload), &update); err != nil {
+				logger.Log.Errorf("failed to decode a promo update: %v", err)
+				continue
+			}
+			handle(update)
+		}
+	}
+}
@@ -7,5 +7,7 @@ var (
 	Forbidden         = errors.New("forbidden")
 	NotFound          = errors.New("not found")
 	EmailTaken        = errors.New("email already taken")
+	Conflict          = errors.New("conflict")
 	BadRequest        = errors.New("ALEXANDR SHAKHOV YA VASH FANAT!!!1!")
+	CommentRejected   = errors.New("comment rejected")
 )
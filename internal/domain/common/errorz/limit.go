@@ -0,0 +1,29 @@
+package errorz
+
+import (
+	"errors"
+	"time"
+)
+
+var LimitReached = errors.New("limit reached")
+
+// LimitError is an error of an activation over a per-user or per-period limit or of a comment over the rate limit, errors.Is matches LimitReached.
+/*
+ * RetryAt is the start of the next period, nil when the limit never resets (once-ever per-user limits).
+ */
+type LimitError struct {
+	Scope   string // user or period of an activation, comment of a comment
+	RetryAt *time.Time
+}
+
+func (e *LimitError) Error() string {
+	if e.RetryAt == nil {
+		return LimitReached.Error() + ": " + e.Scope
+	}
+
+	return LimitReached.Error() + ": " + e.Scope + ", retry at " + e.RetryAt.Format(time.RFC3339)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == LimitReached
+}
@@ -1,25 +1,36 @@
 package dto
 
+import "time"
+
 type AddLike struct {
 	PromoID string `uri:"id" validate:"required"`
 }
 
 type AddComment struct {
-	PromoID string `uri:"id" validate:"required"`
-	Text    string `json:"text" validate:"required,min=10,max=1000"`
+	PromoID  string  `uri:"id" validate:"required"`
+	Text     string  `json:"text" validate:"required,min=10,max=1000"`
+	ParentID *string `json:"parent_id" validate:"omitempty,uuid"` // reply to the comment, its thread if it is a reply itself
 }
 
+// GetComments lists top-level comments newest first, or replies of the thread of ParentID oldest first.
 type GetComments struct {
-	ID     string `uri:"id" validate:"required"`
-	Limit  int    `query:"limit"`
-	Offset int    `query:"offset"`
+	ID        string `uri:"id" validate:"required"`
+	ParentID  string `query:"parent_id" validate:"omitempty,uuid"`
+	Limit     int    `query:"limit"`
+	Offset    int    `query:"offset"`
+	Cursor    string `query:"cursor"`
+	WithTotal bool   `query:"with_total"`
 }
 
 type Comment struct {
-	ID     string `json:"id"`
-	Text   string `json:"text"`
-	Date   string `json:"date"`
-	Author Author `json:"author"`
+	ID         string  `json:"id"`
+	Text       string  `json:"text"`
+	Date       string  `json:"date"`
+	Author     Author  `json:"author"`
+	ParentID   *string `json:"parent_id,omitempty"`
+	Official   bool    `json:"official"`    // a reply of the promo's company, Author.Name is the company name
+	ReplyCount int     `json:"reply_count"` // of a top-level comment
+	Status     string  `json:"status"`      // published, or pending for a comment of the author held for moderation
 }
 
 type Author struct {
@@ -28,6 +39,12 @@ type Author struct {
 	AvatarURL string `json:"avatar_url,omitempty"`
 }
 
+type OfficialReply struct {
+	ID        string `uri:"id" validate:"required,uuid"` // promo id
+	CommentID string `uri:"comment_id" validate:"required,uuid"`
+	Text      string `json:"text" validate:"required,min=1,max=1000"`
+}
+
 type GetCommentById struct {
 	ID        string `uri:"id" validate:"required"` // promo id
 	CommentID string `uri:"comment_id" validate:"required"`
@@ -51,3 +68,11 @@ type Activate struct {
 type ActivateResponse struct {
 	Promo string `json:"promo,omitempty"`
 }
+
+// ActivationLimitResponse is a response to an activation over a per-user or per-period limit of the promo.
+type ActivationLimitResponse struct {
+	Status  string     `json:"status"`
+	Message string     `json:"message,omitempty"`
+	Scope   string     `json:"scope" example:"user"` // user or period
+	RetryAt *time.Time `json:"retry_at,omitempty"`   // when the user can activate again, absent for once-ever limits
+}
@@ -0,0 +1,63 @@
+package dto
+
+import "time"
+
+type PromoAnalyticsRequest struct {
+	ID       string `uri:"id" validate:"required"`
+	From     string `query:"from"`                                                   // date or RFC 3339, 30 days before to by default
+	To       string `query:"to"`                                                     // date or RFC 3339, exclusive, now by default
+	Bucket   string `query:"bucket" validate:"omitempty,oneof=hour day week"`        // day by default
+	Timezone string `query:"tz" validate:"omitempty,max=64" example:"Europe/Moscow"` // buckets are aligned to this timezone, UTC by default
+}
+
+// PromoAnalyticsResponse is a time series of a promo's activations, likes, comments, impressions and views with activation breakdowns.
+/*
+ * An activation is "returning" if the user had activated any promo of the company before it, "new" otherwise.
+ * Likes are counted by when they were set and only while they stand.
+ * Impressions and views are counted once per user and day (UTC), by when they were written, a few seconds after they happened.
+ */
+type PromoAnalyticsResponse struct {
+	From      time.Time            `json:"from"`
+	To        time.Time            `json:"to"`
+	Bucket    string               `json:"bucket" example:"day"`
+	Timezone  string               `json:"timezone" example:"UTC"`
+	Totals    AnalyticsCounts      `json:"totals"`
+	Funnel    ViewFunnel           `json:"funnel"`
+	Series    []AnalyticsPoint     `json:"series"`
+	Countries []AnalyticsBreakdown `json:"countries"` // lowercase alpha-2
+	AgeBands  []AnalyticsBreakdown `json:"age_bands"` // <18, 18-24, 25-34, 35-44, 45-54, 55+
+}
+
+type AnalyticsCounts struct {
+	Activations    int `json:"activations"`
+	NewUsers       int `json:"new_users"`
+	ReturningUsers int `json:"returning_users"`
+	Likes          int `json:"likes"`
+	Comments       int `json:"comments"`
+	Impressions    int `json:"impressions"`
+	Views          int `json:"views"`
+}
+
+// ViewFunnel is a number of distinct users at each step from seeing a promo in the feed to activating it.
+/*
+ * activations are users who opened the promo and activated it on the day of their first view in the range or later.
+ */
+type ViewFunnel struct {
+	Impressions    int      `json:"impressions"`
+	Views          int      `json:"views"`
+	Activations    int      `json:"activations"`
+	ViewRate       *float64 `json:"view_rate"`       // views per impression, null without impressions
+	ActivationRate *float64 `json:"activation_rate"` // activations per view, null without views
+}
+
+type AnalyticsPoint struct {
+	Time time.Time `json:"time"` // start of the bucket
+	AnalyticsCounts
+}
+
+type AnalyticsBreakdown struct {
+	Key            string `json:"key"`
+	Activations    int    `json:"activations"`
+	NewUsers       int    `json:"new_users"`
+	ReturningUsers int    `json:"returning_users"`
+}
@@ -0,0 +1,54 @@
+package dto
+
+import "time"
+
+// Domain event types, written to the outbox in the transaction of the change.
+const (
+	EventPromoPublished = "PromoPublished" // a new promo went live, resuming a paused one is not
+	EventPromoActivated = "PromoActivated"
+	EventPromoExhausted = "PromoExhausted" // written by the activation that took the last code
+	EventCommentAdded   = "CommentAdded"
+	EventLikeToggled    = "LikeToggled" // only when the like actually changed
+)
+
+// DomainEvent is an event read from the outbox, Payload is the JSON of the payload of its Type.
+type DomainEvent struct {
+	Seq       int64     `json:"seq"`
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	PromoID   string    `json:"promo_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Payload   string    `json:"payload"`
+	Attempts  int       `json:"-"`
+}
+
+type PromoPublishedEvent struct {
+	CompanyID   string    `json:"company_id"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+type PromoActivatedEvent struct {
+	UserID      string    `json:"user_id"`
+	Country     string    `json:"country"` // lowercase alpha-2 of the user
+	ActivatedAt time.Time `json:"activated_at"`
+}
+
+type PromoExhaustedEvent struct {
+	ExhaustedAt time.Time `json:"exhausted_at"`
+}
+
+type CommentAddedEvent struct {
+	CommentID string    `json:"comment_id"`
+	ParentID  *string   `json:"parent_id,omitempty"`
+	UserID    *string   `json:"user_id,omitempty"`    // author, NULL for official replies
+	CompanyID *string   `json:"company_id,omitempty"` // author of official replies
+	Official  bool      `json:"official"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type LikeToggledEvent struct {
+	UserID    string    `json:"user_id"`
+	Liked     bool      `json:"liked"`
+	ToggledAt time.Time `json:"toggled_at"`
+}
@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"github.com/biter777/countries"
+	"time"
+)
+
+type PromoActivationsExportRequest struct {
+	ID     string `uri:"id" validate:"required"`
+	Format string `query:"format" validate:"omitempty,oneof=csv xlsx"` // csv by default
+	From   string `query:"from"`                                       // date or RFC 3339, all activations by default
+	To     string `query:"to"`                                         // date or RFC 3339, exclusive
+}
+
+type CompanyActivationsExportRequest struct {
+	Format string `query:"format" validate:"omitempty,oneof=csv xlsx"` // csv by default
+	From   string `query:"from"`                                       // date or RFC 3339, 30 days before to by default
+	To     string `query:"to"`                                         // date or RFC 3339, exclusive, now by default
+}
+
+// ActivationExportFilter is a set of activations of a company to export, PromoID is empty for all promos.
+type ActivationExportFilter struct {
+	CompanyID string
+	PromoID   string
+	From      *time.Time
+	To        *time.Time
+	Limit     int // the first activations only, 0 - all
+}
+
+// ActivationExportRow is an activation as it is read for an export, UserID is replaced with a pseudonym before writing.
+type ActivationExportRow struct {
+	ActivationID string
+	CreatedAt    time.Time
+	PromoID      string
+	UserID       string
+	Country      countries.CountryCode
+	AgeBand      string
+	Code         string
+}
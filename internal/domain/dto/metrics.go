@@ -0,0 +1,13 @@
+package dto
+
+// CacheCounter is a number of cache hits and misses since the start of the instance.
+type CacheCounter struct {
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+type CacheMetrics struct {
+	Promos    CacheCounter `json:"promos"`
+	Companies CacheCounter `json:"companies"`
+}
@@ -0,0 +1,45 @@
+package dto
+
+// Comment statuses
+const (
+	CommentPublished = "published"
+	CommentPending   = "pending" // waits in the moderation queue of the company
+	CommentHidden    = "hidden"  // hidden or rejected by the company
+)
+
+// Reasons a comment is pending or hidden
+const (
+	ModerationReasonLink     = "link"     // held by the filter
+	ModerationReasonReports  = "reports"  // reported by report-threshold users
+	ModerationReasonCompany  = "company"  // hidden by the company
+	ModerationReasonRejected = "rejected" // rejected in the moderation queue
+)
+
+type ReportComment struct {
+	ID        string `uri:"id" validate:"required,uuid"` // promo id
+	CommentID string `uri:"comment_id" validate:"required,uuid"`
+	Reason    string `json:"reason" validate:"required,oneof=spam abuse other" example:"spam"`
+}
+
+// ModerateComment is a request of the company to hide, approve or reject a comment under its promo.
+type ModerateComment struct {
+	ID        string `uri:"id" validate:"required,uuid"` // promo id
+	CommentID string `uri:"comment_id" validate:"required,uuid"`
+}
+
+// ModerationQueueRequest lists comments of the company's promos held for moderation, oldest first.
+type ModerationQueueRequest struct {
+	PromoID   string `query:"promo_id" validate:"omitempty,uuid"`
+	Status    string `query:"status" validate:"omitempty,oneof=pending hidden"` // pending by default
+	Limit     int    `query:"limit"`
+	Offset    int    `query:"offset"`
+	Cursor    string `query:"cursor"`
+	WithTotal bool   `query:"with_total"`
+}
+
+type ModerationComment struct {
+	Comment
+	PromoID          string `json:"promo_id"`
+	ModerationReason string `json:"moderation_reason"`
+	ReportCount      int    `json:"report_count"`
+}
@@ -0,0 +1,72 @@
+package dto
+
+import "time"
+
+const (
+	NotificationPromoExpiring = "promo_expiring" // a liked promo ends soon
+	NotificationCompanyPromo  = "company_promo"  // a favorite company published a promo
+	NotificationCommentReply  = "comment_reply"  // someone replied to the user's comment
+)
+
+// NotificationTypes is a list of notification types a user can turn off.
+var NotificationTypes = []string{NotificationPromoExpiring, NotificationCompanyPromo, NotificationCommentReply}
+
+type NotificationsRequest struct {
+	Unread    bool   `query:"unread"` // only unread notifications
+	Limit     int    `query:"limit"`
+	Offset    int    `query:"offset"`
+	Cursor    string `query:"cursor"`
+	WithTotal bool   `query:"with_total"`
+}
+
+type NotificationRead struct {
+	ID string `uri:"id" validate:"required,uuid"`
+}
+
+type NotificationDTO struct {
+	ID        string               `json:"id"`
+	Type      string               `json:"type" example:"promo_expiring"`
+	CreatedAt time.Time            `json:"created_at"`
+	Read      bool                 `json:"read"`
+	Promo     *NotificationPromo   `json:"promo,omitempty"`
+	Company   *NotificationCompany `json:"company,omitempty"`
+	CommentID *string              `json:"comment_id,omitempty"` // the reply, for comment_reply
+}
+
+type NotificationPromo struct {
+	ID          string    `json:"id"`
+	Description string    `json:"description"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	ActiveUntil time.Time `json:"active_until"`
+}
+
+type NotificationCompany struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type NotificationsReadAll struct {
+	Updated int64 `json:"updated"`
+}
+
+type NotificationUnreadCount struct {
+	Unread int64 `json:"unread"`
+}
+
+// NotificationPreferences is a flag per notification type.
+type NotificationPreferences struct {
+	PromoExpiring bool `json:"promo_expiring"`
+	CompanyPromo  bool `json:"company_promo"`
+	CommentReply  bool `json:"comment_reply"`
+}
+
+// NotificationPreferencesUpdate changes the given flags, omitted ones are kept.
+type NotificationPreferencesUpdate struct {
+	PromoExpiring *bool `json:"promo_expiring"`
+	CompanyPromo  *bool `json:"company_promo"`
+	CommentReply  *bool `json:"comment_reply"`
+}
+
+type FavoriteCompanyRequest struct {
+	ID string `uri:"id" validate:"required,uuid"`
+}
@@ -0,0 +1,27 @@
+package dto
+
+import "prod/internal/domain/utils/cursor"
+
+// Page is a set of pagination params.
+/*
+ * Keyset is true when the client asked for cursor pagination, Cursor is nil on its first page.
+ * Offset is ignored in keyset mode.
+ */
+type Page struct {
+	Limit     int
+	Offset    int
+	Keyset    bool
+	Cursor    *cursor.Cursor
+	WithTotal bool
+}
+
+// NeedTotal is a method that reports whether the total count has to be computed.
+func (p Page) NeedTotal() bool {
+	return !p.Keyset || p.WithTotal
+}
+
+// CursorPage is a response of cursor paginated endpoints.
+type CursorPage[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
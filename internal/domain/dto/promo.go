@@ -6,23 +6,81 @@ import (
 )
 
 type PromoCreate struct {
-	Target      *Target  `json:"target" validate:"required"`
-	ActiveFrom  string   `json:"active_from"`
-	ActiveUntil string   `json:"active_until"`
-	Description string   `json:"description" validate:"required,min=10,max=300"`
-	ImageURL    string   `json:"image_url" validate:"omitempty,url,max=350"`
-	MaxCount    int      `json:"max_count" validate:"omitempty,required,min=0,max=100000000"`
-	Mode        string   `json:"mode" validate:"required"`
-	PromoCommon string   `json:"promo_common" validate:"omitempty,min=5,max=30"`
-	PromoUnique []string `json:"promo_unique" validate:"omitempty,max=5000,dive,min=3,max=30"`
+	Target      *Target             `json:"target" validate:"required"`
+	ActiveFrom  string              `json:"active_from"`
+	ActiveUntil string              `json:"active_until"`
+	Description string              `json:"description" validate:"required,min=10,max=300"`
+	ImageURL    string              `json:"image_url" validate:"omitempty,url,max=350"`
+	MaxCount    int                 `json:"max_count" validate:"omitempty,required,min=0,max=100000000"`
+	Mode        string              `json:"mode" validate:"required"`
+	PromoCommon string              `json:"promo_common" validate:"omitempty,min=5,max=30"`
+	PromoUnique []string            `json:"promo_unique" validate:"omitempty,max=5000,dive,min=3,max=30"`
+	Generator   *PromoCodeGenerator `json:"promo_unique_generator,omitempty"` // alternative to PromoUnique, codes are generated on the server
+	Draft       bool                `json:"draft,omitempty"`                  // create unpublished, see the publish endpoint
+	Schedule    *Schedule           `json:"schedule,omitempty"`               // recurring availability windows within active_from and active_until
+	Limits      *PromoLimits        `json:"limits,omitempty"`                 // COMMON only
 	Active      bool
 }
 
+// PromoCodeGenerator is a spec of UNIQUE codes generated on promo creation: prefix, random part and optional check character.
+type PromoCodeGenerator struct {
+	Count    int    `json:"count" validate:"required,min=1,max=100000"`
+	Alphabet string `json:"alphabet,omitempty" validate:"omitempty,min=2,max=64" example:"ABCDEFGHJKMNPQRSTUVWXYZ23456789"`
+	Length   int    `json:"length" validate:"required,min=3,max=30"`
+	Prefix   string `json:"prefix,omitempty" validate:"omitempty,max=20" example:"SALE-"`
+	Checksum bool   `json:"checksum,omitempty"` // append a Luhn mod N check character
+}
+
+// PromoLimits is a set of activation limits of a COMMON promo on top of max_count.
+/*
+ * Periods are calendar days or weeks (from Monday) in the schedule's timezone, UTC without a schedule.
+ */
+type PromoLimits struct {
+	PerUser       int    `json:"per_user,omitempty" validate:"omitempty,min=0,max=1000000"`          // activations of one user per per_user_period, 0 - unlimited
+	PerUserPeriod string `json:"per_user_period,omitempty" validate:"omitempty,oneof=ever day week"` // ever by default
+	PerPeriod     int    `json:"per_period,omitempty" validate:"omitempty,min=0,max=100000000"`      // activations of all users per period, 0 - unlimited
+	Period        string `json:"period,omitempty" validate:"omitempty,oneof=day week"`               // day by default
+}
+
+// Normalized is a method that fills default periods of set limits and drops periods of unset ones.
+func (l PromoLimits) Normalized() PromoLimits {
+	if l.PerUser == 0 {
+		l.PerUserPeriod = ""
+	} else if l.PerUserPeriod == "" {
+		l.PerUserPeriod = "ever"
+	}
+
+	if l.PerPeriod == 0 {
+		l.Period = ""
+	} else if l.Period == "" {
+		l.Period = "day"
+	}
+
+	return l
+}
+
+// Schedule is a recurring availability of a promo: it can be activated only within one of the windows.
+type Schedule struct {
+	Timezone string           `json:"timezone,omitempty" validate:"omitempty,max=64" example:"Europe/Moscow"` // IANA name, UTC by default
+	Windows  []ScheduleWindow `json:"windows" validate:"max=50,dive"`                                         // empty windows remove the schedule
+}
+
+// ScheduleWindow is a time-of-day window on the given weekdays, "until" can be "24:00".
+type ScheduleWindow struct {
+	Weekdays []int  `json:"weekdays" validate:"required,min=1,max=7,dive,min=1,max=7"` // ISO weekdays, 1 - Monday, 7 - Sunday
+	From     string `json:"from" validate:"required,len=5" example:"17:00"`
+	Until    string `json:"until" validate:"required,len=5" example:"19:00"`
+}
+
 type Target struct {
 	AgeFrom    int      `json:"age_from" validate:"omitempty,min=0,max=100"`
 	AgeUntil   int      `json:"age_until,omitempty" validate:"omitempty,min=0,max=100"`
 	Country    string   `json:"country,omitempty" validate:"omitempty"`
 	Categories []string `json:"categories,omitempty" validate:"omitempty,max=20,dive,min=2,max=20"`
+
+	Countries          []string `json:"countries,omitempty" validate:"omitempty,max=250,dive,len=2"`                 // show only in these countries, can't be combined with Country
+	ExcludedCountries  []string `json:"excluded_countries,omitempty" validate:"omitempty,max=250,dive,len=2"`        // never show in these countries
+	AffinityCategories []string `json:"affinity_categories,omitempty" validate:"omitempty,max=20,dive,min=2,max=20"` // show only to users who liked or activated a promo in these categories
 }
 
 type PromoCreateResponse struct {
@@ -30,25 +88,28 @@ type PromoCreateResponse struct {
 }
 
 type PromoUpdate struct {
-	Target          *Target `json:"target,omitempty" validate:"omitempty,min=10,max=300"`
-	ActiveFrom      *string `json:"active_from,omitempty"`
-	ActiveUntil     *string `json:"active_until,omitempty"`
-	Description     *string `json:"description,omitempty"`
-	ImageURL        *string `json:"image_url,omitempty" validate:"omitempty,url,max=350"`
-	MaxCount        *int    `json:"max_count,omitempty" validate:"omitempty,min=0,max=100000000"`
-	CountryOriginal string  `json:"-"`
+	Target          *Target      `json:"target,omitempty" validate:"omitempty,min=10,max=300"`
+	ActiveFrom      *string      `json:"active_from,omitempty"`
+	ActiveUntil     *string      `json:"active_until,omitempty"`
+	Description     *string      `json:"description,omitempty"`
+	ImageURL        *string      `json:"image_url,omitempty" validate:"omitempty,url,max=350"`
+	MaxCount        *int         `json:"max_count,omitempty" validate:"omitempty,min=0,max=100000000"`
+	Schedule        *Schedule    `json:"schedule,omitempty"` // replaces the schedule, empty windows remove it
+	Limits          *PromoLimits `json:"limits,omitempty"`   // replaces the limits, zero values remove them
+	CountryOriginal string       `json:"-"`
 }
 
 type PromoGetWithPaginationRequest struct {
 	Limit     int      `query:"limit"`
 	Offset    int      `query:"offset"`
+	Cursor    string   `query:"cursor"`
+	WithTotal bool     `query:"with_total"`
 	SortBy    string   `query:"sort_by"`
 	Countries []string `query:"country"`
 }
 
 type PromoGetWithPagination struct {
-	Limit     int
-	Offset    int
+	Page      Page
 	SortBy    string
 	Countries []countries.CountryCode
 }
@@ -64,18 +125,21 @@ type PromoDTO struct {
 	CreatedAt   time.Time `json:"-"`
 	UpdatedAt   time.Time `json:"-"`
 
-	Target      Target   `json:"target"`
-	Active      bool     `json:"active"`
-	ActiveFrom  string   `json:"active_from,omitempty"`
-	ActiveUntil string   `json:"active_until,omitempty"`
-	Description string   `json:"description"`
-	ImageURL    string   `json:"image_url,omitempty"`
-	MaxCount    int      `json:"max_count"`
-	Mode        string   `json:"mode"`
-	LikeCount   int      `json:"like_count"`
-	UsedCount   int      `json:"used_count"`
-	PromoCommon string   `json:"promo_common,omitempty"`
-	PromoUnique []string `json:"promo_unique,omitempty"`
+	Target      Target       `json:"target"`
+	Active      bool         `json:"active"`
+	ActiveFrom  string       `json:"active_from,omitempty"`
+	ActiveUntil string       `json:"active_until,omitempty"`
+	Description string       `json:"description"`
+	ImageURL    string       `json:"image_url,omitempty"`
+	MaxCount    int          `json:"max_count"`
+	Mode        string       `json:"mode"`
+	LikeCount   int          `json:"like_count"`
+	UsedCount   int          `json:"used_count"`
+	PromoCommon string       `json:"promo_common,omitempty"`
+	Status      string       `json:"status" example:"live"` // draft, scheduled, live, paused or ended
+	Archived    bool         `json:"archived"`
+	Schedule    *Schedule    `json:"schedule,omitempty"`
+	Limits      *PromoLimits `json:"limits,omitempty"`
 }
 
 type PromoGetWithPaginationResponse struct {
@@ -83,30 +147,69 @@ type PromoGetWithPaginationResponse struct {
 }
 
 type PromoFeedRequest struct {
-	Limit    int     `query:"limit"`
-	Offset   int     `query:"offset"`
-	Category *string `query:"category"`
-	Active   string  `query:"active"`
+	Limit     int     `query:"limit"`
+	Offset    int     `query:"offset"`
+	Cursor    string  `query:"cursor"`
+	WithTotal bool    `query:"with_total"`
+	SortBy    string  `query:"sort_by" validate:"omitempty,oneof=relevance popular new"`
+	Category  *string `query:"category"`
+	Active    string  `query:"active"`
+}
+
+// FeedRanking is a set of weights used to score promos in the ranked feed.
+/*
+ * Likes, Comments and Velocity are applied to ln(1 + count), Recency, Affinity and Stock are in [0, 1].
+ * Velocity counts activations within VelocityWindow, Recency halves every RecencyHalfLife.
+ */
+type FeedRanking struct {
+	Likes           float64
+	Comments        float64
+	Velocity        float64
+	Recency         float64
+	Affinity        float64
+	Stock           float64
+	VelocityWindow  time.Duration
+	RecencyHalfLife time.Duration
 }
 
 // PromoForUser promoDTO for user's feed
 type PromoForUser struct {
-	PromoID           string `json:"promo_id"`
-	CompanyID         string `json:"company_id"`
-	CompanyName       string `json:"company_name"`
-	Description       string `json:"description"`
-	ImageURL          string `json:"image_url,omitempty"`
-	Active            bool   `json:"active"`
-	LikeCount         int    `json:"like_count"`
-	CommentCount      int    `json:"comment_count"`
-	IsLikedByUser     bool   `json:"is_liked_by_user"`
-	IsActivatedByUser bool   `json:"is_activated_by_user"`
-	UsedCount         int    `json:"used_count"`
+	PromoID           string   `json:"promo_id"`
+	CompanyID         string   `json:"company_id"`
+	CompanyName       string   `json:"company_name"`
+	Description       string   `json:"description"`
+	ImageURL          string   `json:"image_url,omitempty"`
+	Active            bool     `json:"active"`
+	Categories        []string `json:"categories,omitempty"`
+	LikeCount         int      `json:"like_count"`
+	CommentCount      int      `json:"comment_count"`
+	IsLikedByUser     bool     `json:"is_liked_by_user"`
+	IsActivatedByUser bool     `json:"is_activated_by_user"`
+	UsedCount         int      `json:"used_count"`
+
+	Schedule     *Schedule  `json:"schedule,omitempty"`
+	AvailableNow bool       `json:"available_now"`         // within the schedule right now, always true without a schedule
+	NextWindow   *time.Time `json:"next_window,omitempty"` // start of the next window when not available now
+
+	// Из них active вычисляется при каждом чтении, закэшированное значение устарело бы со временем
+	InStock     bool      `json:"-"` // codes or activations left
+	Status      string    `json:"-"` // stored status: draft, live or paused
+	ActiveFrom  time.Time `json:"-"`
+	ActiveUntil time.Time `json:"-"`
+}
+
+// PromoUserState is a promo reference with the user's own flags, overlaid on the cached promo details.
+type PromoUserState struct {
+	PromoID     string
+	IsLiked     bool
+	IsActivated bool
 }
 
 type PromoHistory struct {
-	Limit  int `query:"limit"`
-	Offset int `query:"offset"`
+	Limit     int    `query:"limit"`
+	Offset    int    `query:"offset"`
+	Cursor    string `query:"cursor"`
+	WithTotal bool   `query:"with_total"`
 }
 
 type PromoStats struct {
@@ -122,3 +225,37 @@ type ActivationsByCountry struct {
 	Country string `json:"country"`
 	Count   int    `json:"activations_count"`
 }
+
+type PromoSearchRequest struct {
+	Query      string   `query:"q" validate:"required,min=1,max=200"`
+	Categories []string `query:"category" validate:"omitempty,max=20,dive,min=2,max=20"`
+	CompanyIDs []string `query:"company_id" validate:"omitempty,max=20"`
+	Mode       string   `query:"mode" validate:"omitempty,oneof=COMMON UNIQUE"`
+	Active     string   `query:"active" validate:"omitempty,oneof=true false"`
+	Limit      int      `query:"limit" validate:"omitempty,min=0,max=100"`
+	Offset     int      `query:"offset" validate:"omitempty,min=0"`
+}
+
+// PromoSearchResult promoDTO for user's search with a highlighted description snippet
+type PromoSearchResult struct {
+	PromoForUser
+	Headline string  `json:"headline"`
+	Rank     float64 `json:"rank"`
+}
+
+type PromoSearchResponse struct {
+	Items  []PromoSearchResult `json:"items"`
+	Facets PromoSearchFacets   `json:"facets"`
+}
+
+type PromoSearchFacets struct {
+	Categories []Facet `json:"categories"`
+	Companies  []Facet `json:"companies"`
+	Modes      []Facet `json:"modes"`
+}
+
+type Facet struct {
+	Value string `json:"value"`
+	Name  string `json:"name,omitempty"`
+	Count int64  `json:"count"`
+}
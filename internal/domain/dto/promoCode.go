@@ -0,0 +1,97 @@
+package dto
+
+type PromoCodesAdd struct {
+	ID    string   `uri:"id" validate:"required,uuid"`
+	Codes []string `json:"codes" validate:"required,min=1,max=5000,dive,min=3,max=30"`
+}
+
+type PromoCodesAddResponse struct {
+	Added       int  `json:"added"`     // duplicates of existing codes are skipped
+	Available   int  `json:"available"` // codes left after adding
+	Reactivated bool `json:"reactivated"`
+}
+
+type PromoCodesList struct {
+	ID     string `uri:"id" validate:"required,uuid"`
+	Status string `query:"status" validate:"omitempty,oneof=available activated"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=1000"`
+	Offset int    `query:"offset" validate:"omitempty,min=0"`
+}
+
+// PromoCode is a unique code of a promo with its redemption status.
+type PromoCode struct {
+	ID          string          `json:"id"`
+	Code        string          `json:"code"`
+	Status      string          `json:"status" example:"available"` // available or activated
+	ActivatedBy *PromoCodeOwner `json:"activated_by,omitempty"`
+	ActivatedAt string          `json:"activated_at,omitempty"`
+}
+
+type PromoCodeOwner struct {
+	UserID  string `json:"user_id"`
+	Name    string `json:"name"`
+	Surname string `json:"surname"`
+}
+
+type PromoCodesRevoke struct {
+	ID    string   `uri:"id" validate:"required,uuid"`
+	Codes []string `json:"codes" validate:"omitempty,max=5000,dive,min=3,max=30"` // empty - revoke all unused codes
+}
+
+type PromoCodesRevokeResponse struct {
+	Revoked   int `json:"revoked"`
+	Available int `json:"available"`
+}
+
+type PromoCodesLowInventory struct {
+	Threshold int `query:"threshold" validate:"omitempty,min=1,max=5000"`
+}
+
+type PromoCodesLowInventoryResponse struct {
+	Threshold int              `json:"threshold"`
+	Count     int              `json:"count"`
+	Promos    []PromoInventory `json:"promos"`
+}
+
+// PromoInventory is a number of available and activated codes of a UNIQUE promo.
+type PromoInventory struct {
+	PromoID     string `json:"promo_id"`
+	Description string `json:"description"`
+	Active      bool   `json:"active"`
+	Available   int    `json:"available"`
+	Activated   int    `json:"activated"`
+}
+
+type PromoCodesImport struct {
+	ID   string `uri:"id" validate:"required,uuid"`
+	File string `json:"file" format:"binary"` // multipart form field, only described in the docs
+}
+
+type PromoCodesImportGet struct {
+	ID       string `uri:"id" validate:"required,uuid"`
+	ImportID string `uri:"import_id" validate:"required,uuid"`
+}
+
+// PromoCodesImportResponse is a state of a background import of codes from a CSV file.
+type PromoCodesImportResponse struct {
+	ImportID   string                `json:"import_id"`
+	PromoID    string                `json:"promo_id"`
+	Status     string                `json:"status" example:"running"` // pending, running, done or failed
+	TotalRows  int                   `json:"total_rows"`
+	Processed  int                   `json:"processed"`
+	Progress   float64               `json:"progress"` // share of processed rows, from 0 to 1
+	Added      int                   `json:"added"`
+	Duplicates int                   `json:"duplicates"` // repeated in the file or already uploaded
+	Invalid    int                   `json:"invalid"`
+	RowErrors  []PromoCodesImportRow `json:"row_errors"`
+	Error      string                `json:"error,omitempty"`
+	CreatedAt  string                `json:"created_at"`
+	FinishedAt string                `json:"finished_at,omitempty"`
+}
+
+// PromoCodesImportRow is a CSV row rejected by the import, Row starts from 1.
+type PromoCodesImportRow struct {
+	Row   int    `json:"row"`
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+// Causes of a promo update.
+const (
+	PromoLiveUpdateLike    = "like"
+	PromoLiveUpdateComment = "comment"
+	PromoLiveUpdateStock   = "stock"
+)
+
+type PromoLiveUpdatesRequest struct {
+	PromoIDs string `query:"promo_ids" validate:"required" example:"6c5b0d1e-2f0a-4b8e-9a57-1f1e9c6f3a10,0b8f9a52-7c1d-4e0f-a3b6-5d2e8f1c9a74"` // comma separated, up to 100
+}
+
+// PromoLiveUpdate is a snapshot of the live counters of a promo, pushed to its subscribers after a change.
+/*
+ * Every update carries all counters, so a client that missed some only needs the last one.
+ */
+type PromoLiveUpdate struct {
+	PromoID      string    `json:"promo_id"`
+	Type         string    `json:"type" example:"like"`
+	LikeCount    int       `json:"like_count"`
+	CommentCount int       `json:"comment_count"`
+	UsedCount    int       `json:"used_count"`
+	Remaining    int       `json:"remaining"` // uses of a COMMON promo or codes of a UNIQUE promo left
+	Active       bool      `json:"active"`
+	CommentID    *string   `json:"comment_id,omitempty"` // the added comment, for type comment
+	At           time.Time `json:"at"`
+}
@@ -0,0 +1,58 @@
+package dto
+
+import "time"
+
+type CompanyStatsRequest struct {
+	From string `query:"from"`                                  // date, 30 days before to by default
+	To   string `query:"to"`                                    // date, exclusive, tomorrow by default
+	Top  int    `query:"top" validate:"omitempty,min=1,max=50"` // promos in each top, 5 by default
+}
+
+// CompanyStatsResponse is a dashboard over all promos of the company in [from, to), read from daily rollups.
+/*
+ * Impressions and views are counted once per user and day. Rates are null without their denominator.
+ * Events after refreshed_until are not counted yet.
+ */
+type CompanyStatsResponse struct {
+	From             time.Time           `json:"from"`
+	To               time.Time           `json:"to"`
+	Activations      int64               `json:"activations"`
+	ActivationsTotal int64               `json:"activations_total"` // all time
+	Likes            int64               `json:"likes"`
+	Comments         int64               `json:"comments"`
+	Impressions      int64               `json:"impressions"`
+	Views            int64               `json:"views"`
+	ViewRate         *float64            `json:"view_rate"`  // views per impression
+	Conversion       *float64            `json:"conversion"` // activations per view
+	TopByActivations []CompanyPromoStats `json:"top_by_activations"`
+	TopByLikeRate    []CompanyPromoStats `json:"top_by_like_rate"`
+	Exhausted        []ExhaustedPromo    `json:"exhausted"` // not archived promos with no codes left, up to 100
+	UniqueInventory  UniqueInventory     `json:"unique_inventory"`
+	RefreshedUntil   time.Time           `json:"refreshed_until"`
+}
+
+type CompanyPromoStats struct {
+	PromoID        string   `json:"promo_id"`
+	Description    string   `json:"description"`
+	Activations    int64    `json:"activations"`
+	Likes          int64    `json:"likes"`
+	Impressions    int64    `json:"impressions"`
+	Views          int64    `json:"views"`
+	ViewRate       *float64 `json:"view_rate"`       // views per impression
+	ActivationRate *float64 `json:"activation_rate"` // activations per view
+	LikeRate       *float64 `json:"like_rate"`       // likes per view
+}
+
+type ExhaustedPromo struct {
+	PromoID     string `json:"promo_id"`
+	Description string `json:"description"`
+	Mode        string `json:"mode"`
+	UsedCount   int    `json:"used_count"`
+}
+
+// UniqueInventory is a sum of codes over not deleted UNIQUE promos of the company.
+type UniqueInventory struct {
+	Promos    int64 `json:"promos"`
+	Total     int64 `json:"total"`
+	Available int64 `json:"available"`
+}
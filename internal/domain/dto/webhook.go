@@ -0,0 +1,115 @@
+package dto
+
+import "time"
+
+const (
+	WebhookEventActivated = "promo.activated"
+	WebhookEventExhausted = "promo.exhausted"
+	WebhookEventCommented = "promo.commented"
+	WebhookEventLiked     = "promo.liked"
+)
+
+// WebhookEvents is a list of event types a webhook can subscribe to.
+var WebhookEvents = []string{WebhookEventActivated, WebhookEventExhausted, WebhookEventCommented, WebhookEventLiked}
+
+type WebhookCreate struct {
+	URL    string   `json:"url" validate:"required,url,max=2048" example:"https://example.com/hooks/promo"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=promo.activated promo.exhausted promo.commented promo.liked"`
+}
+
+type WebhookUpdate struct {
+	ID     string   `json:"-" uri:"id" validate:"required,uuid"`
+	URL    *string  `json:"url" validate:"omitempty,url,max=2048"`
+	Events []string `json:"events" validate:"omitempty,min=1,dive,oneof=promo.activated promo.exhausted promo.commented promo.liked"`
+	Active *bool    `json:"active"`
+}
+
+type WebhookGetByID struct {
+	ID string `uri:"id" validate:"required,uuid"`
+}
+
+type WebhookDTO struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"` // only returned on creation and rotation
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDeliveriesRequest struct {
+	ID        string `uri:"id" validate:"required,uuid"`
+	Status    string `query:"status" validate:"omitempty,oneof=pending delivered dead"` // dead is the dead-letter list
+	Limit     int    `query:"limit"`
+	Offset    int    `query:"offset"`
+	Cursor    string `query:"cursor"`
+	WithTotal bool   `query:"with_total"`
+}
+
+type WebhookDeliveryRedeliver struct {
+	ID         string `uri:"id" validate:"required,uuid"`
+	DeliveryID string `uri:"delivery_id" validate:"required,uuid"`
+}
+
+type WebhookDeliveryDTO struct {
+	ID            string         `json:"id"`
+	EventID       string         `json:"event_id"`
+	Event         string         `json:"event" example:"promo.activated"`
+	Status        string         `json:"status" example:"pending"`
+	Attempts      int            `json:"attempts"`
+	Payload       WebhookPayload `json:"payload"`
+	CreatedAt     time.Time      `json:"created_at"`
+	NextAttemptAt *time.Time     `json:"next_attempt_at,omitempty"` // only while pending
+	LastAttemptAt *time.Time     `json:"last_attempt_at,omitempty"`
+	LastStatus    *int           `json:"last_status,omitempty"`
+	LastError     *string        `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time     `json:"delivered_at,omitempty"`
+}
+
+// WebhookPayload is the body posted to a webhook.
+/*
+ * It is signed with the secret of the webhook: X-Webhook-Signature is "sha256=" and the hex HMAC-SHA256
+ * of X-Webhook-Timestamp, a dot and the raw body. id is the same for all attempts and webhooks of an event.
+ */
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type" example:"promo.activated"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type WebhookActivatedData struct {
+	PromoID     string    `json:"promo_id"`
+	ActivatedAt time.Time `json:"activated_at"`
+	Country     string    `json:"country"` // lowercase alpha-2 of the user
+}
+
+// WebhookExhaustedData is sent once, by the activation that took the last code.
+type WebhookExhaustedData struct {
+	PromoID     string    `json:"promo_id"`
+	ExhaustedAt time.Time `json:"exhausted_at"`
+}
+
+type WebhookCommentedData struct {
+	PromoID   string    `json:"promo_id"`
+	CommentID string    `json:"comment_id"`
+	ParentID  *string   `json:"parent_id,omitempty"` // the thread of a reply
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookLikedData struct {
+	PromoID string    `json:"promo_id"`
+	LikedAt time.Time `json:"liked_at"`
+}
+
+// WebhookDispatch is a claimed delivery with the endpoint it goes to.
+type WebhookDispatch struct {
+	DeliveryID string
+	EventID    string
+	Event      string
+	Payload    string
+	Attempts   int
+	URL        string
+	Secret     string
+}
@@ -7,13 +7,39 @@ type Likes struct {
 	PromoID string `json:"promo_id" gorm:"not null;"`
 	UserID  string `json:"user_id" gorm:"not null;"`
 
-	Like bool `json:"like" gorm:"default:false"`
+	Like    bool       `json:"like" gorm:"default:false"`
+	LikedAt *time.Time `json:"-"` // when the like was last set, NULL for likes made before it was tracked
 }
 
+// Comment is a comment of a user or an official reply of the promo's company.
+/*
+ * Threads are one level deep: ParentID is the top-level comment of the thread, replies to a reply join the same thread.
+ * ReplyCount of a top-level comment is kept in the transaction that adds or deletes a reply.
+ * Only published comments are shown and counted, ReplyCount and the comment count of the promo included.
+ */
 type Comment struct {
-	CommentID string    `json:"comment_id" gorm:"primaryKey;not null;type:uuid;default:gen_random_uuid()"`
-	CreatedAt time.Time `json:"date" gorm:"not null;"`
-	PromoID   string    `json:"promo_id" gorm:"not null;"`
-	UserID    string    `json:"user_id" gorm:"not null;"`
-	Text      string    `json:"text" gorm:"not null;"`
+	CommentID  string    `json:"comment_id" gorm:"primaryKey;not null;type:uuid;default:gen_random_uuid()"`
+	CreatedAt  time.Time `json:"date" gorm:"not null;"`
+	PromoID    string    `json:"promo_id" gorm:"not null;"`
+	UserID     *string   `json:"user_id"`                     // NULL for official replies
+	CompanyID  *string   `json:"company_id" gorm:"type:uuid"` // the author of an official reply
+	Text       string    `json:"text" gorm:"not null;"`
+	ParentID   *string   `json:"parent_id" gorm:"type:uuid"`
+	Official   bool      `json:"official" gorm:"not null;default:false"`
+	ReplyCount int       `json:"reply_count" gorm:"not null;default:0"`
+
+	Status           string     `json:"status" gorm:"type:varchar(16);not null;default:'published'"` // published, pending or hidden
+	ModerationReason string     `json:"moderation_reason" gorm:"not null;default:''"`                // why it is pending or hidden
+	ReportCount      int        `json:"report_count" gorm:"not null;default:0"`
+	ModeratedAt      *time.Time `json:"moderated_at"`
+	PublishedAt      *time.Time `json:"-"` // first publication, the dashboard rolls comments up by it
+}
+
+// CommentReport is a report of a user on a comment, a user reports a comment once.
+type CommentReport struct {
+	ReportID  string    `json:"report_id" gorm:"primaryKey;not null;type:uuid;default:gen_random_uuid()"`
+	CommentID string    `json:"comment_id" gorm:"not null;type:uuid;uniqueIndex:idx_comment_reports_comment_user"`
+	UserID    string    `json:"user_id" gorm:"not null;type:uuid;uniqueIndex:idx_comment_reports_comment_user"`
+	Reason    string    `json:"reason" gorm:"type:varchar(16);not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
 }
@@ -7,4 +7,5 @@ type Activation struct {
 	UserID       string `json:"user_id" gorm:"not null;"`
 	PromoID      string `json:"promo_id" gorm:"not null;"`
 	CreatedAt    time.Time
+	Code         *string `json:"-"` // the issued code, NULL for activations made before it was stored
 }
@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// CodeImport is a background import of unique codes from a CSV file into a UNIQUE promo.
+type CodeImport struct {
+	ImportID  string `json:"import_id" gorm:"primaryKey;not null;type:uuid;default:gen_random_uuid()"`
+	PromoID   string `json:"promo_id" gorm:"not null;type:uuid;index"`
+	CompanyID string `json:"-" gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Status     string     `json:"status" gorm:"not null;default:pending"` // pending, running, done or failed
+	TotalRows  int        `json:"total_rows"`
+	Processed  int        `json:"processed"`
+	Added      int        `json:"added"`
+	Duplicates int        `json:"duplicates"`
+	Invalid    int        `json:"invalid"`
+	RowErrors  string     `json:"-" gorm:"type:jsonb;default:'[]'"` // first rows that failed validation
+	Error      string     `json:"-"`
+	FinishedAt *time.Time `json:"-"`
+}
@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+// Notification is an in-app notification of a user.
+/*
+ * Key identifies what the notification is about within its type (a promo, a reply), so the same trigger
+ * handled twice doesn't notify the user twice.
+ */
+type Notification struct {
+	NotificationID string    `gorm:"primaryKey;not null;type:uuid;default:gen_random_uuid()"`
+	UserID         string    `gorm:"not null;type:uuid;index:idx_notifications_user_created_at,priority:1;uniqueIndex:idx_notifications_user_type_key,priority:1"`
+	CreatedAt      time.Time `gorm:"not null;default:now();index:idx_notifications_user_created_at,priority:2"`
+	Type           string    `gorm:"not null;uniqueIndex:idx_notifications_user_type_key,priority:2"`
+	Key            string    `gorm:"not null;uniqueIndex:idx_notifications_user_type_key,priority:3"`
+
+	PromoID   *string `gorm:"type:uuid"`
+	CompanyID *string `gorm:"type:uuid"`
+	CommentID *string `gorm:"type:uuid"`
+	ReadAt    *time.Time
+}
+
+// NotificationPreference is a user's choice of whether to get notifications of a type, types without one are enabled.
+type NotificationPreference struct {
+	UserID  string `gorm:"primaryKey;not null;type:uuid"`
+	Type    string `gorm:"primaryKey;not null"`
+	Enabled bool   `gorm:"not null"`
+}
+
+// FavoriteCompany is a company whose new promos the user is notified of.
+type FavoriteCompany struct {
+	UserID    string    `gorm:"primaryKey;not null;type:uuid"`
+	CompanyID string    `gorm:"primaryKey;not null;type:uuid;index"`
+	CreatedAt time.Time `gorm:"not null;default:now()"`
+}
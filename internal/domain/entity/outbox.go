@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// OutboxEvent is a domain event written in the transaction of the change it describes.
+/*
+ * The event bus publishes events in Seq order and sets PublishedAt, a failed event is retried at NextAttemptAt
+ * and is given up (published with LastError) after a number of attempts.
+ */
+type OutboxEvent struct {
+	Seq     int64  `gorm:"primaryKey;autoIncrement"`
+	EventID string `gorm:"not null;type:uuid;uniqueIndex;default:gen_random_uuid()"`
+	Type    string `gorm:"not null"`
+	PromoID string `gorm:"not null;type:uuid"`
+	Payload string `gorm:"not null;type:jsonb"`
+
+	CreatedAt     time.Time `gorm:"not null;default:now()"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null;default:now()"`
+	LastError     *string
+	PublishedAt   *time.Time
+}
@@ -29,9 +29,52 @@ type Promo struct {
 	Country         countries.CountryCode `json:"country"`
 	CountryOriginal string                `json:"-" gorm:"country_original"`
 	Categories      []Category            `json:"categories" gorm:"foreignKey:PromoID"`
+	Countries       []PromoCountry        `json:"-" gorm:"foreignKey:PromoID"` // included and excluded countries, see PromoCountry
+	Affinities      []PromoAffinity       `json:"-" gorm:"foreignKey:PromoID"`
 	Actions         []Likes               `json:"-" gorm:"foreignKey:PromoID"`
 	Comments        []Comment             `json:"-" gorm:"foreignKey:PromoID"`
 	Activations     []Activation          `json:"-" gorm:"foreignKey:PromoID"`
+
+	Status     string     `json:"-" gorm:"not null;default:live"` // stored status: draft, live or paused, see EffectiveStatus
+	ArchivedAt *time.Time `json:"-"`
+	DeletedAt  *time.Time `json:"-" gorm:"index"`      // soft delete, activations are kept for stats
+	Schedule   *string    `json:"-" gorm:"type:jsonb"` // recurring availability windows as dto.Schedule, NULL - around the clock
+
+	// Лимиты активаций COMMON-промо поверх max_count, 0 - без лимита
+	UserLimit       int    `json:"-" gorm:"not null;default:0"` // activations of one user per UserLimitPeriod
+	UserLimitPeriod string `json:"-"`                           // ever, day or week
+	PeriodLimit     int    `json:"-" gorm:"not null;default:0"` // activations of all users per LimitPeriod
+	LimitPeriod     string `json:"-"`                           // day or week
+
+	HasCodes bool `json:"-" gorm:"-"` // UNIQUE promo has codes that are not activated yet, set by GetByID
+}
+
+// Promo statuses. Only draft, live and paused are stored, scheduled and ended follow from the dates of a live promo.
+const (
+	PromoStatusDraft     = "draft"
+	PromoStatusScheduled = "scheduled"
+	PromoStatusLive      = "live"
+	PromoStatusPaused    = "paused"
+	PromoStatusEnded     = "ended"
+)
+
+// EffectiveStatus is a method that returns the status of the promo at the moment now.
+func (p *Promo) EffectiveStatus(now time.Time) string {
+	switch {
+	case p.Status == PromoStatusDraft || p.Status == PromoStatusPaused:
+		return p.Status
+	case !now.Before(p.ActiveUntil):
+		return PromoStatusEnded
+	case now.Before(p.ActiveFrom):
+		return PromoStatusScheduled
+	}
+
+	return PromoStatusLive
+}
+
+// IsActive is a method that reports whether the promo can be activated at the moment now: it's live and has codes left.
+func (p *Promo) IsActive(now time.Time) bool {
+	return p.Active && p.ArchivedAt == nil && p.DeletedAt == nil && p.EffectiveStatus(now) == PromoStatusLive
 }
 
 type PromoUnique struct {
@@ -40,6 +83,9 @@ type PromoUnique struct {
 	Body          string `json:"-" gorm:"not null"`
 	Activated     bool   `json:"-" gorm:"default:false"`
 	Index         int    `json:"-"`
+
+	ActivatedBy *string    `json:"-" gorm:"type:uuid"` // id of the user who redeemed the code
+	ActivatedAt *time.Time `json:"-"`
 }
 
 type Category struct {
@@ -48,3 +94,18 @@ type Category struct {
 	Name       string `json:"name" gorm:"not null"`
 	Index      int    `json:"-"`
 }
+
+// PromoCountry is a country in the promo's targeting: the promo is shown only in included countries and never in excluded ones.
+type PromoCountry struct {
+	PromoCountryID string                `json:"-" gorm:"primaryKey;not null;type:uuid;default:gen_random_uuid()"`
+	PromoID        string                `json:"-" gorm:"not null;index"`
+	Country        countries.CountryCode `json:"-" gorm:"not null"`
+	Excluded       bool                  `json:"-" gorm:"not null;default:false"`
+}
+
+// PromoAffinity is a category-affinity target: the promo is shown to users who liked or activated a promo in this category.
+type PromoAffinity struct {
+	PromoAffinityID string `json:"-" gorm:"primaryKey;not null;type:uuid;default:gen_random_uuid()"`
+	PromoID         string `json:"-" gorm:"not null;index"`
+	Name            string `json:"-" gorm:"not null"` // lowercased category name
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// PromoDailyStats is a rollup of a promo's events per day (UTC) for the company dashboard.
+/*
+ * Rows are only incremented by the rollup refresh, see StatsRollup.
+ */
+type PromoDailyStats struct {
+	PromoID     string    `gorm:"primaryKey;type:uuid"`
+	Day         time.Time `gorm:"primaryKey;type:date;index:idx_promo_daily_stats_company_day,priority:2"`
+	CompanyID   string    `gorm:"not null;type:uuid;index:idx_promo_daily_stats_company_day,priority:1"`
+	Activations int64     `gorm:"not null;default:0"`
+	Likes       int64     `gorm:"not null;default:0"` // likes set that day, taking a like back doesn't decrement it
+	Comments    int64     `gorm:"not null;default:0"`
+	Impressions int64     `gorm:"not null;default:0"` // users who saw the promo in the feed that day
+	Views       int64     `gorm:"not null;default:0"` // users who opened the promo that day
+}
+
+// StatsRollup is a watermark of a source of events: events up to RefreshedUntil are already counted in PromoDailyStats.
+type StatsRollup struct {
+	Source         string    `gorm:"primaryKey"`
+	RefreshedUntil time.Time `gorm:"not null"`
+}
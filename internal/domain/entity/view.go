@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+const (
+	ViewKindImpression = "impression" // the promo was shown in the feed
+	ViewKindDetail     = "view"       // the promo page was opened
+)
+
+// PromoView is a user seeing a promo, recorded once per user, promo, kind and day (UTC).
+/*
+ * CreatedAt is when the row was written, the rollups of the dashboard are refreshed by it.
+ */
+type PromoView struct {
+	PromoID   string    `gorm:"primaryKey;type:uuid"`
+	UserID    string    `gorm:"primaryKey;type:uuid"`
+	Day       time.Time `gorm:"primaryKey;type:date"`
+	Kind      string    `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"not null;default:now();index"`
+}
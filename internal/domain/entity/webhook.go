@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+// Webhook is an endpoint of a company that receives business events.
+type Webhook struct {
+	WebhookID string `gorm:"primaryKey;not null;type:uuid;default:gen_random_uuid()"`
+	CompanyID string `gorm:"not null;type:uuid;index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	URL    string `gorm:"not null"`
+	Secret string `gorm:"not null"`              // key of the HMAC signature of payloads
+	Events string `gorm:"not null"`              // comma separated event types
+	Active bool   `gorm:"not null;default:true"` // inactive webhooks get no new deliveries
+}
+
+// WebhookDelivery is an event queued for or delivered to a webhook, it is also the delivery log.
+/*
+ * A delivery is pending until it succeeds (delivered) or runs out of attempts (dead, the dead-letter list).
+ * Between attempts NextAttemptAt grows exponentially, a delivery being sent has it moved by a lease.
+ */
+type WebhookDelivery struct {
+	DeliveryID string    `gorm:"primaryKey;not null;type:uuid;default:gen_random_uuid()"`
+	WebhookID  string    `gorm:"not null;type:uuid;index:idx_webhook_deliveries_webhook_created_at,priority:1;uniqueIndex:idx_webhook_deliveries_webhook_event,priority:1"`
+	CreatedAt  time.Time `gorm:"index:idx_webhook_deliveries_webhook_created_at,priority:2"`
+
+	EventID       string    `gorm:"not null;type:uuid;uniqueIndex:idx_webhook_deliveries_webhook_event,priority:2"`
+	Event         string    `gorm:"not null"`
+	Payload       string    `gorm:"not null;type:jsonb"`
+	Status        string    `gorm:"not null;default:pending;index:idx_webhook_deliveries_due,priority:1"` // pending, delivered or dead
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null;index:idx_webhook_deliveries_due,priority:2"`
+	LastAttemptAt *time.Time
+	LastStatus    *int // HTTP status of the last attempt, NULL if there was no response
+	LastError     *string
+	DeliveredAt   *time.Time
+}
@@ -16,11 +16,16 @@ import (
 type actionsStorage interface {
 	AddLike(ctx context.Context, userID, promoID string) error
 	DeleteLike(ctx context.Context, userID, promoID string) error
-	AddComment(ctx context.Context, userID, promoID, text string) (string, error)
-	GetComments(ctx context.Context, promoID string, limit, offset int) ([]dto.Comment, int64, error)
+	AddComment(ctx context.Context, userID, promoID string, parentID *string, text, moderationReason string, rateLimit int64, rateWindow time.Duration) (string, error)
+	AddOfficialReply(ctx context.Context, companyID, promoID, parentID, text string) (string, error)
+	GetComments(ctx context.Context, promoID, parentID string, page dto.Page) ([]dto.Comment, string, int64, error)
 	GetCommentById(ctx context.Context, promoID, commentID string) (dto.Comment, error)
-	UpdateComment(ctx context.Context, promoID, commentID, userID, text string) (dto.Comment, error)
+	UpdateComment(ctx context.Context, promoID, commentID, userID, text, moderationReason string) (dto.Comment, error)
 	DeleteComment(ctx context.Context, promoID, commentID, userID string) error
+	GetPromoCompanyID(ctx context.Context, promoID string) (string, error)
+	SetCommentStatus(ctx context.Context, promoID, commentID string, from []string, status, reason string) error
+	ReportComment(ctx context.Context, userID, promoID, commentID, reason string, threshold int) error
+	GetModerationQueue(ctx context.Context, companyID, promoID, status string, page dto.Page) ([]dto.ModerationComment, string, int64, error)
 }
 
 type activationStorage interface {
@@ -36,45 +41,150 @@ type actionsService struct {
 	actionStorage          actionsStorage
 	activationStorage      activationStorage
 	activationRedisStorage activationRedisStorage
+	promoCacheStorage      promoCacheStorage
 }
 
-func NewActionsService(actionStorage actionsStorage, activationStorage activationStorage, activationRedisStorage activationRedisStorage) *actionsService {
+func NewActionsService(actionStorage actionsStorage, activationStorage activationStorage, activationRedisStorage activationRedisStorage, promoCacheStorage promoCacheStorage) *actionsService {
 	return &actionsService{
 		actionStorage:          actionStorage,
 		activationStorage:      activationStorage,
 		activationRedisStorage: activationRedisStorage,
+		promoCacheStorage:      promoCacheStorage,
 	}
 }
 
 func (s *actionsService) AddLike(ctx context.Context, userID, promoID string) error {
-	return s.actionStorage.AddLike(ctx, userID, promoID)
+	if err := s.actionStorage.AddLike(ctx, userID, promoID); err != nil {
+		return err
+	}
+
+	invalidatePromo(ctx, s.promoCacheStorage, promoID)
+	return nil
 }
 
 func (s *actionsService) DeleteLike(ctx context.Context, userID, promoID string) error {
-	return s.actionStorage.DeleteLike(ctx, userID, promoID)
+	if err := s.actionStorage.DeleteLike(ctx, userID, promoID); err != nil {
+		return err
+	}
+
+	invalidatePromo(ctx, s.promoCacheStorage, promoID)
+	return nil
+}
+
+// AddComment is a method that adds a comment of the user after the moderation filter, it is pending if the filter holds it.
+/*
+ * Returns errorz.LimitError if the user wrote rate-limit comments within rate-window, the storage counts them atomically.
+ */
+func (s *actionsService) AddComment(ctx context.Context, userID, promoID string, parentID *string, text string) (dto.Comment, error) {
+	moderation := commentModeration()
+
+	reason, err := moderation.check(text)
+	if err != nil {
+		return dto.Comment{}, err
+	}
+
+	commentID, err := s.actionStorage.AddComment(ctx, userID, promoID, parentID, text, reason, moderation.rateLimit, moderation.rateWindow)
+	if err != nil {
+		return dto.Comment{}, err
+	}
+
+	invalidatePromo(ctx, s.promoCacheStorage, promoID)
+	return s.actionStorage.GetCommentById(ctx, promoID, commentID)
+}
+
+// AddOfficialReply is a method that adds a reply of the company to a comment under its own promo.
+func (s *actionsService) AddOfficialReply(ctx context.Context, companyID string, request dto.OfficialReply) (dto.Comment, error) {
+	if err := s.checkOwner(ctx, companyID, request.ID); err != nil {
+		return dto.Comment{}, err
+	}
+
+	commentID, err := s.actionStorage.AddOfficialReply(ctx, companyID, request.ID, request.CommentID, request.Text)
+	if err != nil {
+		return dto.Comment{}, err
+	}
+
+	invalidatePromo(ctx, s.promoCacheStorage, request.ID)
+	return s.actionStorage.GetCommentById(ctx, request.ID, commentID)
+}
+
+func (s *actionsService) GetComments(ctx context.Context, promoID, parentID string, page dto.Page) ([]dto.Comment, string, int64, error) {
+	return s.actionStorage.GetComments(ctx, promoID, parentID, page)
 }
 
-func (s *actionsService) AddComment(ctx context.Context, userID, promoID, text string) (string, error) {
-	return s.actionStorage.AddComment(ctx, userID, promoID, text)
+// GetCompanyComments is a method that returns comments of a promo of the company, like GetComments.
+func (s *actionsService) GetCompanyComments(ctx context.Context, companyID, promoID, parentID string, page dto.Page) ([]dto.Comment, string, int64, error) {
+	if err := s.checkOwner(ctx, companyID, promoID); err != nil {
+		return nil, "", 0, err
+	}
+
+	return s.actionStorage.GetComments(ctx, promoID, parentID, page)
 }
 
-func (s *actionsService) GetComments(ctx context.Context, promoID string, limit, offset int) ([]dto.Comment, int64, error) {
-	return s.actionStorage.GetComments(ctx, promoID, limit, offset)
+// checkOwner is a method that returns errorz.NotFound if there is no such promo and errorz.Forbidden if it is not the company's.
+func (s *actionsService) checkOwner(ctx context.Context, companyID, promoID string) error {
+	ownerID, err := s.actionStorage.GetPromoCompanyID(ctx, promoID)
+	if err != nil {
+		return err
+	}
+	if ownerID != companyID {
+		return errorz.Forbidden
+	}
+
+	return nil
 }
 
+// GetCommentById is a method that returns a published comment.
 func (s *actionsService) GetCommentById(ctx context.Context, commentID, promoID string) (dto.Comment, error) {
-	return s.actionStorage.GetCommentById(ctx, promoID, commentID)
+	comment, err := s.actionStorage.GetCommentById(ctx, promoID, commentID)
+	if err != nil {
+		return dto.Comment{}, err
+	}
+	if comment.Status != dto.CommentPublished {
+		return dto.Comment{}, errorz.NotFound
+	}
+
+	return comment, nil
 }
 
+// UpdateComment is a method that changes the text of the user's comment after the moderation filter, like AddComment.
 func (s *actionsService) UpdateComment(ctx context.Context, promoID, commentID, userID, text string) (dto.Comment, error) {
-	return s.actionStorage.UpdateComment(ctx, promoID, commentID, userID, text)
+	reason, err := commentModeration().check(text)
+	if err != nil {
+		return dto.Comment{}, err
+	}
+
+	comment, err := s.actionStorage.UpdateComment(ctx, promoID, commentID, userID, text, reason)
+	if err != nil {
+		return dto.Comment{}, err
+	}
+
+	if reason != "" {
+		invalidatePromo(ctx, s.promoCacheStorage, promoID)
+	}
+	return comment, nil
 }
 
 func (s *actionsService) DeleteComment(ctx context.Context, promoID, commentID, userID string) error {
-	return s.actionStorage.DeleteComment(ctx, promoID, commentID, userID)
+	if err := s.actionStorage.DeleteComment(ctx, promoID, commentID, userID); err != nil {
+		return err
+	}
+
+	invalidatePromo(ctx, s.promoCacheStorage, promoID)
+	return nil
 }
 
+// Activate is a method that activates a promo for the user and drops its cached details (used count, active flag).
 func (s *actionsService) Activate(ctx context.Context, user *entity.User, promoID string) (string, error) {
+	code, err := s.activate(ctx, user, promoID)
+	if err != nil {
+		return "", err
+	}
+
+	invalidatePromo(ctx, s.promoCacheStorage, promoID)
+	return code, nil
+}
+
+func (s *actionsService) activate(ctx context.Context, user *entity.User, promoID string) (string, error) {
 	antiFraudAddress := os.Getenv("ANTIFRAUD_ADDRESS")
 	checkCache, cacheErr := s.activationRedisStorage.CheckCache(ctx, user.Email)
 	if cacheErr != nil {
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/utils/schedule"
+	"time"
+)
+
+// maxAnalyticsBuckets is a limit of buckets in one analytics response, e.g. 41 days by hour or 19 years by week.
+const maxAnalyticsBuckets = 1000
+
+var ErrInvalidAnalyticsRange = errors.New("invalid analytics range")
+
+// GetAnalytics is a method that returns time-series analytics of a promo of the company, soft deleted promos included.
+func (s *promoService) GetAnalytics(ctx context.Context, companyID string, request dto.PromoAnalyticsRequest) (dto.PromoAnalyticsResponse, error) {
+	promoCompanyID, err := s.promoStorage.GetCompanyID(ctx, request.ID)
+	if err != nil {
+		return dto.PromoAnalyticsResponse{}, err
+	}
+	if promoCompanyID != companyID {
+		return dto.PromoAnalyticsResponse{}, errorz.Forbidden
+	}
+
+	to := time.Now()
+	if request.To != "" {
+		if to, err = schedule.ParseBound(request.To); err != nil {
+			return dto.PromoAnalyticsResponse{}, fmt.Errorf("%w: to is neither a date nor RFC 3339", ErrInvalidAnalyticsRange)
+		}
+	}
+
+	from := to.AddDate(0, 0, -30)
+	if request.From != "" {
+		if from, err = schedule.ParseBound(request.From); err != nil {
+			return dto.PromoAnalyticsResponse{}, fmt.Errorf("%w: from is neither a date nor RFC 3339", ErrInvalidAnalyticsRange)
+		}
+	}
+
+	if !from.Before(to) {
+		return dto.PromoAnalyticsResponse{}, fmt.Errorf("%w: from must be before to", ErrInvalidAnalyticsRange)
+	}
+
+	bucket, width := request.Bucket, 24*time.Hour
+	switch bucket {
+	case "hour":
+		width = time.Hour
+	case "week":
+		width = 7 * 24 * time.Hour
+	default:
+		bucket = "day"
+	}
+	if to.Sub(from)/width > maxAnalyticsBuckets {
+		return dto.PromoAnalyticsResponse{}, fmt.Errorf("%w: more than %d buckets, use a larger bucket or a shorter range", ErrInvalidAnalyticsRange, maxAnalyticsBuckets)
+	}
+
+	location := time.UTC
+	if request.Timezone != "" {
+		if location, err = time.LoadLocation(request.Timezone); err != nil {
+			return dto.PromoAnalyticsResponse{}, fmt.Errorf("%w: unknown timezone %q", ErrInvalidAnalyticsRange, request.Timezone)
+		}
+	}
+
+	return s.promoStorage.GetAnalytics(ctx, request.ID, from, to, bucket, location)
+}
@@ -13,6 +13,7 @@ type businessStorage interface {
 	Update(ctx context.Context, business *entity.Business) (*entity.Business, error)
 	Delete(ctx context.Context, id string) error
 	GetByEmail(ctx context.Context, email string) (*entity.Business, error)
+	GetNames(ctx context.Context, ids []string) (map[string]string, error)
 }
 
 type businessService struct {
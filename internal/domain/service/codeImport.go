@@ -0,0 +1,450 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	codeMinLength = 3
+	codeMaxLength = 30
+
+	importBatchSize    = 1000 // codes inserted and progress saved at once
+	importMaxRowErrors = 100  // rejected rows kept in the report, the rest are only counted
+	importQueueSize    = 16   // uploaded files waiting for the worker, more are refused until it frees up
+
+	// defaultImportStaleAfter is used when imports.stale-after is not set.
+	defaultImportStaleAfter = 10 * time.Minute
+	// importSaveTimeout bounds saving the result of an import interrupted by shutdown.
+	importSaveTimeout = 5 * time.Second
+)
+
+type codeImportStorage interface {
+	Create(ctx context.Context, codeImport entity.CodeImport) (*entity.CodeImport, error)
+	Update(ctx context.Context, codeImport *entity.CodeImport) error
+	GetByID(ctx context.Context, importID, promoID, companyID string) (*entity.CodeImport, error)
+	FailStale(ctx context.Context, before time.Time, reason string) (int64, error)
+	Touch(ctx context.Context, importIDs []string) error
+}
+
+// codeImportJob is an uploaded file waiting for the worker.
+type codeImportJob struct {
+	codeImport entity.CodeImport
+	path       string
+}
+
+type codeImportService struct {
+	codeImportStorage codeImportStorage
+	promoCodeStorage  promoCodeStorage
+	promoCacheStorage promoCacheStorage
+	jobs              chan codeImportJob
+
+	mu   sync.Mutex
+	held map[string]struct{} // imports queued or running on this instance
+}
+
+func NewCodeImportService(codeImportStorage codeImportStorage, promoCodeStorage promoCodeStorage, promoCacheStorage promoCacheStorage) *codeImportService {
+	return &codeImportService{
+		codeImportStorage: codeImportStorage,
+		promoCodeStorage:  promoCodeStorage,
+		promoCacheStorage: promoCacheStorage,
+		jobs:              make(chan codeImportJob, importQueueSize),
+		held:              make(map[string]struct{}),
+	}
+}
+
+// Start is a method that saves the uploaded CSV to a temporary file and queues it for Run.
+/*
+ * The promo is checked before the upload is accepted, the returned import is polled with Get.
+ * Returns errorz.LimitError if importQueueSize files are already waiting.
+ */
+func (s *codeImportService) Start(ctx context.Context, companyID, promoID string, file io.Reader) (dto.PromoCodesImportResponse, error) {
+	if err := s.promoCodeStorage.CheckPromo(ctx, promoID, companyID); err != nil {
+		return dto.PromoCodesImportResponse{}, err
+	}
+
+	tmp, err := os.CreateTemp("", "promo-codes-*.csv")
+	if err != nil {
+		return dto.PromoCodesImportResponse{}, err
+	}
+	if _, err = io.Copy(tmp, file); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return dto.PromoCodesImportResponse{}, err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return dto.PromoCodesImportResponse{}, err
+	}
+
+	codeImport, err := s.codeImportStorage.Create(ctx, entity.CodeImport{
+		PromoID:   promoID,
+		CompanyID: companyID,
+		Status:    "pending",
+		RowErrors: "[]",
+	})
+	if err != nil {
+		os.Remove(tmp.Name())
+		return dto.PromoCodesImportResponse{}, err
+	}
+
+	job := codeImportJob{codeImport: *codeImport, path: tmp.Name()}
+	s.hold(codeImport.ImportID)
+	select {
+	case s.jobs <- job:
+	default:
+		s.fail(job, "import queue is full")
+		return dto.PromoCodesImportResponse{}, &errorz.LimitError{Scope: "import"}
+	}
+
+	return importResponse(codeImport), nil
+}
+
+// Run is a method that fails imports left unfinished by a stopped instance, then imports queued files one by one until ctx is done.
+/*
+ * An import is stale when it was not saved for staleAfter, its temporary file is gone with the instance that ran it.
+ * Imports queued or running here are saved every third of staleAfter, so a starting instance doesn't fail them.
+ * Files still queued on shutdown are removed and their imports fail.
+ */
+func (s *codeImportService) Run(ctx context.Context, staleAfter time.Duration) {
+	if staleAfter <= 0 {
+		staleAfter = defaultImportStaleAfter
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.keepAlive(ctx, staleAfter/3)
+	}()
+	defer wg.Wait()
+
+	failed, err := s.codeImportStorage.FailStale(ctx, time.Now().Add(-staleAfter), "interrupted by restart")
+	if err != nil {
+		logger.Log.Errorf("failed to fail stale code imports: %v", err)
+	} else if failed > 0 {
+		logger.Log.Infof("failed %d stale code imports", failed)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case job := <-s.jobs:
+					s.fail(job, "interrupted by shutdown")
+				default:
+					return
+				}
+			}
+		case job := <-s.jobs:
+			s.run(ctx, job)
+		}
+	}
+}
+
+// keepAlive is a method that refreshes the imports held by this instance every interval until ctx is done.
+func (s *codeImportService) keepAlive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			importIDs := make([]string, 0, len(s.held))
+			for importID := range s.held {
+				importIDs = append(importIDs, importID)
+			}
+			s.mu.Unlock()
+
+			if len(importIDs) == 0 {
+				continue
+			}
+			if err := s.codeImportStorage.Touch(ctx, importIDs); err != nil {
+				logger.Log.Errorf("failed to refresh code imports: %v", err)
+			}
+		}
+	}
+}
+
+// hold is a method that marks an import as queued or running on this instance.
+func (s *codeImportService) hold(importID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held[importID] = struct{}{}
+}
+
+// release is a method that unmarks an import once it is finished or failed.
+func (s *codeImportService) release(importID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.held, importID)
+}
+
+func (s *codeImportService) Get(ctx context.Context, companyID string, request dto.PromoCodesImportGet) (dto.PromoCodesImportResponse, error) {
+	codeImport, err := s.codeImportStorage.GetByID(ctx, request.ImportID, request.ID, companyID)
+	if err != nil {
+		return dto.PromoCodesImportResponse{}, err
+	}
+
+	return importResponse(codeImport), nil
+}
+
+// codeImportRun is a state of an import in progress.
+type codeImportRun struct {
+	codeImport entity.CodeImport
+	rowErrors  []dto.PromoCodesImportRow
+	seen       map[string]struct{}
+	batch      []string
+}
+
+// run is a method that imports codes from the file of the job and removes it when done.
+/*
+ * Codes added before ctx is done stay, the import fails with the shutdown as the error.
+ */
+func (s *codeImportService) run(ctx context.Context, job codeImportJob) {
+	defer os.Remove(job.path)
+	defer s.release(job.codeImport.ImportID)
+
+	codeImport := job.codeImport
+	state := &codeImportRun{
+		codeImport: codeImport,
+		rowErrors:  make([]dto.PromoCodesImportRow, 0),
+		seen:       make(map[string]struct{}),
+		batch:      make([]string, 0, importBatchSize),
+	}
+
+	err := s.process(ctx, state, job.path)
+	interrupted := ctx.Err() != nil
+
+	// Итог сохраняется и после остановки, иначе импорт навсегда останется running
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), importSaveTimeout)
+	defer cancel()
+
+	finishedAt := time.Now()
+	state.codeImport.FinishedAt = &finishedAt
+	state.codeImport.Status = "done"
+	if err != nil {
+		logger.Log.Errorf("failed to import codes %s into promo %s: %v", codeImport.ImportID, codeImport.PromoID, err)
+		state.codeImport.Status = "failed"
+		state.codeImport.Error = err.Error()
+		if interrupted {
+			state.codeImport.Error = "interrupted by shutdown"
+		}
+	}
+
+	if err = s.save(ctx, state); err != nil {
+		logger.Log.Errorf("failed to save code import %s: %v", codeImport.ImportID, err)
+	}
+
+	if state.codeImport.Added > 0 {
+		invalidatePromo(ctx, s.promoCacheStorage, codeImport.PromoID)
+	}
+}
+
+func (s *codeImportService) process(ctx context.Context, state *codeImportRun, path string) error {
+	// Первый проход только считает строки, чтобы отдавать прогресс
+	total := 0
+	if err := readCodes(path, func(int, string, error) error {
+		total++
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	state.codeImport.Status = "running"
+	state.codeImport.TotalRows = total
+	if err := s.save(ctx, state); err != nil {
+		return err
+	}
+
+	err := readCodes(path, func(row int, code string, parseErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		state.codeImport.Processed++
+
+		if parseErr != nil {
+			state.reject(row, code, parseErr.Error())
+			return nil
+		}
+
+		if reason := validateCode(code); reason != "" {
+			state.reject(row, code, reason)
+			return nil
+		}
+
+		if _, ok := state.seen[code]; ok {
+			state.codeImport.Duplicates++
+			return nil
+		}
+		state.seen[code] = struct{}{}
+
+		state.batch = append(state.batch, code)
+		if len(state.batch) < importBatchSize {
+			return nil
+		}
+
+		return s.flush(ctx, state)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.flush(ctx, state)
+}
+
+// flush is a method that inserts the collected codes and saves the progress.
+func (s *codeImportService) flush(ctx context.Context, state *codeImportRun) error {
+	if len(state.batch) > 0 {
+		res, err := s.promoCodeStorage.AddCodes(ctx, state.codeImport.PromoID, state.codeImport.CompanyID, state.batch)
+		if err != nil {
+			return err
+		}
+
+		state.codeImport.Added += res.Added
+		state.codeImport.Duplicates += len(state.batch) - res.Added
+		state.batch = state.batch[:0]
+	}
+
+	return s.save(ctx, state)
+}
+
+// fail is a method that removes the file of a job that never ran and saves its import as failed.
+func (s *codeImportService) fail(job codeImportJob, reason string) {
+	os.Remove(job.path)
+	defer s.release(job.codeImport.ImportID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), importSaveTimeout)
+	defer cancel()
+
+	finishedAt := time.Now()
+	job.codeImport.Status = "failed"
+	job.codeImport.Error = reason
+	job.codeImport.FinishedAt = &finishedAt
+	if err := s.codeImportStorage.Update(ctx, &job.codeImport); err != nil {
+		logger.Log.Errorf("failed to save code import %s: %v", job.codeImport.ImportID, err)
+	}
+}
+
+func (s *codeImportService) save(ctx context.Context, state *codeImportRun) error {
+	rowErrors, err := json.Marshal(state.rowErrors)
+	if err != nil {
+		return err
+	}
+	state.codeImport.RowErrors = string(rowErrors)
+
+	return s.codeImportStorage.Update(ctx, &state.codeImport)
+}
+
+func (r *codeImportRun) reject(row int, code, reason string) {
+	r.codeImport.Invalid++
+	if len(r.rowErrors) < importMaxRowErrors {
+		r.rowErrors = append(r.rowErrors, dto.PromoCodesImportRow{Row: row, Code: code, Error: reason})
+	}
+}
+
+// readCodes is a function that streams the first column of a CSV file, skipping an optional "code" header.
+/*
+ * Malformed rows are passed to fn with a parse error instead of failing the whole file.
+ */
+func readCodes(path string, fn func(row int, code string, err error) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err = fn(row, "", parseErr.Err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		code := strings.TrimSpace(record[0])
+		if row == 1 && strings.EqualFold(code, "code") {
+			continue
+		}
+
+		if err = fn(row, code, nil); err != nil {
+			return err
+		}
+	}
+}
+
+// validateCode is a function that returns the reason a code is rejected or an empty string.
+func validateCode(code string) string {
+	length := utf8.RuneCountInString(code)
+	switch {
+	case length == 0:
+		return "empty code"
+	case length < codeMinLength:
+		return "code is shorter than 3 characters"
+	case length > codeMaxLength:
+		return "code is longer than 30 characters"
+	}
+
+	return ""
+}
+
+func importResponse(codeImport *entity.CodeImport) dto.PromoCodesImportResponse {
+	res := dto.PromoCodesImportResponse{
+		ImportID:   codeImport.ImportID,
+		PromoID:    codeImport.PromoID,
+		Status:     codeImport.Status,
+		TotalRows:  codeImport.TotalRows,
+		Processed:  codeImport.Processed,
+		Added:      codeImport.Added,
+		Duplicates: codeImport.Duplicates,
+		Invalid:    codeImport.Invalid,
+		RowErrors:  make([]dto.PromoCodesImportRow, 0),
+		Error:      codeImport.Error,
+		CreatedAt:  codeImport.CreatedAt.UTC().Format(time.RFC3339),
+	}
+
+	if codeImport.TotalRows > 0 {
+		res.Progress = float64(codeImport.Processed) / float64(codeImport.TotalRows)
+	} else if codeImport.Status == "done" {
+		res.Progress = 1
+	}
+	if codeImport.FinishedAt != nil {
+		res.FinishedAt = codeImport.FinishedAt.UTC().Format(time.RFC3339)
+	}
+	if codeImport.RowErrors != "" {
+		if err := json.Unmarshal([]byte(codeImport.RowErrors), &res.RowErrors); err != nil {
+			logger.Log.Errorf("failed to decode row errors of code import %s: %v", codeImport.ImportID, err)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/dto"
+	"sync"
+	"time"
+)
+
+const (
+	eventMaxAttempts = 10 // an event is given up after this many failed publications
+	eventFirstRetry  = 10 * time.Second
+	eventMaxRetry    = time.Hour
+	eventLease       = time.Minute
+	eventBatchSize   = 100
+
+	// defaultEventInterval is used when events.dispatch-interval is not set.
+	defaultEventInterval = time.Second
+	// defaultEventRetention is used when events.retention is not set.
+	defaultEventRetention = 7 * 24 * time.Hour
+	// eventAllTypes subscribes a handler to every event type.
+	eventAllTypes = "*"
+)
+
+type outboxStorage interface {
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]dto.DomainEvent, error)
+	MarkPublished(ctx context.Context, seq int64) error
+	MarkFailed(ctx context.Context, seq int64, nextAttemptAt *time.Time, lastError string) error
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+// EventHandler is a subscriber of the event bus, an error makes the bus publish the event again later.
+type EventHandler func(ctx context.Context, event dto.DomainEvent) error
+
+// EventBus is a dispatcher of the outbox to in-process subscribers and sinks.
+/*
+ * Delivery is at least once: an event is published again to all its subscribers if any of them fails,
+ * so handlers must be idempotent by the event ID. Events are published in the order they were written,
+ * a failed event is retried later and doesn't hold the events after it.
+ */
+type EventBus struct {
+	outboxStorage outboxStorage
+
+	mu       sync.RWMutex
+	handlers map[string][]EventHandler
+}
+
+func NewEventBus(outboxStorage outboxStorage) *EventBus {
+	return &EventBus{
+		outboxStorage: outboxStorage,
+		handlers:      make(map[string][]EventHandler),
+	}
+}
+
+// Subscribe is a method that adds a handler of the event type, "*" subscribes it to all types.
+func (b *EventBus) Subscribe(eventType string, handler EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Run is a method that publishes due events every interval and deletes events published longer than retention ago, until ctx is done.
+func (b *EventBus) Run(ctx context.Context, interval, retention time.Duration) {
+	if interval <= 0 {
+		interval = defaultEventInterval
+	}
+	if retention <= 0 {
+		retention = defaultEventRetention
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Пачки выбираются, пока очередь не опустеет
+		for {
+			events, err := b.outboxStorage.ClaimDue(ctx, eventBatchSize, eventLease)
+			if err != nil {
+				logger.Log.Errorf("failed to claim outbox events: %v", err)
+				break
+			}
+
+			for _, event := range events {
+				b.publish(ctx, event)
+			}
+
+			if len(events) < eventBatchSize {
+				break
+			}
+		}
+
+		if time.Since(lastCleanup) >= time.Hour {
+			lastCleanup = time.Now()
+			if _, err := b.outboxStorage.DeletePublished(ctx, time.Now().Add(-retention)); err != nil {
+				logger.Log.Errorf("failed to delete published outbox events: %v", err)
+			}
+		}
+	}
+}
+
+func (b *EventBus) publish(ctx context.Context, event dto.DomainEvent) {
+	b.mu.RLock()
+	handlers := append(append([]EventHandler(nil), b.handlers[event.Type]...), b.handlers[eventAllTypes]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		nextAttemptAt := nextEventAttempt(event.Attempts + 1)
+		if nextAttemptAt == nil {
+			logger.Log.Errorf("gave up publishing %s event %s: %v", event.Type, event.ID, err)
+		}
+
+		if err = b.outboxStorage.MarkFailed(ctx, event.Seq, nextAttemptAt, truncate(err.Error(), webhookErrorMaxSize)); err != nil {
+			logger.Log.Errorf("failed to record outbox event %s: %v", event.ID, err)
+		}
+		return
+	}
+
+	if err := b.outboxStorage.MarkPublished(ctx, event.Seq); err != nil {
+		logger.Log.Errorf("failed to record outbox event %s: %v", event.ID, err)
+	}
+}
+
+// nextEventAttempt is a function that returns when to publish an event after the failed attempts, nil to give it up.
+func nextEventAttempt(attempts int) *time.Time {
+	if attempts >= eventMaxAttempts {
+		return nil
+	}
+
+	delay := eventFirstRetry << (attempts - 1)
+	if delay > eventMaxRetry {
+		delay = eventMaxRetry
+	}
+
+	next := time.Now().Add(delay)
+	return &next
+}
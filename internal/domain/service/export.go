@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/utils/schedule"
+	"prod/internal/domain/utils/xlsx"
+	"strings"
+	"time"
+)
+
+var ErrInvalidExportRange = errors.New("invalid export range")
+
+// exportHeader is the first row of an activations export.
+var exportHeader = []string{"activation_id", "activated_at", "promo_id", "user", "country", "age_band", "code"}
+
+// Export is a file ready to be streamed: Write writes it to w once the response headers are sent.
+type Export struct {
+	Filename    string
+	ContentType string
+	Write       func(ctx context.Context, w io.Writer) error
+}
+
+// rowWriter is a writer of a table, both csv.Writer and xlsx.Writer are.
+type rowWriter interface {
+	Write(cells []string) error
+}
+
+// ExportPromoActivations is a method that returns an export of the activations of a promo of the company, soft deleted promos included.
+func (s *promoService) ExportPromoActivations(ctx context.Context, companyID string, request dto.PromoActivationsExportRequest) (Export, error) {
+	promoCompanyID, err := s.promoStorage.GetCompanyID(ctx, request.ID)
+	if err != nil {
+		return Export{}, err
+	}
+	if promoCompanyID != companyID {
+		return Export{}, errorz.Forbidden
+	}
+
+	filter := dto.ActivationExportFilter{CompanyID: companyID, PromoID: request.ID}
+	if request.From != "" {
+		from, err := schedule.ParseBound(request.From)
+		if err != nil {
+			return Export{}, fmt.Errorf("%w: from is neither a date nor RFC 3339", ErrInvalidExportRange)
+		}
+		filter.From = &from
+	}
+	if request.To != "" {
+		to, err := schedule.ParseBound(request.To)
+		if err != nil {
+			return Export{}, fmt.Errorf("%w: to is neither a date nor RFC 3339", ErrInvalidExportRange)
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return Export{}, fmt.Errorf("%w: from must be before to", ErrInvalidExportRange)
+	}
+
+	return s.export(ctx, filter, request.Format, "activations-"+request.ID)
+}
+
+// ExportCompanyActivations is a method that returns an export of the activations of all promos of the company over [from, to).
+func (s *promoService) ExportCompanyActivations(ctx context.Context, companyID string, request dto.CompanyActivationsExportRequest) (Export, error) {
+	var err error
+
+	to := time.Now()
+	if request.To != "" {
+		if to, err = schedule.ParseBound(request.To); err != nil {
+			return Export{}, fmt.Errorf("%w: to is neither a date nor RFC 3339", ErrInvalidExportRange)
+		}
+	}
+
+	from := to.AddDate(0, 0, -30)
+	if request.From != "" {
+		if from, err = schedule.ParseBound(request.From); err != nil {
+			return Export{}, fmt.Errorf("%w: from is neither a date nor RFC 3339", ErrInvalidExportRange)
+		}
+	}
+
+	if !from.Before(to) {
+		return Export{}, fmt.Errorf("%w: from must be before to", ErrInvalidExportRange)
+	}
+
+	filter := dto.ActivationExportFilter{CompanyID: companyID, From: &from, To: &to}
+	name := fmt.Sprintf("activations-%s-%s", from.UTC().Format(schedule.DateLayout), to.UTC().Format(schedule.DateLayout))
+
+	return s.export(ctx, filter, request.Format, name)
+}
+
+// export is a method that returns an export of the activations matching the filter as csv (by default) or xlsx.
+/*
+ * An xlsx sheet holds xlsx.MaxRows rows with the header, larger ranges are rejected before anything is sent.
+ */
+func (s *promoService) export(ctx context.Context, filter dto.ActivationExportFilter, format, name string) (Export, error) {
+	pseudonym := pseudonymizer(filter.CompanyID)
+
+	if format == "xlsx" {
+		count, err := s.promoStorage.CountActivations(ctx, filter)
+		if err != nil {
+			return Export{}, err
+		}
+		if count > xlsx.MaxRows-1 {
+			return Export{}, fmt.Errorf("%w: %d activations don't fit in an xlsx sheet, use csv or a shorter range", ErrInvalidExportRange, count)
+		}
+
+		// Активации, сделанные после подсчета, в лист уже не попадут
+		filter.Limit = xlsx.MaxRows - 1
+	}
+
+	writeRows := func(ctx context.Context, rows rowWriter) error {
+		if err := rows.Write(exportHeader); err != nil {
+			return err
+		}
+
+		return s.promoStorage.ExportActivations(ctx, filter, func(row dto.ActivationExportRow) error {
+			return rows.Write([]string{
+				row.ActivationID,
+				row.CreatedAt.UTC().Format(time.RFC3339),
+				row.PromoID,
+				pseudonym(row.UserID),
+				strings.ToLower(row.Country.Alpha2()),
+				row.AgeBand,
+				row.Code,
+			})
+		})
+	}
+
+	if format == "xlsx" {
+		return Export{
+			Filename:    name + ".xlsx",
+			ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			Write: func(ctx context.Context, w io.Writer) error {
+				sheet, err := xlsx.NewWriter(w, "activations")
+				if err != nil {
+					return err
+				}
+				if err = writeRows(ctx, sheet); err != nil {
+					return err
+				}
+				return sheet.Close()
+			},
+		}, nil
+	}
+
+	return Export{
+		Filename:    name + ".csv",
+		ContentType: "text/csv; charset=utf-8",
+		Write: func(ctx context.Context, w io.Writer) error {
+			// BOM, чтобы Excel открывал файл в UTF-8
+			if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+				return err
+			}
+
+			table := csv.NewWriter(w)
+			if err := writeRows(ctx, table); err != nil {
+				return err
+			}
+			table.Flush()
+			return table.Error()
+		},
+	}, nil
+}
+
+// pseudonymizer is a function that returns a stable pseudonym of a user for the company.
+/*
+ * The pseudonym is an HMAC of the company and user ids, so it is the same in every export of the company
+ * but can't be reversed or matched across companies without export.pseudonym-key. The key is required at start.
+ */
+func pseudonymizer(companyID string) func(userID string) string {
+	key := viper.GetString("export.pseudonym-key")
+
+	return func(userID string) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(companyID + ":" + userID))
+		return hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// defaultCommentRateLimit and defaultCommentRateWindow are used when moderation.rate-limit or moderation.rate-window is not set.
+	defaultCommentRateLimit  = 5
+	defaultCommentRateWindow = time.Minute
+	// defaultReportThreshold is used when moderation.report-threshold is not set.
+	defaultReportThreshold = 3
+)
+
+// Actions of the filter on comments with links
+const (
+	linksHold   = "pending"
+	linksReject = "reject"
+	linksAllow  = "allow"
+)
+
+// linkPattern matches URLs, www. and bare domains in popular zones, including t.me links.
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.|t\.me/|\b[a-z0-9][a-z0-9-]*\.(com|net|org|ru|su|io|me|info|biz|xyz|top|site|online|shop|club)\b|[a-zа-яё0-9-]+\.рф)`)
+
+// moderationConfig is the comment filter and the report threshold from the config.
+type moderationConfig struct {
+	blockedWords    map[string]struct{}
+	links           string
+	rateLimit       int64
+	rateWindow      time.Duration
+	reportThreshold int
+}
+
+// commentModeration is a function that reads the comment filter from the config.
+func commentModeration() moderationConfig {
+	config := moderationConfig{
+		blockedWords:    make(map[string]struct{}),
+		links:           viper.GetString("moderation.links"),
+		rateLimit:       viper.GetInt64("moderation.rate-limit"),
+		rateWindow:      viper.GetDuration("moderation.rate-window"),
+		reportThreshold: viper.GetInt("moderation.report-threshold"),
+	}
+
+	for _, word := range viper.GetStringSlice("moderation.blocked-words") {
+		if word = normalizeWord(word); word != "" {
+			config.blockedWords[word] = struct{}{}
+		}
+	}
+
+	if config.links == "" {
+		config.links = linksHold
+	}
+	if config.rateLimit <= 0 {
+		config.rateLimit = defaultCommentRateLimit
+	}
+	if config.rateWindow <= 0 {
+		config.rateWindow = defaultCommentRateWindow
+	}
+	if config.reportThreshold <= 0 {
+		config.reportThreshold = defaultReportThreshold
+	}
+
+	return config
+}
+
+// normalizeWord is a function that lowercases a word and replaces ё with е, so spellings of a blocked word match.
+func normalizeWord(word string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(word)), "ё", "е")
+}
+
+// check is a method that returns errorz.CommentRejected for a text with a blocked word, or the reason to hold it for moderation.
+/*
+ * An empty reason means the text can be published right away.
+ */
+func (c moderationConfig) check(text string) (string, error) {
+	if len(c.blockedWords) > 0 {
+		words := strings.FieldsFunc(text, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			if _, ok := c.blockedWords[normalizeWord(word)]; ok {
+				return "", errorz.CommentRejected
+			}
+		}
+	}
+
+	if c.links != linksAllow && linkPattern.MatchString(text) {
+		if c.links == linksReject {
+			return "", errorz.CommentRejected
+		}
+		return dto.ModerationReasonLink, nil
+	}
+
+	return "", nil
+}
+
+// ReportComment is a method that reports a published comment, reported by report-threshold users it goes to the moderation queue.
+func (s *actionsService) ReportComment(ctx context.Context, userID string, request dto.ReportComment) error {
+	if err := s.actionStorage.ReportComment(ctx, userID, request.ID, request.CommentID, request.Reason, commentModeration().reportThreshold); err != nil {
+		return err
+	}
+
+	invalidatePromo(ctx, s.promoCacheStorage, request.ID)
+	return nil
+}
+
+// HideComment is a method that hides a published or pending comment under a promo of the company.
+func (s *actionsService) HideComment(ctx context.Context, companyID string, request dto.ModerateComment) error {
+	return s.moderate(ctx, companyID, request, []string{dto.CommentPublished, dto.CommentPending}, dto.CommentHidden, dto.ModerationReasonCompany)
+}
+
+// ApproveComment is a method that publishes a pending or hidden comment under a promo of the company.
+func (s *actionsService) ApproveComment(ctx context.Context, companyID string, request dto.ModerateComment) error {
+	return s.moderate(ctx, companyID, request, []string{dto.CommentPending, dto.CommentHidden}, dto.CommentPublished, "")
+}
+
+// RejectComment is a method that hides a pending comment under a promo of the company, a published one returns errorz.Conflict.
+func (s *actionsService) RejectComment(ctx context.Context, companyID string, request dto.ModerateComment) error {
+	return s.moderate(ctx, companyID, request, []string{dto.CommentPending}, dto.CommentHidden, dto.ModerationReasonRejected)
+}
+
+func (s *actionsService) moderate(ctx context.Context, companyID string, request dto.ModerateComment, from []string, status, reason string) error {
+	if err := s.checkOwner(ctx, companyID, request.ID); err != nil {
+		return err
+	}
+
+	if err := s.actionStorage.SetCommentStatus(ctx, request.ID, request.CommentID, from, status, reason); err != nil {
+		return err
+	}
+
+	invalidatePromo(ctx, s.promoCacheStorage, request.ID)
+	return nil
+}
+
+// GetModerationQueue is a method that returns comments of the company's promos held for moderation or hidden, oldest first.
+func (s *actionsService) GetModerationQueue(ctx context.Context, companyID string, request dto.ModerationQueueRequest, page dto.Page) ([]dto.ModerationComment, string, int64, error) {
+	if request.PromoID != "" {
+		if err := s.checkOwner(ctx, companyID, request.PromoID); err != nil {
+			return nil, "", 0, err
+		}
+	}
+
+	status := request.Status
+	if status == "" {
+		status = dto.CommentPending
+	}
+
+	return s.actionStorage.GetModerationQueue(ctx, companyID, request.PromoID, status, page)
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"testing"
+)
+
+func moderationWith(links string, blockedWords ...string) moderationConfig {
+	config := moderationConfig{blockedWords: make(map[string]struct{}), links: links}
+	for _, word := range blockedWords {
+		config.blockedWords[normalizeWord(word)] = struct{}{}
+	}
+
+	return config
+}
+
+func TestModerationCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     moderationConfig
+		text       string
+		wantReason string
+		wantErr    error
+	}{
+		{name: "ordinary text", config: moderationWith(linksHold, "спам"), text: "Отличная скидка, спасибо!"},
+		{name: "blocked word", config: moderationWith(linksHold, "спам"), text: "Это спам, не ведитесь", wantErr: errorz.CommentRejected},
+		{name: "blocked word in another case", config: moderationWith(linksHold, "спам"), text: "СПАМ", wantErr: errorz.CommentRejected},
+		{name: "blocked word between punctuation", config: moderationWith(linksHold, "спам"), text: "...спам!!!", wantErr: errorz.CommentRejected},
+		{name: "ё in the text, е in the list", config: moderationWith(linksHold, "елка"), text: "Купите ёлку или Ёлка", wantErr: errorz.CommentRejected},
+		{name: "е in the text, ё in the list", config: moderationWith(linksHold, "ёлка"), text: "Зеленая елка", wantErr: errorz.CommentRejected},
+		{name: "list is trimmed and lowercased", config: moderationWith(linksHold, "  Спам "), text: "спам", wantErr: errorz.CommentRejected},
+		{name: "part of a longer word", config: moderationWith(linksHold, "спам"), text: "Спамеры сюда не ходят", wantReason: ""},
+		{name: "blocked word in Latin", config: moderationWith(linksHold, "scam"), text: "Total SCAM.", wantErr: errorz.CommentRejected},
+		{name: "blocked word wins over a link", config: moderationWith(linksAllow, "спам"), text: "спам на example.com", wantErr: errorz.CommentRejected},
+
+		{name: "link held", config: moderationWith(linksHold), text: "Заходите на https://example.com/sale", wantReason: dto.ModerationReasonLink},
+		{name: "link rejected", config: moderationWith(linksReject), text: "Заходите на https://example.com/sale", wantErr: errorz.CommentRejected},
+		{name: "link allowed", config: moderationWith(linksAllow), text: "Заходите на https://example.com/sale"},
+		{name: "bare domain held", config: moderationWith(linksHold), text: "пишите в shop-best.ru", wantReason: dto.ModerationReasonLink},
+		{name: "no links, hold mode", config: moderationWith(linksHold), text: "Взял 2.5 кг за 199.90 руб, т.е. почти даром"},
+		{name: "no links, reject mode", config: moderationWith(linksReject), text: "Взял 2.5 кг за 199.90 руб, т.е. почти даром"},
+		{name: "empty filter", config: moderationWith(linksHold), text: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, err := tt.config.check(tt.text)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if reason != tt.wantReason {
+				t.Fatalf("got reason %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestLinkPattern(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "http://example.com", want: true},
+		{text: "HTTPS://EXAMPLE.ORG/path?q=1", want: true},
+		{text: "www.something", want: true},
+		{text: "подписывайтесь t.me/promo_channel", want: true},
+		{text: "example.com", want: true},
+		{text: "My-Shop.Online", want: true},
+		{text: "best-deals.xyz!", want: true},
+		{text: "сайт магазин.рф", want: true},
+		{text: "ПРИМЕР.РФ", want: true},
+
+		{text: "Отличная скидка, спасибо!", want: false},
+		{text: "Взял 2.5 кг за 199.90 руб", want: false},
+		{text: "т.е. почти даром", want: false},
+		{text: "e.g. coffee or tea", want: false},
+		{text: "версия 1.2.3 работает", want: false},
+		{text: "Конец предложения.Начало следующего", want: false},
+		{text: "Ends today. Come early", want: false},
+		{text: "The community.", want: false},
+		{text: "info: ru, com, net", want: false},
+		{text: "example.community", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := linkPattern.MatchString(tt.text); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
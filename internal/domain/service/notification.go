@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/dto"
+	"time"
+)
+
+const (
+	// defaultNotificationSweepInterval is used when notifications.sweep-interval is not set.
+	defaultNotificationSweepInterval = 10 * time.Minute
+	// defaultNotificationExpiringWithin is used when notifications.expiring-within is not set.
+	defaultNotificationExpiringWithin = 24 * time.Hour
+)
+
+type notificationStorage interface {
+	List(ctx context.Context, userID string, unread bool, page dto.Page) ([]dto.NotificationDTO, string, int64, error)
+	UnreadCount(ctx context.Context, userID string) (int64, error)
+	MarkRead(ctx context.Context, userID, notificationID string) error
+	MarkAllRead(ctx context.Context, userID string) (int64, error)
+	GetPreferences(ctx context.Context, userID string) (map[string]bool, error)
+	SetPreferences(ctx context.Context, userID string, preferences map[string]bool) error
+	AddFavoriteCompany(ctx context.Context, userID, companyID string) error
+	DeleteFavoriteCompany(ctx context.Context, userID, companyID string) error
+	NotifyCompanyPromo(ctx context.Context, promoID string) (int64, error)
+	NotifyCommentReply(ctx context.Context, replyID string) (int64, error)
+	NotifyExpiringLikes(ctx context.Context, within time.Duration) (int64, error)
+}
+
+type notificationService struct {
+	notificationStorage notificationStorage
+}
+
+func NewNotificationService(notificationStorage notificationStorage) *notificationService {
+	return &notificationService{notificationStorage: notificationStorage}
+}
+
+func (s *notificationService) List(ctx context.Context, userID string, unread bool, page dto.Page) ([]dto.NotificationDTO, string, int64, error) {
+	return s.notificationStorage.List(ctx, userID, unread, page)
+}
+
+func (s *notificationService) UnreadCount(ctx context.Context, userID string) (int64, error) {
+	return s.notificationStorage.UnreadCount(ctx, userID)
+}
+
+func (s *notificationService) MarkRead(ctx context.Context, userID, notificationID string) error {
+	return s.notificationStorage.MarkRead(ctx, userID, notificationID)
+}
+
+func (s *notificationService) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	return s.notificationStorage.MarkAllRead(ctx, userID)
+}
+
+// GetPreferences is a method that returns the user's flag of every notification type, enabled unless turned off.
+func (s *notificationService) GetPreferences(ctx context.Context, userID string) (dto.NotificationPreferences, error) {
+	stored, err := s.notificationStorage.GetPreferences(ctx, userID)
+	if err != nil {
+		return dto.NotificationPreferences{}, err
+	}
+
+	enabled := func(notificationType string) bool {
+		value, ok := stored[notificationType]
+		return !ok || value
+	}
+
+	return dto.NotificationPreferences{
+		PromoExpiring: enabled(dto.NotificationPromoExpiring),
+		CompanyPromo:  enabled(dto.NotificationCompanyPromo),
+		CommentReply:  enabled(dto.NotificationCommentReply),
+	}, nil
+}
+
+// UpdatePreferences is a method that changes the given flags of the user and returns all of them.
+func (s *notificationService) UpdatePreferences(ctx context.Context, userID string, request dto.NotificationPreferencesUpdate) (dto.NotificationPreferences, error) {
+	preferences := make(map[string]bool)
+	for notificationType, value := range map[string]*bool{
+		dto.NotificationPromoExpiring: request.PromoExpiring,
+		dto.NotificationCompanyPromo:  request.CompanyPromo,
+		dto.NotificationCommentReply:  request.CommentReply,
+	} {
+		if value != nil {
+			preferences[notificationType] = *value
+		}
+	}
+
+	if err := s.notificationStorage.SetPreferences(ctx, userID, preferences); err != nil {
+		return dto.NotificationPreferences{}, err
+	}
+
+	return s.GetPreferences(ctx, userID)
+}
+
+func (s *notificationService) AddFavoriteCompany(ctx context.Context, userID, companyID string) error {
+	return s.notificationStorage.AddFavoriteCompany(ctx, userID, companyID)
+}
+
+func (s *notificationService) DeleteFavoriteCompany(ctx context.Context, userID, companyID string) error {
+	return s.notificationStorage.DeleteFavoriteCompany(ctx, userID, companyID)
+}
+
+// HandleEvent is a method that creates notifications of a domain event, it is subscribed to the event bus.
+/*
+ * Notifications are deduplicated by their key, so an event published again doesn't notify twice.
+ */
+func (s *notificationService) HandleEvent(ctx context.Context, event dto.DomainEvent) error {
+	switch event.Type {
+	case dto.EventPromoPublished:
+		_, err := s.notificationStorage.NotifyCompanyPromo(ctx, event.PromoID)
+		return err
+	case dto.EventCommentAdded:
+		var payload dto.CommentAddedEvent
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return err
+		}
+		if payload.ParentID == nil {
+			return nil
+		}
+		_, err := s.notificationStorage.NotifyCommentReply(ctx, payload.CommentID)
+		return err
+	}
+
+	return nil
+}
+
+// Run is a method that notifies users of liked promos ending within expiringWithin every interval until ctx is done.
+func (s *notificationService) Run(ctx context.Context, interval, expiringWithin time.Duration) {
+	if interval <= 0 {
+		interval = defaultNotificationSweepInterval
+	}
+	if expiringWithin <= 0 {
+		expiringWithin = defaultNotificationExpiringWithin
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.notificationStorage.NotifyExpiringLikes(ctx, expiringWithin); err != nil {
+			logger.Log.Errorf("failed to notify of expiring promos: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
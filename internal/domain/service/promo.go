@@ -2,11 +2,17 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"github.com/biter777/countries"
 	"github.com/gofiber/fiber/v3"
+	"github.com/spf13/viper"
+	"prod/internal/adapters/logger"
 	"prod/internal/domain/common/errorz"
 	"prod/internal/domain/dto"
 	"prod/internal/domain/entity"
+	"prod/internal/domain/utils/codegen"
+	"prod/internal/domain/utils/schedule"
+	"slices"
 	"strings"
 	"time"
 )
@@ -14,23 +20,53 @@ import (
 type promoStorage interface {
 	Create(ctx context.Context, promo entity.Promo) (*entity.Promo, error)
 	GetByID(ctx context.Context, id string) (*entity.Promo, error)
+	GetCompanyID(ctx context.Context, promoID string) (string, error)
 	Update(ctx context.Context, fiberCtx fiber.Ctx, promo dto.PromoUpdate, id string) (*entity.Promo, error)
-	GetWithPagination(ctx context.Context, limit, offset int, sortBy, companyId string, countries []countries.CountryCode) ([]entity.Promo, int64, error)
-	GetFeed(ctx context.Context, age, limit, offset int, country countries.CountryCode, category *string, active, userID string) ([]dto.PromoForUser, int64, error)
-	GetByIdUser(ctx context.Context, promoID, userID string) (dto.PromoForUser, error)
-	GetHistory(ctx context.Context, userID string, limit, offset int) ([]dto.PromoForUser, int64, error)
+	Archive(ctx context.Context, id string) error
+	Unarchive(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string) error
+	SetStatus(ctx context.Context, id, from, to string) error
+	GetWithPagination(ctx context.Context, page dto.Page, sortBy, companyId string, countries []countries.CountryCode) ([]entity.Promo, string, int64, error)
+	GetFeed(ctx context.Context, age int, country countries.CountryCode, category *string, active, userID, sortBy string, ranking dto.FeedRanking, page dto.Page) ([]dto.PromoUserState, string, int64, error)
+	GetDetails(ctx context.Context, promoIDs []string) ([]dto.PromoForUser, error)
+	GetUserStates(ctx context.Context, age int, country countries.CountryCode, userID string, promoIDs []string) ([]dto.PromoUserState, error)
+	GetHistory(ctx context.Context, userID string, page dto.Page) ([]dto.PromoForUser, string, int64, error)
 	GetStats(ctx context.Context, promoID, companyID string) (dto.PromoStatsResponse, error)
+	GetAnalytics(ctx context.Context, promoID string, from, to time.Time, bucket string, location *time.Location) (dto.PromoAnalyticsResponse, error)
+	ExportActivations(ctx context.Context, filter dto.ActivationExportFilter, write func(row dto.ActivationExportRow) error) error
+	CountActivations(ctx context.Context, filter dto.ActivationExportFilter) (int64, error)
+	Search(ctx context.Context, age int, country countries.CountryCode, userID string, search dto.PromoSearchRequest) ([]dto.PromoSearchResult, dto.PromoSearchFacets, int64, error)
+}
+
+type promoCacheStorage interface {
+	GetPromos(ctx context.Context, promoIDs []string) (map[string]dto.PromoForUser, map[string]int64, error)
+	SetPromos(ctx context.Context, promos []dto.PromoForUser, versions map[string]int64) error
+	InvalidatePromo(ctx context.Context, promoID string) error
+	GetCompanyNames(ctx context.Context, companyIDs []string) (map[string]string, error)
+	SetCompanyNames(ctx context.Context, names map[string]string) error
+}
+
+// invalidatePromo is a function that drops cached promo details after the promo, its counters or its stock changed.
+/*
+ * A failure is only logged: the cache entry expires on its own, and the change itself is already saved.
+ */
+func invalidatePromo(ctx context.Context, cache promoCacheStorage, promoID string) {
+	if err := cache.InvalidatePromo(ctx, promoID); err != nil {
+		logger.Log.Errorf("failed to invalidate promo %s cache: %v", promoID, err)
+	}
 }
 
 type promoService struct {
-	promoStorage    promoStorage
-	businessStorage businessStorage
+	promoStorage      promoStorage
+	businessStorage   businessStorage
+	promoCacheStorage promoCacheStorage
 }
 
-func NewPromoService(promoStorage promoStorage, businessStorage businessStorage) *promoService {
+func NewPromoService(promoStorage promoStorage, businessStorage businessStorage, promoCacheStorage promoCacheStorage) *promoService {
 	return &promoService{
-		promoStorage:    promoStorage,
-		businessStorage: businessStorage,
+		promoStorage:      promoStorage,
+		businessStorage:   businessStorage,
+		promoCacheStorage: promoCacheStorage,
 	}
 }
 
@@ -38,7 +74,7 @@ func (s *promoService) Create(ctx context.Context, fiberCTX fiber.Ctx, promoDTO
 	var activeFrom, activeUntil time.Time
 	var timeError error
 	if promoDTO.ActiveFrom != "" {
-		activeFrom, timeError = time.Parse("2006-01-02", promoDTO.ActiveFrom)
+		activeFrom, timeError = schedule.ParseBound(promoDTO.ActiveFrom)
 		if timeError != nil {
 			//activeFrom, timeError = time.Parse("2006-01-02 15:04:05", promoDTO.ActiveFrom)
 			//if timeError != nil {
@@ -51,7 +87,7 @@ func (s *promoService) Create(ctx context.Context, fiberCTX fiber.Ctx, promoDTO
 		activeFrom = time.Unix(0, 0)
 	}
 	if promoDTO.ActiveUntil != "" {
-		activeUntil, timeError = time.Parse("2006-01-02", promoDTO.ActiveUntil)
+		activeUntil, timeError = schedule.ParseBound(promoDTO.ActiveUntil)
 		if timeError != nil {
 			//activeUntil, timeError = time.Parse("2006-01-02 15:04:05", promoDTO.ActiveUntil)
 			//if timeError != nil {
@@ -80,6 +116,26 @@ func (s *promoService) Create(ctx context.Context, fiberCTX fiber.Ctx, promoDTO
 		}
 	}
 
+	if promoDTO.Generator != nil {
+		codes, err := codegen.Generate(codegen.Spec{
+			Count:    promoDTO.Generator.Count,
+			Alphabet: promoDTO.Generator.Alphabet,
+			Length:   promoDTO.Generator.Length,
+			Prefix:   promoDTO.Generator.Prefix,
+			Checksum: promoDTO.Generator.Checksum,
+			MaxTotal: 30, // как у загружаемых кодов
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, code := range codes {
+			promoUniques = append(promoUniques, entity.PromoUnique{
+				Body: code,
+			})
+		}
+	}
+
 	company := fiberCTX.Locals("business").(*entity.Business)
 
 	promo := entity.Promo{
@@ -104,17 +160,36 @@ func (s *promoService) Create(ctx context.Context, fiberCTX fiber.Ctx, promoDTO
 			promo.AgeUntil = 1000
 		}
 		promo.AgeFrom = promoDTO.Target.AgeFrom
+		promo.Countries, promo.Affinities = targeting(*promoDTO.Target)
 	} else {
 		promo.AgeFrom = 0
 		promo.AgeUntil = 1000
 	}
 
-	if activeFrom.After(time.Now()) || activeUntil.Before(time.Now()) {
-		promo.Active = false
+	promoSchedule, err := schedule.Marshal(promoDTO.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	promo.Schedule = promoSchedule
+
+	if promoDTO.Limits != nil {
+		limits := promoDTO.Limits.Normalized()
+		promo.UserLimit, promo.UserLimitPeriod = limits.PerUser, limits.PerUserPeriod
+		promo.PeriodLimit, promo.LimitPeriod = limits.PerPeriod, limits.Period
 	}
 
-	company.Promos = append(company.Promos, promo)
-	_, err := s.businessStorage.Update(ctx, company)
+	promo.Status = entity.PromoStatusLive
+	if promoDTO.Draft {
+		promo.Status = entity.PromoStatusDraft
+	}
+
+	// Коды и таргетинг вставляет только promoStorage.Create, в обновление компании их не передаём
+	companyPromo := promo
+	companyPromo.PromoUnique = nil
+	companyPromo.Countries = nil
+	companyPromo.Affinities = nil
+	company.Promos = append(company.Promos, companyPromo)
+	_, err = s.businessStorage.Update(ctx, company)
 	if err != nil {
 		return nil, err
 	}
@@ -122,30 +197,428 @@ func (s *promoService) Create(ctx context.Context, fiberCTX fiber.Ctx, promoDTO
 	return s.promoStorage.Create(ctx, promo)
 }
 
+// targeting is a function that converts included and excluded countries and affinity categories of the target to entities.
+func targeting(target dto.Target) ([]entity.PromoCountry, []entity.PromoAffinity) {
+	var promoCountries []entity.PromoCountry
+	for _, name := range target.Countries {
+		promoCountries = append(promoCountries, entity.PromoCountry{Country: countries.ByName(strings.ToUpper(name))})
+	}
+	for _, name := range target.ExcludedCountries {
+		promoCountries = append(promoCountries, entity.PromoCountry{Country: countries.ByName(strings.ToUpper(name)), Excluded: true})
+	}
+
+	var affinities []entity.PromoAffinity
+	for _, name := range target.AffinityCategories {
+		affinities = append(affinities, entity.PromoAffinity{Name: strings.ToLower(name)})
+	}
+
+	return promoCountries, affinities
+}
+
+// GetByID is a method that returns a promo by id, soft deleted promos are returned too for stats.
 func (s *promoService) GetByID(ctx context.Context, id string) (*entity.Promo, error) {
-	return s.promoStorage.GetByID(ctx, id)
+	promo, err := s.promoStorage.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if promo == nil {
+		return nil, errorz.NotFound
+	}
+
+	return promo, nil
+}
+
+// getOwned is a method that returns a not deleted promo of the company.
+func (s *promoService) getOwned(ctx context.Context, id, companyID string) (*entity.Promo, error) {
+	promo, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if promo.DeletedAt != nil {
+		return nil, errorz.NotFound
+	}
+	if promo.CompanyID != companyID {
+		return nil, errorz.Forbidden
+	}
+
+	return promo, nil
 }
 
-func (s *promoService) GetWithPagination(ctx context.Context, companyId string, dto dto.PromoGetWithPagination) ([]entity.Promo, int64, error) {
-	return s.promoStorage.GetWithPagination(ctx, dto.Limit, dto.Offset, dto.SortBy, companyId, dto.Countries)
+func (s *promoService) Archive(ctx context.Context, id, companyID string) (*entity.Promo, error) {
+	if _, err := s.getOwned(ctx, id, companyID); err != nil {
+		return nil, err
+	}
+
+	if err := s.promoStorage.Archive(ctx, id); err != nil {
+		return nil, err
+	}
+	invalidatePromo(ctx, s.promoCacheStorage, id)
+
+	return s.GetByID(ctx, id)
+}
+
+func (s *promoService) Unarchive(ctx context.Context, id, companyID string) (*entity.Promo, error) {
+	if _, err := s.getOwned(ctx, id, companyID); err != nil {
+		return nil, err
+	}
+
+	if err := s.promoStorage.Unarchive(ctx, id); err != nil {
+		return nil, err
+	}
+	invalidatePromo(ctx, s.promoCacheStorage, id)
+
+	return s.GetByID(ctx, id)
+}
+
+func (s *promoService) Delete(ctx context.Context, id, companyID string) error {
+	if _, err := s.getOwned(ctx, id, companyID); err != nil {
+		return err
+	}
+
+	if err := s.promoStorage.Delete(ctx, id); err != nil {
+		return err
+	}
+	invalidatePromo(ctx, s.promoCacheStorage, id)
+
+	return nil
+}
+
+// Publish is a method that moves a draft to live, it becomes scheduled or live depending on its dates.
+func (s *promoService) Publish(ctx context.Context, id, companyID string) (*entity.Promo, error) {
+	return s.transition(ctx, id, companyID, entity.PromoStatusDraft, entity.PromoStatusLive, func(promo *entity.Promo, now time.Time) error {
+		if !promo.ActiveUntil.After(now) {
+			return fmt.Errorf("%w: the promo has already ended, move active_until first", errorz.Conflict)
+		}
+		if promo.Mode == "COMMON" && promo.UsedCount >= promo.MaxCount {
+			return fmt.Errorf("%w: max_count is used up", errorz.Conflict)
+		}
+		if promo.Mode == "UNIQUE" && !promo.HasCodes {
+			return fmt.Errorf("%w: upload unique codes first", errorz.Conflict)
+		}
+
+		return nil
+	})
+}
+
+// Pause is a method that hides a scheduled or live promo from users and stops activations.
+/*
+ * Activations made before the pause still count towards max_count.
+ */
+func (s *promoService) Pause(ctx context.Context, id, companyID string) (*entity.Promo, error) {
+	return s.transition(ctx, id, companyID, entity.PromoStatusLive, entity.PromoStatusPaused, func(promo *entity.Promo, now time.Time) error {
+		if promo.EffectiveStatus(now) == entity.PromoStatusEnded {
+			return fmt.Errorf("%w: the promo has already ended", errorz.Conflict)
+		}
+
+		return nil
+	})
+}
+
+// Resume is a method that returns a paused promo to live.
+func (s *promoService) Resume(ctx context.Context, id, companyID string) (*entity.Promo, error) {
+	return s.transition(ctx, id, companyID, entity.PromoStatusPaused, entity.PromoStatusLive, func(promo *entity.Promo, now time.Time) error {
+		if !promo.ActiveUntil.After(now) {
+			return fmt.Errorf("%w: the promo has already ended, move active_until first", errorz.Conflict)
+		}
+
+		return nil
+	})
+}
+
+// transition is a method that validates and applies a status change of an owned promo.
+func (s *promoService) transition(ctx context.Context, id, companyID, from, to string, validate func(promo *entity.Promo, now time.Time) error) (*entity.Promo, error) {
+	promo, err := s.getOwned(ctx, id, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if promo.ArchivedAt != nil {
+		return nil, fmt.Errorf("%w: the promo is archived", errorz.Conflict)
+	}
+
+	now := time.Now()
+	if promo.Status != from {
+		return nil, fmt.Errorf("%w: can't move a %s promo to %s", errorz.Conflict, promo.EffectiveStatus(now), to)
+	}
+	if err = validate(promo, now); err != nil {
+		return nil, err
+	}
+
+	if err = s.promoStorage.SetStatus(ctx, id, from, to); err != nil {
+		return nil, err
+	}
+	invalidatePromo(ctx, s.promoCacheStorage, id)
+
+	return s.GetByID(ctx, id)
+}
+
+// Clone is a method that copies targeting, categories and description of a promo into a new draft.
+/*
+ * The draft starts today and lasts as long as the original did. Unique codes are one-time,
+ * so a clone of a UNIQUE promo has none and they have to be uploaded again.
+ */
+func (s *promoService) Clone(ctx context.Context, id, companyID string) (*entity.Promo, error) {
+	original, err := s.getOwned(ctx, id, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Границы по умолчанию как в Create
+	noStart, noEnd := time.Unix(0, 0), time.Unix(8210266876, 0)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	activeUntil := noEnd
+	if original.ActiveFrom.After(noStart) && original.ActiveUntil.Before(noEnd) {
+		activeUntil = today.Add(original.ActiveUntil.Sub(original.ActiveFrom))
+	}
+
+	clone := entity.Promo{
+		CompanyID:       companyID,
+		Active:          original.Mode == "COMMON" && original.MaxCount > 0, // active отражает только остаток кодов
+		Status:          entity.PromoStatusDraft,
+		ActiveFrom:      today,
+		ActiveUntil:     activeUntil,
+		Description:     original.Description,
+		ImageURL:        original.ImageURL,
+		MaxCount:        original.MaxCount,
+		Mode:            original.Mode,
+		PromoCommon:     original.PromoCommon,
+		AgeFrom:         original.AgeFrom,
+		AgeUntil:        original.AgeUntil,
+		Country:         original.Country,
+		CountryOriginal: original.CountryOriginal,
+		Schedule:        original.Schedule,
+		UserLimit:       original.UserLimit,
+		UserLimitPeriod: original.UserLimitPeriod,
+		PeriodLimit:     original.PeriodLimit,
+		LimitPeriod:     original.LimitPeriod,
+	}
+	for _, category := range original.Categories {
+		clone.Categories = append(clone.Categories, entity.Category{Name: category.Name})
+	}
+	for _, country := range original.Countries {
+		clone.Countries = append(clone.Countries, entity.PromoCountry{Country: country.Country, Excluded: country.Excluded})
+	}
+	for _, affinity := range original.Affinities {
+		clone.Affinities = append(clone.Affinities, entity.PromoAffinity{Name: affinity.Name})
+	}
+
+	return s.promoStorage.Create(ctx, clone)
+}
+
+func (s *promoService) GetWithPagination(ctx context.Context, companyId string, dto dto.PromoGetWithPagination) ([]entity.Promo, string, int64, error) {
+	return s.promoStorage.GetWithPagination(ctx, dto.Page, dto.SortBy, companyId, dto.Countries)
 }
 
 func (s *promoService) Update(ctx context.Context, fiberCtx fiber.Ctx, dto dto.PromoUpdate, id string) (*entity.Promo, error) {
-	return s.promoStorage.Update(ctx, fiberCtx, dto, id)
+	promo, err := s.promoStorage.Update(ctx, fiberCtx, dto, id)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidatePromo(ctx, s.promoCacheStorage, id)
+
+	return promo, nil
+}
+
+func (s *promoService) GetFeed(ctx context.Context, user *entity.User, dto dto.PromoFeedRequest, page dto.Page) ([]dto.PromoForUser, string, int64, error) {
+	sortBy := dto.SortBy
+	if sortBy == "" {
+		sortBy = "new"
+	}
+
+	states, nextCursor, total, err := s.promoStorage.GetFeed(ctx, user.Age, user.Country, dto.Category, dto.Active, user.ID, sortBy, feedRanking(), page)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	promos, err := s.withDetails(ctx, states)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	return promos, nextCursor, total, nil
 }
 
-func (s *promoService) GetFeed(ctx context.Context, user *entity.User, dto dto.PromoFeedRequest) ([]dto.PromoForUser, int64, error) {
-	return s.promoStorage.GetFeed(ctx, user.Age, dto.Limit, dto.Offset, user.Country, dto.Category, dto.Active, user.ID)
+// feedRanking is a function that reads the feed ranking weights from the config.
+func feedRanking() dto.FeedRanking {
+	ranking := dto.FeedRanking{
+		Likes:           viper.GetFloat64("feed.ranking.likes"),
+		Comments:        viper.GetFloat64("feed.ranking.comments"),
+		Velocity:        viper.GetFloat64("feed.ranking.velocity"),
+		Recency:         viper.GetFloat64("feed.ranking.recency"),
+		Affinity:        viper.GetFloat64("feed.ranking.affinity"),
+		Stock:           viper.GetFloat64("feed.ranking.stock"),
+		VelocityWindow:  viper.GetDuration("feed.ranking.velocity-window"),
+		RecencyHalfLife: viper.GetDuration("feed.ranking.recency-half-life"),
+	}
+
+	if ranking.VelocityWindow <= 0 {
+		ranking.VelocityWindow = 7 * 24 * time.Hour
+	}
+	if ranking.RecencyHalfLife <= 0 {
+		ranking.RecencyHalfLife = 72 * time.Hour
+	}
+
+	return ranking
 }
 
-func (s *promoService) GetByIdUser(ctx context.Context, promoID, userID string) (dto.PromoForUser, error) {
-	return s.promoStorage.GetByIdUser(ctx, promoID, userID)
+func (s *promoService) GetByIdUser(ctx context.Context, promoID string, user *entity.User) (dto.PromoForUser, error) {
+	states, err := s.promoStorage.GetUserStates(ctx, user.Age, user.Country, user.ID, []string{promoID})
+	if err != nil {
+		return dto.PromoForUser{}, err
+	}
+
+	promos, err := s.withDetails(ctx, states)
+	if err != nil {
+		return dto.PromoForUser{}, err
+	}
+
+	if len(promos) == 0 {
+		return dto.PromoForUser{}, errorz.NotFound
+	}
+
+	return promos[0], nil
 }
 
-func (s *promoService) GetHistory(ctx context.Context, userID string, limit, offset int) ([]dto.PromoForUser, int64, error) {
-	return s.promoStorage.GetHistory(ctx, userID, limit, offset)
+func (s *promoService) GetHistory(ctx context.Context, userID string, page dto.Page) ([]dto.PromoForUser, string, int64, error) {
+	return s.promoStorage.GetHistory(ctx, userID, page)
 }
 
 func (s *promoService) GetStats(ctx context.Context, promoID, companyID string) (dto.PromoStatsResponse, error) {
 	return s.promoStorage.GetStats(ctx, promoID, companyID)
 }
+
+func (s *promoService) Search(ctx context.Context, user *entity.User, search dto.PromoSearchRequest) (dto.PromoSearchResponse, int64, error) {
+	items, facets, total, err := s.promoStorage.Search(ctx, user.Age, user.Country, user.ID, search)
+	if err != nil {
+		return dto.PromoSearchResponse{}, 0, err
+	}
+
+	return dto.PromoSearchResponse{
+		Items:  items,
+		Facets: facets,
+	}, total, nil
+}
+
+// withDetails is a method that overlays the user's flags on promo details, keeping the order of states.
+/*
+ * Details are read through the cache, promos deleted in the meantime are skipped.
+ */
+func (s *promoService) withDetails(ctx context.Context, states []dto.PromoUserState) ([]dto.PromoForUser, error) {
+	promoIDs := make([]string, 0, len(states))
+	for _, state := range states {
+		promoIDs = append(promoIDs, state.PromoID)
+	}
+
+	details, err := s.promoDetails(ctx, promoIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	promos := make([]dto.PromoForUser, 0, len(states))
+	for _, state := range states {
+		promo, ok := details[state.PromoID]
+		if !ok {
+			continue
+		}
+
+		promo.IsLikedByUser = state.IsLiked
+		promo.IsActivatedByUser = state.IsActivated
+		promo.Active = promoActive(promo, now)
+		promo.AvailableNow, promo.NextWindow = schedule.Availability(promo.Schedule, now)
+		promos = append(promos, promo)
+	}
+
+	return promos, nil
+}
+
+// promoActive is a function that reports whether a promo with the given details can be activated at the moment now, as activeExpression does.
+func promoActive(promo dto.PromoForUser, now time.Time) bool {
+	return promo.InStock && promo.Status == entity.PromoStatusLive && !now.Before(promo.ActiveFrom) && now.Before(promo.ActiveUntil)
+}
+
+// promoDetails is a method that returns promo details by ids from the cache, loading and caching the missing ones.
+/*
+ * Cache errors are logged and treated as misses, so Redis being down only costs latency.
+ */
+func (s *promoService) promoDetails(ctx context.Context, promoIDs []string) (map[string]dto.PromoForUser, error) {
+	details, versions, err := s.promoCacheStorage.GetPromos(ctx, promoIDs)
+	if err != nil {
+		logger.Log.Errorf("failed to read promo cache: %v", err)
+	}
+
+	var missing []string
+	for _, promoID := range promoIDs {
+		if _, ok := details[promoID]; !ok {
+			missing = append(missing, promoID)
+		}
+	}
+
+	if len(missing) == 0 {
+		return details, nil
+	}
+
+	loaded, err := s.promoStorage.GetDetails(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	companyIDs := make([]string, 0, len(loaded))
+	for _, promo := range loaded {
+		if !slices.Contains(companyIDs, promo.CompanyID) {
+			companyIDs = append(companyIDs, promo.CompanyID)
+		}
+	}
+
+	names, err := s.companyNames(ctx, companyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range loaded {
+		loaded[i].CompanyName = names[loaded[i].CompanyID]
+		details[loaded[i].PromoID] = loaded[i]
+	}
+
+	if err = s.promoCacheStorage.SetPromos(ctx, loaded, versions); err != nil {
+		logger.Log.Errorf("failed to write promo cache: %v", err)
+	}
+
+	return details, nil
+}
+
+// companyNames is a method that returns company names by ids through the cache.
+func (s *promoService) companyNames(ctx context.Context, companyIDs []string) (map[string]string, error) {
+	names, err := s.promoCacheStorage.GetCompanyNames(ctx, companyIDs)
+	if err != nil {
+		logger.Log.Errorf("failed to read company name cache: %v", err)
+	}
+
+	var missing []string
+	for _, companyID := range companyIDs {
+		if _, ok := names[companyID]; !ok {
+			missing = append(missing, companyID)
+		}
+	}
+
+	if len(missing) == 0 {
+		return names, nil
+	}
+
+	loaded, err := s.businessStorage.GetNames(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	for companyID, name := range loaded {
+		names[companyID] = name
+	}
+
+	if err = s.promoCacheStorage.SetCompanyNames(ctx, loaded); err != nil {
+		logger.Log.Errorf("failed to write company name cache: %v", err)
+	}
+
+	return names, nil
+}
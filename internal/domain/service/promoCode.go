@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"prod/internal/domain/dto"
+)
+
+// defaultLowInventoryThreshold is the number of available codes at which a UNIQUE promo is reported as running low.
+const defaultLowInventoryThreshold = 10
+
+type promoCodeStorage interface {
+	AddCodes(ctx context.Context, promoID, companyID string, codes []string) (dto.PromoCodesAddResponse, error)
+	GetCodes(ctx context.Context, promoID, companyID, status string, limit, offset int) ([]dto.PromoCode, int64, error)
+	RevokeCodes(ctx context.Context, promoID, companyID string, codes []string) (dto.PromoCodesRevokeResponse, error)
+	GetLowInventory(ctx context.Context, companyID string, threshold int) ([]dto.PromoInventory, error)
+	CheckPromo(ctx context.Context, promoID, companyID string) error
+}
+
+type promoCodeService struct {
+	promoCodeStorage  promoCodeStorage
+	promoCacheStorage promoCacheStorage
+}
+
+func NewPromoCodeService(promoCodeStorage promoCodeStorage, promoCacheStorage promoCacheStorage) *promoCodeService {
+	return &promoCodeService{
+		promoCodeStorage:  promoCodeStorage,
+		promoCacheStorage: promoCacheStorage,
+	}
+}
+
+func (s *promoCodeService) AddCodes(ctx context.Context, companyID string, request dto.PromoCodesAdd) (dto.PromoCodesAddResponse, error) {
+	res, err := s.promoCodeStorage.AddCodes(ctx, request.ID, companyID, request.Codes)
+	if err != nil {
+		return dto.PromoCodesAddResponse{}, err
+	}
+
+	if res.Added > 0 {
+		invalidatePromo(ctx, s.promoCacheStorage, request.ID)
+	}
+
+	return res, nil
+}
+
+func (s *promoCodeService) GetCodes(ctx context.Context, companyID string, request dto.PromoCodesList) ([]dto.PromoCode, int64, error) {
+	if request.Limit == 0 {
+		request.Limit = 100
+	}
+
+	return s.promoCodeStorage.GetCodes(ctx, request.ID, companyID, request.Status, request.Limit, request.Offset)
+}
+
+func (s *promoCodeService) RevokeCodes(ctx context.Context, companyID string, request dto.PromoCodesRevoke) (dto.PromoCodesRevokeResponse, error) {
+	res, err := s.promoCodeStorage.RevokeCodes(ctx, request.ID, companyID, request.Codes)
+	if err != nil {
+		return dto.PromoCodesRevokeResponse{}, err
+	}
+
+	if res.Revoked > 0 {
+		invalidatePromo(ctx, s.promoCacheStorage, request.ID)
+	}
+
+	return res, nil
+}
+
+func (s *promoCodeService) GetLowInventory(ctx context.Context, companyID string, request dto.PromoCodesLowInventory) (dto.PromoCodesLowInventoryResponse, error) {
+	threshold := request.Threshold
+	if threshold == 0 {
+		threshold = defaultLowInventoryThreshold
+	}
+
+	promos, err := s.promoCodeStorage.GetLowInventory(ctx, companyID, threshold)
+	if err != nil {
+		return dto.PromoCodesLowInventoryResponse{}, err
+	}
+
+	return dto.PromoCodesLowInventoryResponse{
+		Threshold: threshold,
+		Count:     len(promos),
+		Promos:    promos,
+	}, nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/biter777/countries"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/common/errorz"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// realtimeBufferSize is a number of updates waiting to be sent to a client, a client that falls behind it is dropped.
+	realtimeBufferSize = 32
+	// RealtimeMaxPromos is a number of promos one connection can subscribe to.
+	RealtimeMaxPromos = 100
+	// defaultRealtimeMaxConnections is used when realtime.max-connections is not set.
+	defaultRealtimeMaxConnections = 10000
+	realtimeResubscribeDelay      = time.Second
+)
+
+var ErrTooManyConnections = errors.New("too many realtime connections")
+
+type promoCounterStorage interface {
+	GetCounters(ctx context.Context, promoID string) (dto.PromoLiveUpdate, error)
+	GetVisiblePromoIDs(ctx context.Context, age int, country countries.CountryCode, userID string, promoIDs []string) ([]string, error)
+}
+
+type promoUpdateStorage interface {
+	Publish(ctx context.Context, update dto.PromoLiveUpdate) error
+	Subscribe(ctx context.Context, handle func(update dto.PromoLiveUpdate)) error
+}
+
+// RealtimeClient is a connection subscribed to updates of some promos.
+type RealtimeClient struct {
+	promoIDs []string
+	updates  chan dto.PromoLiveUpdate
+	dropped  chan struct{}
+	drop     sync.Once
+}
+
+// Updates is a method that returns the channel of updates of the client's promos.
+func (c *RealtimeClient) Updates() <-chan dto.PromoLiveUpdate {
+	return c.updates
+}
+
+// Dropped is a method that returns a channel closed when the client fell behind and was unsubscribed.
+func (c *RealtimeClient) Dropped() <-chan struct{} {
+	return c.dropped
+}
+
+// RealtimeHub is a fan-out of promo updates to the clients of this instance.
+/*
+ * Updates go through Redis pub/sub: the instance that publishes a domain event from the outbox sends the new
+ * counters of the promo to the channel, and every instance passes them to its own clients. Sending never blocks:
+ * a client whose buffer is full is dropped and has to reconnect, slow clients don't hold the others.
+ */
+type RealtimeHub struct {
+	counterStorage promoCounterStorage
+	updateStorage  promoUpdateStorage
+	maxConnections int64
+
+	mu          sync.RWMutex
+	clients     map[string]map[*RealtimeClient]struct{}
+	connections atomic.Int64
+}
+
+func NewRealtimeHub(counterStorage promoCounterStorage, updateStorage promoUpdateStorage, maxConnections int64) *RealtimeHub {
+	if maxConnections <= 0 {
+		maxConnections = defaultRealtimeMaxConnections
+	}
+
+	return &RealtimeHub{
+		counterStorage: counterStorage,
+		updateStorage:  updateStorage,
+		maxConnections: maxConnections,
+		clients:        make(map[string]map[*RealtimeClient]struct{}),
+	}
+}
+
+// Subscribe is a method that registers a client of the promos, it must be passed to Unsubscribe when it disconnects.
+/*
+ * Only promos the user can see in the feed are subscribed, the others are dropped, so counters of drafts
+ * and of promos targeted at other users don't leak. Returns errorz.NotFound if none of them is visible.
+ */
+func (h *RealtimeHub) Subscribe(ctx context.Context, user *entity.User, promoIDs []string) (*RealtimeClient, error) {
+	promoIDs, err := h.counterStorage.GetVisiblePromoIDs(ctx, user.Age, user.Country, user.ID, promoIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(promoIDs) == 0 {
+		return nil, errorz.NotFound
+	}
+
+	if h.connections.Add(1) > h.maxConnections {
+		h.connections.Add(-1)
+		return nil, ErrTooManyConnections
+	}
+
+	client := &RealtimeClient{
+		promoIDs: promoIDs,
+		updates:  make(chan dto.PromoLiveUpdate, realtimeBufferSize),
+		dropped:  make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, promoID := range promoIDs {
+		if h.clients[promoID] == nil {
+			h.clients[promoID] = make(map[*RealtimeClient]struct{})
+		}
+		h.clients[promoID][client] = struct{}{}
+	}
+
+	return client, nil
+}
+
+// Unsubscribe is a method that removes a client, removing it again is a no-op.
+func (h *RealtimeHub) Unsubscribe(client *RealtimeClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	removed := false
+	for _, promoID := range client.promoIDs {
+		if _, ok := h.clients[promoID][client]; !ok {
+			continue
+		}
+		removed = true
+		delete(h.clients[promoID], client)
+		if len(h.clients[promoID]) == 0 {
+			delete(h.clients, promoID)
+		}
+	}
+
+	if removed {
+		h.connections.Add(-1)
+	}
+}
+
+// HandleEvent is a method that publishes the new counters of the promo of a domain event, it is subscribed to the event bus.
+func (h *RealtimeHub) HandleEvent(ctx context.Context, event dto.DomainEvent) error {
+	var (
+		updateType string
+		commentID  *string
+	)
+
+	switch event.Type {
+	case dto.EventLikeToggled:
+		updateType = dto.PromoLiveUpdateLike
+	case dto.EventCommentAdded:
+		var payload dto.CommentAddedEvent
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return err
+		}
+		updateType, commentID = dto.PromoLiveUpdateComment, &payload.CommentID
+	case dto.EventPromoActivated, dto.EventPromoExhausted:
+		updateType = dto.PromoLiveUpdateStock
+	default:
+		return nil
+	}
+
+	update, err := h.counterStorage.GetCounters(ctx, event.PromoID)
+	if errors.Is(err, errorz.NotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	update.Type = updateType
+	update.CommentID = commentID
+	update.At = time.Now().UTC()
+
+	return h.updateStorage.Publish(ctx, update)
+}
+
+// Run is a method that passes updates from all instances to the clients of this one until ctx is done.
+func (h *RealtimeHub) Run(ctx context.Context) {
+	for {
+		err := h.updateStorage.Subscribe(ctx, h.broadcast)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Log.Errorf("promo updates subscription failed: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(realtimeResubscribeDelay):
+		}
+	}
+}
+
+func (h *RealtimeHub) broadcast(update dto.PromoLiveUpdate) {
+	var slow []*RealtimeClient
+
+	h.mu.RLock()
+	for client := range h.clients[update.PromoID] {
+		select {
+		case client.updates <- update:
+		default:
+			slow = append(slow, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range slow {
+		h.Unsubscribe(client)
+		client.drop.Do(func() { close(client.dropped) })
+	}
+}
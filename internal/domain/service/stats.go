@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/utils/schedule"
+	"time"
+)
+
+const (
+	// defaultStatsTop is a number of promos in each top of the dashboard by default.
+	defaultStatsTop = 5
+	// defaultStatsRefreshInterval is used when stats.refresh-interval is not set.
+	defaultStatsRefreshInterval = time.Minute
+)
+
+type statsStorage interface {
+	Refresh(ctx context.Context) error
+	GetCompanyStats(ctx context.Context, companyID string, from, to time.Time, top int) (dto.CompanyStatsResponse, error)
+}
+
+type statsService struct {
+	statsStorage statsStorage
+}
+
+func NewStatsService(statsStorage statsStorage) *statsService {
+	return &statsService{statsStorage: statsStorage}
+}
+
+// Run is a method that refreshes the dashboard rollups every interval until ctx is done.
+func (s *statsService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultStatsRefreshInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.statsStorage.Refresh(ctx); err != nil {
+			logger.Log.Errorf("failed to refresh stats rollups: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetCompanyStats is a method that returns the dashboard of the company, the range is in whole days (UTC).
+func (s *statsService) GetCompanyStats(ctx context.Context, companyID string, request dto.CompanyStatsRequest) (dto.CompanyStatsResponse, error) {
+	var err error
+
+	to := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if request.To != "" {
+		if to, err = schedule.ParseBound(request.To); err != nil {
+			return dto.CompanyStatsResponse{}, fmt.Errorf("%w: to is not a date", ErrInvalidAnalyticsRange)
+		}
+	}
+
+	from := to.AddDate(0, 0, -30)
+	if request.From != "" {
+		if from, err = schedule.ParseBound(request.From); err != nil {
+			return dto.CompanyStatsResponse{}, fmt.Errorf("%w: from is not a date", ErrInvalidAnalyticsRange)
+		}
+	}
+
+	from, to = from.UTC().Truncate(24*time.Hour), to.UTC().Truncate(24*time.Hour)
+	if !from.Before(to) {
+		return dto.CompanyStatsResponse{}, fmt.Errorf("%w: from must be before to", ErrInvalidAnalyticsRange)
+	}
+
+	top := request.Top
+	if top == 0 {
+		top = defaultStatsTop
+	}
+
+	return s.statsStorage.GetCompanyStats(ctx, companyID, from, to, top)
+}
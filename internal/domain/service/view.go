@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/entity"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// viewBufferSize is a number of views waiting to be written, views over it are dropped.
+	viewBufferSize = 10000
+	// viewFlushSize is a number of views that are written without waiting for the next tick.
+	viewFlushSize = 1000
+	// defaultViewFlushInterval is used when views.flush-interval is not set.
+	defaultViewFlushInterval = 5 * time.Second
+	// viewFlushTimeout bounds writing one batch, also after shutdown.
+	viewFlushTimeout = 5 * time.Second
+)
+
+type viewStorage interface {
+	InsertViews(ctx context.Context, views []entity.PromoView) error
+}
+
+// ViewRecorder is a buffered writer of promo impressions and views.
+/*
+ * Record never blocks the request: views go to a channel that Run writes in batches,
+ * when the buffer is full they are dropped and the number of dropped ones is logged on the next flush.
+ */
+type ViewRecorder struct {
+	viewStorage viewStorage
+	views       chan entity.PromoView
+	dropped     atomic.Int64
+}
+
+func NewViewRecorder(viewStorage viewStorage) *ViewRecorder {
+	return &ViewRecorder{
+		viewStorage: viewStorage,
+		views:       make(chan entity.PromoView, viewBufferSize),
+	}
+}
+
+// Record is a method that queues a view of kind of the promos by the user.
+func (r *ViewRecorder) Record(userID, kind string, promoIDs ...string) {
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+
+	for _, promoID := range promoIDs {
+		select {
+		case r.views <- entity.PromoView{PromoID: promoID, UserID: userID, Day: day, Kind: kind}:
+		default:
+			r.dropped.Add(1)
+		}
+	}
+}
+
+// Run is a method that writes queued views every interval or once viewFlushSize of them are queued, until ctx is done.
+/*
+ * On shutdown the views still queued are written too, each batch within viewFlushTimeout.
+ */
+func (r *ViewRecorder) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultViewFlushInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	batch := make([]entity.PromoView, 0, viewFlushSize)
+	flush := func() {
+		if dropped := r.dropped.Swap(0); dropped > 0 {
+			logger.Log.Warnf("dropped %d promo views, the buffer is full", dropped)
+		}
+		if len(batch) == 0 {
+			return
+		}
+
+		// Запись не отменяется вместе с ctx, чтобы последние пачки ушли и после остановки, но ограничена по времени
+		flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), viewFlushTimeout)
+		defer cancel()
+
+		if err := r.viewStorage.InsertViews(flushCtx, batch); err != nil {
+			logger.Log.Errorf("failed to record %d promo views: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case view := <-r.views:
+					batch = append(batch, view)
+					if len(batch) >= viewFlushSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		case view := <-r.views:
+			batch = append(batch, view)
+			if len(batch) >= viewFlushSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
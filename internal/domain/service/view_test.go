@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/entity"
+	"testing"
+	"time"
+)
+
+type viewStorageFunc func(ctx context.Context, views []entity.PromoView) error
+
+func (f viewStorageFunc) InsertViews(ctx context.Context, views []entity.PromoView) error {
+	return f(ctx, views)
+}
+
+func TestViewRecorderDrainsOnShutdown(t *testing.T) {
+	logger.New(false, "")
+
+	var written int
+	recorder := NewViewRecorder(viewStorageFunc(func(ctx context.Context, views []entity.PromoView) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("views are written without a timeout")
+		}
+		if ctx.Err() != nil {
+			t.Errorf("views are written with a done context: %v", ctx.Err())
+		}
+		written += len(views)
+		return nil
+	}))
+
+	// Больше одной пачки ждут в очереди к моменту остановки
+	for i := 0; i < 2*viewFlushSize+500; i++ {
+		recorder.Record("user", entity.ViewKindImpression, "promo")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	recorder.Run(ctx, time.Hour)
+
+	if written != 2*viewFlushSize+500 {
+		t.Fatalf("got %d views written, want %d", written, 2*viewFlushSize+500)
+	}
+}
@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v3/client"
+	"net"
+	"net/url"
+	"prod/internal/adapters/logger"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/entity"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	webhookMaxAttempts  = 10               // a delivery is dead after this many failed attempts
+	webhookFirstRetry   = 30 * time.Second // delay after the first failure, doubled after each next one
+	webhookMaxRetry     = 6 * time.Hour
+	webhookTimeout      = 10 * time.Second
+	webhookLease        = time.Minute // longer than webhookTimeout, so a delivery is never sent twice at once
+	webhookBatchSize    = 50
+	webhookConcurrency  = 10
+	webhookErrorMaxSize = 500
+
+	// defaultWebhookInterval is used when webhooks.dispatch-interval is not set.
+	defaultWebhookInterval = 5 * time.Second
+)
+
+var ErrInvalidWebhookURL = errors.New("invalid webhook url")
+
+type webhookStorage interface {
+	Create(ctx context.Context, webhook entity.Webhook) (*entity.Webhook, error)
+	List(ctx context.Context, companyID string) ([]entity.Webhook, error)
+	GetByID(ctx context.Context, webhookID, companyID string) (*entity.Webhook, error)
+	Update(ctx context.Context, webhook *entity.Webhook) error
+	Delete(ctx context.Context, webhookID string) error
+	Enqueue(ctx context.Context, promoID string, payload dto.WebhookPayload) error
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]dto.WebhookDispatch, error)
+	RecordAttempt(ctx context.Context, deliveryID, status string, nextAttemptAt time.Time, lastStatus *int, lastError *string) error
+	ListDeliveries(ctx context.Context, webhookID, status string, page dto.Page) ([]entity.WebhookDelivery, string, int64, error)
+	Redeliver(ctx context.Context, webhookID, deliveryID string) (*entity.WebhookDelivery, error)
+}
+
+type webhookService struct {
+	webhookStorage webhookStorage
+}
+
+func NewWebhookService(webhookStorage webhookStorage) *webhookService {
+	return &webhookService{webhookStorage: webhookStorage}
+}
+
+// Create is a method that registers a webhook of the company with a new secret.
+func (s *webhookService) Create(ctx context.Context, companyID string, request dto.WebhookCreate) (*entity.Webhook, error) {
+	if err := validateWebhookURL(request.URL); err != nil {
+		return nil, err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.webhookStorage.Create(ctx, entity.Webhook{
+		CompanyID: companyID,
+		URL:       request.URL,
+		Secret:    secret,
+		Events:    joinEvents(request.Events),
+		Active:    true,
+	})
+}
+
+func (s *webhookService) List(ctx context.Context, companyID string) ([]entity.Webhook, error) {
+	return s.webhookStorage.List(ctx, companyID)
+}
+
+func (s *webhookService) GetByID(ctx context.Context, webhookID, companyID string) (*entity.Webhook, error) {
+	return s.webhookStorage.GetByID(ctx, webhookID, companyID)
+}
+
+// Update is a method that changes the url, events or active flag of a webhook of the company.
+func (s *webhookService) Update(ctx context.Context, companyID string, request dto.WebhookUpdate) (*entity.Webhook, error) {
+	webhook, err := s.webhookStorage.GetByID(ctx, request.ID, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.URL != nil {
+		if err = validateWebhookURL(*request.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *request.URL
+	}
+	if request.Events != nil {
+		webhook.Events = joinEvents(request.Events)
+	}
+	if request.Active != nil {
+		webhook.Active = *request.Active
+	}
+	webhook.UpdatedAt = time.Now()
+
+	return webhook, s.webhookStorage.Update(ctx, webhook)
+}
+
+// RotateSecret is a method that replaces the secret of a webhook of the company, pending deliveries are signed with the new one.
+func (s *webhookService) RotateSecret(ctx context.Context, webhookID, companyID string) (*entity.Webhook, error) {
+	webhook, err := s.webhookStorage.GetByID(ctx, webhookID, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook.Secret, err = newWebhookSecret(); err != nil {
+		return nil, err
+	}
+	webhook.UpdatedAt = time.Now()
+
+	return webhook, s.webhookStorage.Update(ctx, webhook)
+}
+
+func (s *webhookService) Delete(ctx context.Context, webhookID, companyID string) error {
+	if _, err := s.webhookStorage.GetByID(ctx, webhookID, companyID); err != nil {
+		return err
+	}
+
+	return s.webhookStorage.Delete(ctx, webhookID)
+}
+
+// Deliveries is a method that returns the delivery log of a webhook of the company.
+func (s *webhookService) Deliveries(ctx context.Context, companyID string, request dto.WebhookDeliveriesRequest, page dto.Page) ([]entity.WebhookDelivery, string, int64, error) {
+	if _, err := s.webhookStorage.GetByID(ctx, request.ID, companyID); err != nil {
+		return nil, "", 0, err
+	}
+
+	return s.webhookStorage.ListDeliveries(ctx, request.ID, request.Status, page)
+}
+
+// Redeliver is a method that queues a logged delivery of a webhook of the company again, dead ones included.
+func (s *webhookService) Redeliver(ctx context.Context, companyID string, request dto.WebhookDeliveryRedeliver) (*entity.WebhookDelivery, error) {
+	if _, err := s.webhookStorage.GetByID(ctx, request.ID, companyID); err != nil {
+		return nil, err
+	}
+
+	return s.webhookStorage.Redeliver(ctx, request.ID, request.DeliveryID)
+}
+
+// HandleEvent is a method that queues a domain event for the webhooks of the company of its promo.
+/*
+ * It is subscribed to the event bus. The webhook event has the ID of the domain event, so an event published again
+ * after a failure doesn't queue a second delivery. Unlikes are not sent.
+ */
+func (s *webhookService) HandleEvent(ctx context.Context, event dto.DomainEvent) error {
+	var (
+		webhookEvent string
+		data         interface{}
+	)
+
+	switch event.Type {
+	case dto.EventPromoActivated:
+		var payload dto.PromoActivatedEvent
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return err
+		}
+		webhookEvent = dto.WebhookEventActivated
+		data = dto.WebhookActivatedData{PromoID: event.PromoID, ActivatedAt: payload.ActivatedAt.UTC(), Country: payload.Country}
+	case dto.EventPromoExhausted:
+		var payload dto.PromoExhaustedEvent
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return err
+		}
+		webhookEvent = dto.WebhookEventExhausted
+		data = dto.WebhookExhaustedData{PromoID: event.PromoID, ExhaustedAt: payload.ExhaustedAt.UTC()}
+	case dto.EventCommentAdded:
+		var payload dto.CommentAddedEvent
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return err
+		}
+		// Официальные ответы пишет сама компания, ей о них не сообщаем
+		if payload.Official {
+			return nil
+		}
+		webhookEvent = dto.WebhookEventCommented
+		data = dto.WebhookCommentedData{PromoID: event.PromoID, CommentID: payload.CommentID, ParentID: payload.ParentID, Text: payload.Text, CreatedAt: payload.CreatedAt.UTC()}
+	case dto.EventLikeToggled:
+		var payload dto.LikeToggledEvent
+		if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+			return err
+		}
+		if !payload.Liked {
+			return nil
+		}
+		webhookEvent = dto.WebhookEventLiked
+		data = dto.WebhookLikedData{PromoID: event.PromoID, LikedAt: payload.ToggledAt.UTC()}
+	default:
+		return nil
+	}
+
+	return s.webhookStorage.Enqueue(ctx, event.PromoID, dto.WebhookPayload{
+		ID:        event.ID,
+		Type:      webhookEvent,
+		CreatedAt: event.CreatedAt.UTC(),
+		Data:      data,
+	})
+}
+
+// Run is a method that sends due deliveries every interval until ctx is done.
+func (s *webhookService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultWebhookInterval
+	}
+
+	cc := client.New()
+	cc.SetTimeout(webhookTimeout)
+	cc.SetDial(dialPublic)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Пока очередь полная, следующая пачка берётся сразу
+		for {
+			dispatches, err := s.webhookStorage.ClaimDue(ctx, webhookBatchSize, webhookLease)
+			if err != nil {
+				logger.Log.Errorf("failed to claim webhook deliveries: %v", err)
+				break
+			}
+
+			var wg sync.WaitGroup
+			slots := make(chan struct{}, webhookConcurrency)
+			for _, dispatch := range dispatches {
+				wg.Add(1)
+				slots <- struct{}{}
+				go func() {
+					defer func() { <-slots; wg.Done() }()
+					s.deliver(ctx, cc, dispatch)
+				}()
+			}
+			wg.Wait()
+
+			if len(dispatches) < webhookBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver is a method that posts a claimed delivery and records the result.
+func (s *webhookService) deliver(ctx context.Context, cc *client.Client, dispatch dto.WebhookDispatch) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	resp, err := cc.R().
+		SetContext(ctx).
+		SetHeaders(map[string]string{
+			"Content-Type":        "application/json",
+			"User-Agent":          "promo-webhooks/1",
+			"X-Webhook-Id":        dispatch.EventID,
+			"X-Webhook-Event":     dispatch.Event,
+			"X-Webhook-Timestamp": timestamp,
+			"X-Webhook-Signature": "sha256=" + signWebhook(dispatch.Secret, timestamp, dispatch.Payload),
+		}).
+		SetRawBody([]byte(dispatch.Payload)).
+		Post(dispatch.URL)
+
+	var lastStatus *int
+	var lastError *string
+	if err != nil {
+		message := truncate(err.Error(), webhookErrorMaxSize)
+		lastError = &message
+	} else {
+		status := resp.StatusCode()
+		lastStatus = &status
+		// Тело ответа не сохраняется: компания видит его в истории доставок
+		if status < 200 || status > 299 {
+			message := "unexpected status " + strconv.Itoa(status)
+			lastError = &message
+		}
+		resp.Close()
+	}
+
+	status, next := "delivered", time.Now()
+	if lastError != nil {
+		status, next = nextWebhookAttempt(dispatch.Attempts + 1)
+	}
+
+	// Результат записывается и при остановке, иначе доставка повторится только после аренды
+	if err = s.webhookStorage.RecordAttempt(context.Background(), dispatch.DeliveryID, status, next, lastStatus, lastError); err != nil {
+		logger.Log.Errorf("failed to record webhook delivery %s: %v", dispatch.DeliveryID, err)
+	}
+}
+
+// nextWebhookAttempt is a function that returns the status and time of the next attempt after attempts failed ones.
+func nextWebhookAttempt(attempts int) (string, time.Time) {
+	if attempts >= webhookMaxAttempts {
+		return "dead", time.Now()
+	}
+
+	delay := webhookFirstRetry << (attempts - 1)
+	if delay > webhookMaxRetry {
+		delay = webhookMaxRetry
+	}
+
+	return "pending", time.Now().Add(delay)
+}
+
+// signWebhook is a function that returns the hex HMAC-SHA256 of "timestamp.payload" with the secret.
+func signWebhook(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// joinEvents is a function that returns the distinct events in the order of dto.WebhookEvents, comma separated.
+func joinEvents(events []string) string {
+	var joined []string
+	for _, event := range dto.WebhookEvents {
+		if slices.Contains(events, event) {
+			joined = append(joined, event)
+		}
+	}
+
+	return strings.Join(joined, ",")
+}
+
+// validateWebhookURL is a function that rejects non-HTTP urls and urls of this network.
+/*
+ * Hosts are resolved here only to report a mistake early, a name that resolves to a private address later
+ * is stopped by dialPublic when a delivery is sent.
+ */
+func validateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("%w: must be an http or https url", ErrInvalidWebhookURL)
+	}
+
+	ips, err := net.LookupIP(parsed.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: %s doesn't resolve", ErrInvalidWebhookURL, parsed.Hostname())
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return fmt.Errorf("%w: %s is not a public address", ErrInvalidWebhookURL, parsed.Hostname())
+		}
+	}
+
+	return nil
+}
+
+// nonPublicNetworks are ranges that net.IP has no predicate for: "this network", CGNAT, benchmarking and NAT64.
+var nonPublicNetworks = []*net.IPNet{
+	mustCIDR("0.0.0.0/8"),
+	mustCIDR("100.64.0.0/10"),
+	mustCIDR("192.0.0.0/24"),
+	mustCIDR("198.18.0.0/15"),
+	mustCIDR("64:ff9b::/96"),
+}
+
+func mustCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return network
+}
+
+// isPublicIP is a function that reports whether the address is outside loopback, private, link-local, CGNAT and other special ranges.
+func isPublicIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// dialPublic is a fasthttp.DialFunc that connects only to public addresses of webhook hosts.
+/*
+ * The address is checked right before connecting, after DNS resolution, so a host that resolves to a public address
+ * at registration and to an internal one later (DNS rebinding) is refused. Every connection goes through it,
+ * so do connections to redirect targets if redirects are ever followed.
+ */
+func dialPublic(addr string) (net.Conn, error) {
+	dialer := net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicIP(net.ParseIP(host)) {
+				return fmt.Errorf("%w: not a public address", ErrInvalidWebhookURL)
+			}
+
+			return nil
+		},
+	}
+
+	return dialer.Dial("tcp", addr)
+}
+
+func truncate(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+
+	return strings.ToValidUTF8(s[:size], "")
+}
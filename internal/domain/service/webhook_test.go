@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{ip: "127.0.0.1", want: false},
+		{ip: "::1", want: false},
+		{ip: "10.1.2.3", want: false},
+		{ip: "172.16.0.1", want: false},
+		{ip: "192.168.1.1", want: false},
+		{ip: "169.254.169.254", want: false},
+		{ip: "fe80::1", want: false},
+		{ip: "fd00::1", want: false},
+		{ip: "100.64.0.1", want: false},
+		{ip: "100.127.255.254", want: false},
+		{ip: "0.0.0.0", want: false},
+		{ip: "0.1.2.3", want: false},
+		{ip: "::ffff:127.0.0.1", want: false},
+		{ip: "::ffff:169.254.169.254", want: false},
+		{ip: "64:ff9b::a9fe:a9fe", want: false},
+		{ip: "224.0.0.1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialPublicRefusesLocalAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// localhost резолвится только при подключении, как имя после DNS rebinding
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	for _, addr := range []string{listener.Addr().String(), net.JoinHostPort("localhost", port)} {
+		conn, err := dialPublic(addr)
+		if err == nil {
+			conn.Close()
+			t.Fatalf("dialed %s", addr)
+		}
+		if !errors.Is(err, ErrInvalidWebhookURL) {
+			t.Fatalf("dial %s: got %v, want ErrInvalidWebhookURL", addr, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package codegen
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultAlphabet has no characters that are easy to confuse when read aloud or typed (0/O, 1/I/L).
+const DefaultAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+var ErrInvalidSpec = errors.New("invalid code generator spec")
+
+// Spec is a description of codes to generate: Prefix + Length random characters of Alphabet + optional check character.
+/*
+ * The check character is computed over the random part only, so the prefix can be changed without reissuing codes.
+ */
+type Spec struct {
+	Count    int
+	Alphabet string
+	Length   int
+	Prefix   string
+	Checksum bool
+	MaxTotal int // max length of a whole code, 0 - unlimited
+}
+
+// Generate is a function that returns Count distinct random codes.
+/*
+ * The space of codes must be at least twice as large as Count, otherwise collisions make generation slow
+ * and the codes easy to guess, so such specs are rejected with ErrInvalidSpec.
+ */
+func Generate(spec Spec) ([]string, error) {
+	if spec.Alphabet == "" {
+		spec.Alphabet = DefaultAlphabet
+	}
+	alphabet := []rune(spec.Alphabet)
+
+	if err := validate(spec, alphabet); err != nil {
+		return nil, err
+	}
+
+	max := big.NewInt(int64(len(alphabet)))
+	seen := make(map[string]struct{}, spec.Count)
+	codes := make([]string, 0, spec.Count)
+
+	var body strings.Builder
+	for len(codes) < spec.Count {
+		body.Reset()
+		body.WriteString(spec.Prefix)
+		for i := 0; i < spec.Length; i++ {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, err
+			}
+			body.WriteRune(alphabet[n.Int64()])
+		}
+
+		code := body.String()
+		if _, ok := seen[code]; ok {
+			continue
+		}
+		seen[code] = struct{}{}
+
+		if spec.Checksum {
+			code += string(CheckChar(code[len(spec.Prefix):], alphabet))
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+func validate(spec Spec, alphabet []rune) error {
+	if spec.Count < 1 || spec.Length < 1 {
+		return fmt.Errorf("%w: count and length must be positive", ErrInvalidSpec)
+	}
+
+	unique := make(map[rune]struct{}, len(alphabet))
+	for _, r := range alphabet {
+		unique[r] = struct{}{}
+	}
+	if len(unique) != len(alphabet) || len(alphabet) < 2 {
+		return fmt.Errorf("%w: alphabet must have at least 2 distinct characters", ErrInvalidSpec)
+	}
+
+	total := utf8.RuneCountInString(spec.Prefix) + spec.Length
+	if spec.Checksum {
+		total++
+	}
+	if spec.MaxTotal > 0 && total > spec.MaxTotal {
+		return fmt.Errorf("%w: codes would be %d characters long, at most %d allowed", ErrInvalidSpec, total, spec.MaxTotal)
+	}
+
+	// |alphabet|^length >= 2 * count
+	capacity := new(big.Int).Exp(big.NewInt(int64(len(alphabet))), big.NewInt(int64(spec.Length)), nil)
+	if capacity.Cmp(big.NewInt(2*int64(spec.Count))) < 0 {
+		return fmt.Errorf("%w: only %s codes of length %d over %d characters, too few for %d codes", ErrInvalidSpec, capacity, spec.Length, len(alphabet), spec.Count)
+	}
+
+	return nil
+}
+
+// CheckChar is a function that returns the Luhn mod N check character of s over the alphabet.
+/*
+ * With the "0123456789" alphabet it's the usual Luhn digit. Characters outside the alphabet are ignored.
+ * Over an alphabet of odd length a doubled code point is taken mod N instead of summing its digits in base N:
+ * summing maps two code points to the same addend there, and a substitution of one with the other went unnoticed.
+ */
+func CheckChar(s string, alphabet []rune) rune {
+	n := len(alphabet)
+	index := make(map[rune]int, n)
+	for i, r := range alphabet {
+		index[r] = i
+	}
+
+	runes := []rune(s)
+	factor, sum := 2, 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		codePoint, ok := index[runes[i]]
+		if !ok {
+			continue
+		}
+
+		addend := factor * codePoint
+		if n%2 == 0 {
+			addend = addend/n + addend%n
+		} else {
+			// Умножение на 2 по нечетному модулю взаимно однозначно
+			addend %= n
+		}
+		sum += addend
+
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+	}
+
+	return alphabet[(n-sum%n)%n]
+}
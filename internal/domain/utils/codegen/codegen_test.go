@@ -0,0 +1,168 @@
+package codegen_test
+
+import (
+	"errors"
+	"prod/internal/domain/utils/codegen"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestGenerateUnique(t *testing.T) {
+	tests := []struct {
+		name string
+		spec codegen.Spec
+	}{
+		{name: "default alphabet", spec: codegen.Spec{Count: 2000, Length: 6}},
+		{name: "prefix and checksum", spec: codegen.Spec{Count: 2000, Length: 6, Prefix: "SALE-", Checksum: true}},
+		// 2^12 = 4096 кодов на 2000 - почти половина пространства, коллизий много
+		{name: "dense space", spec: codegen.Spec{Count: 2000, Alphabet: "AB", Length: 12}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes, err := codegen.Generate(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(codes) != tt.spec.Count {
+				t.Fatalf("got %d codes, want %d", len(codes), tt.spec.Count)
+			}
+
+			alphabet := tt.spec.Alphabet
+			if alphabet == "" {
+				alphabet = codegen.DefaultAlphabet
+			}
+
+			want := utf8.RuneCountInString(tt.spec.Prefix) + tt.spec.Length
+			if tt.spec.Checksum {
+				want++
+			}
+
+			seen := make(map[string]struct{}, len(codes))
+			for _, code := range codes {
+				if _, ok := seen[code]; ok {
+					t.Fatalf("duplicate code %q", code)
+				}
+				seen[code] = struct{}{}
+
+				if !strings.HasPrefix(code, tt.spec.Prefix) {
+					t.Fatalf("code %q has no prefix %q", code, tt.spec.Prefix)
+				}
+				if utf8.RuneCountInString(code) != want {
+					t.Fatalf("code %q is not %d characters long", code, want)
+				}
+
+				body := []rune(strings.TrimPrefix(code, tt.spec.Prefix))
+				if tt.spec.Checksum {
+					check := body[len(body)-1]
+					body = body[:len(body)-1]
+					if got := codegen.CheckChar(string(body), []rune(alphabet)); got != check {
+						t.Fatalf("code %q has check character %q, want %q", code, check, got)
+					}
+				}
+				for _, r := range body {
+					if !strings.ContainsRune(alphabet, r) {
+						t.Fatalf("code %q has %q outside the alphabet", code, r)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateInvalidSpec(t *testing.T) {
+	tests := []struct {
+		name string
+		spec codegen.Spec
+	}{
+		{name: "zero count", spec: codegen.Spec{Count: 0, Length: 6}},
+		{name: "zero length", spec: codegen.Spec{Count: 1, Length: 0}},
+		{name: "one character alphabet", spec: codegen.Spec{Count: 1, Alphabet: "A", Length: 6}},
+		{name: "repeated characters", spec: codegen.Spec{Count: 1, Alphabet: "ABA", Length: 6}},
+		{name: "longer than max total", spec: codegen.Spec{Count: 1, Length: 6, Prefix: "SALE-", MaxTotal: 10}},
+		{name: "checksum over max total", spec: codegen.Spec{Count: 1, Length: 5, Prefix: "SALE-", Checksum: true, MaxTotal: 10}},
+		// 10^2 = 100 кодов, нужно не меньше 2 * 51
+		{name: "space under twice the count", spec: codegen.Spec{Count: 51, Alphabet: "0123456789", Length: 2}},
+		{name: "space smaller than the count", spec: codegen.Spec{Count: 5, Alphabet: "AB", Length: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes, err := codegen.Generate(tt.spec)
+			if !errors.Is(err, codegen.ErrInvalidSpec) {
+				t.Fatalf("got %v, want ErrInvalidSpec", err)
+			}
+			if codes != nil {
+				t.Fatalf("got %d codes for an invalid spec", len(codes))
+			}
+		})
+	}
+}
+
+func TestGenerateCapacityBoundary(t *testing.T) {
+	// Ровно 2 * count кодов - допустимо
+	codes, err := codegen.Generate(codegen.Spec{Count: 50, Alphabet: "0123456789", Length: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 50 {
+		t.Fatalf("got %d codes, want 50", len(codes))
+	}
+}
+
+func TestCheckChar(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		alphabet string
+		want     rune
+	}{
+		{name: "luhn digit", s: "7992739871", alphabet: "0123456789", want: '3'},
+		{name: "luhn digit of zero", s: "0", alphabet: "0123456789", want: '0'},
+		{name: "characters outside the alphabet are ignored", s: "7992-7398-71", alphabet: "0123456789", want: '3'},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codegen.CheckChar(tt.s, []rune(tt.alphabet)); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCharCatchesSubstitution(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		alphabet string
+	}{
+		{name: "decimal", s: "7992739871", alphabet: "0123456789"},
+		{name: "default alphabet", s: "K7QX2MZA", alphabet: codegen.DefaultAlphabet},
+		{name: "even alphabet", s: "CAFEBABE", alphabet: "0123456789ABCDEF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alphabet := []rune(tt.alphabet)
+			runes := []rune(tt.s)
+			check := codegen.CheckChar(tt.s, alphabet)
+
+			// Любая замена одного символа меняет контрольный символ
+			for i := range runes {
+				for _, r := range alphabet {
+					if r == runes[i] {
+						continue
+					}
+
+					changed := append([]rune{}, runes...)
+					changed[i] = r
+					if got := codegen.CheckChar(string(changed), alphabet); got == check {
+						t.Fatalf("%q and %q have the same check character %q", tt.s, string(changed), check)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalid = errors.New("invalid cursor")
+
+// Cursor is a position in a list ordered by (Time, ID) descending or, for ranked lists, a position by count.
+/*
+ * Sort is the ordering the cursor was issued for, a cursor can't be reused with another ordering.
+ * In ranked lists Time is the moment the first page was scored and Offset is the number of items already returned:
+ * scores use live counters, so there's no stable key to continue after, and pages are approximate.
+ */
+type Cursor struct {
+	Sort   string    `json:"s,omitempty"`
+	Time   time.Time `json:"t"`
+	Offset int       `json:"o,omitempty"`
+	ID     string    `json:"id"`
+}
+
+// Encode is a function that converts a cursor to an opaque url-safe string.
+func Encode(c Cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode is a function that parses an opaque cursor string, an empty string means the first page.
+func Decode(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	var c Cursor
+	if err = json.Unmarshal(raw, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalid
+	}
+
+	return &c, nil
+}
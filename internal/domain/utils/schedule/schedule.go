@@ -0,0 +1,234 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"prod/internal/domain/dto"
+	"slices"
+	"time"
+)
+
+// DateLayout is the layout of whole-date bounds of a promo, the start of the day in UTC.
+const DateLayout = "2006-01-02"
+
+var ErrInvalid = errors.New("invalid schedule")
+
+// ParseBound is a function that parses active_from or active_until: a whole date or an RFC 3339 timestamp.
+func ParseBound(value string) (time.Time, error) {
+	if t, err := time.Parse(DateLayout, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// FormatBound is a function that formats active_from or active_until back the way it was most likely given.
+/*
+ * Midnight in UTC is formatted as a whole date, anything else as an RFC 3339 timestamp.
+ */
+func FormatBound(t time.Time) string {
+	t = t.UTC()
+	if t.Equal(t.Truncate(24 * time.Hour)) {
+		return t.Format(DateLayout)
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// Normalize is a function that validates the schedule and fills the default timezone.
+/*
+ * Weekdays are ISO (1 - Monday, 7 - Sunday), bounds are "HH:MM" in the timezone, "until" can be "24:00".
+ * A window can't cross midnight, an overnight window is given as two windows.
+ * Returns nil for a schedule without windows: the promo is available around the clock.
+ */
+func Normalize(schedule *dto.Schedule) (*dto.Schedule, error) {
+	if schedule == nil || len(schedule.Windows) == 0 {
+		return nil, nil
+	}
+
+	normalized := dto.Schedule{Timezone: schedule.Timezone}
+	if normalized.Timezone == "" {
+		normalized.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(normalized.Timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalid, normalized.Timezone)
+	}
+
+	for i, window := range schedule.Windows {
+		if len(window.Weekdays) == 0 {
+			return nil, fmt.Errorf("%w: window %d has no weekdays", ErrInvalid, i)
+		}
+		for _, weekday := range window.Weekdays {
+			if weekday < 1 || weekday > 7 {
+				return nil, fmt.Errorf("%w: window %d has weekday %d, expected 1 (Monday) to 7 (Sunday)", ErrInvalid, i, weekday)
+			}
+		}
+
+		from, err := parseClock(window.From)
+		if err != nil {
+			return nil, fmt.Errorf("%w: window %d: %v", ErrInvalid, i, err)
+		}
+		until, err := parseClock(window.Until)
+		if err != nil {
+			return nil, fmt.Errorf("%w: window %d: %v", ErrInvalid, i, err)
+		}
+		if from >= until {
+			return nil, fmt.Errorf("%w: window %d ends before it starts, split overnight windows in two", ErrInvalid, i)
+		}
+
+		weekdays := slices.Clone(window.Weekdays)
+		slices.Sort(weekdays)
+		normalized.Windows = append(normalized.Windows, dto.ScheduleWindow{
+			Weekdays: slices.Compact(weekdays),
+			From:     formatClock(from),
+			Until:    formatClock(until),
+		})
+	}
+
+	return &normalized, nil
+}
+
+// Marshal is a function that normalizes the schedule and returns it as JSON for the database, nil for no schedule.
+func Marshal(schedule *dto.Schedule) (*string, error) {
+	normalized, err := Normalize(schedule)
+	if err != nil || normalized == nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+	value := string(raw)
+
+	return &value, nil
+}
+
+// Unmarshal is a function that returns a schedule stored by Marshal.
+func Unmarshal(value *string) (*dto.Schedule, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+
+	var schedule dto.Schedule
+	if err := json.Unmarshal([]byte(*value), &schedule); err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+// Availability is a function that reports whether the schedule is open at now and, if not, when it opens next.
+/*
+ * A nil schedule is always open. The next window is looked up within a week, nil means the schedule never opens.
+ */
+func Availability(schedule *dto.Schedule, now time.Time) (bool, *time.Time) {
+	if schedule == nil || len(schedule.Windows) == 0 {
+		return true, nil
+	}
+
+	location, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	local := now.In(location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+
+	var next *time.Time
+	for day := 0; day <= 7; day++ {
+		date := midnight.AddDate(0, 0, day)
+		weekday := isoWeekday(date.Weekday())
+
+		for _, window := range schedule.Windows {
+			if !slices.Contains(window.Weekdays, weekday) {
+				continue
+			}
+
+			from, errFrom := parseClock(window.From)
+			until, errUntil := parseClock(window.Until)
+			if errFrom != nil || errUntil != nil {
+				continue
+			}
+
+			// Границы по часам на стене: в день перехода на летнее время в сутках не 24 часа
+			start := time.Date(date.Year(), date.Month(), date.Day(), from/60, from%60, 0, 0, location)
+			end := time.Date(date.Year(), date.Month(), date.Day(), until/60, until%60, 0, 0, location)
+			if !local.Before(start) && local.Before(end) {
+				return true, nil
+			}
+			if start.After(local) && (next == nil || start.Before(*next)) {
+				next = &start
+			}
+		}
+
+		// Окна следующих дней начинаются позже любого окна этого дня
+		if next != nil {
+			break
+		}
+	}
+
+	return false, next
+}
+
+// Activation limit periods.
+const (
+	PeriodEver = "ever"
+	PeriodDay  = "day"
+	PeriodWeek = "week"
+)
+
+// Period is a function that returns bounds of the calendar period containing now, weeks start on Monday.
+/*
+ * Days and weeks are taken in the schedule's timezone, UTC without a schedule.
+ * For PeriodEver start is the zero time and next is nil.
+ */
+func Period(period string, schedule *dto.Schedule, now time.Time) (time.Time, *time.Time) {
+	if period != PeriodDay && period != PeriodWeek {
+		return time.Time{}, nil
+	}
+
+	location := time.UTC
+	if schedule != nil {
+		if loaded, err := time.LoadLocation(schedule.Timezone); err == nil {
+			location = loaded
+		}
+	}
+	local := now.In(location)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+
+	next := start.AddDate(0, 0, 1)
+	if period == PeriodWeek {
+		start = start.AddDate(0, 0, 1-isoWeekday(local.Weekday()))
+		next = start.AddDate(0, 0, 7)
+	}
+
+	return start, &next
+}
+
+// isoWeekday is a function that converts time.Weekday to ISO numbering, Sunday is 7.
+func isoWeekday(weekday time.Weekday) int {
+	if weekday == time.Sunday {
+		return 7
+	}
+
+	return int(weekday)
+}
+
+// parseClock is a function that returns minutes since midnight of an "HH:MM" value, "24:00" is the end of the day.
+func parseClock(value string) (int, error) {
+	var hours, minutes int
+	if n, err := fmt.Sscanf(value, "%d:%d", &hours, &minutes); err != nil || n != 2 || len(value) != 5 {
+		return 0, fmt.Errorf("time %q is not HH:MM", value)
+	}
+	if hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("time %q is out of range", value)
+	}
+
+	return hours*60 + minutes, nil
+}
+
+// formatClock is a function that formats minutes since midnight as "HH:MM".
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
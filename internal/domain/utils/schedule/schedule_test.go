@@ -0,0 +1,296 @@
+package schedule_test
+
+import (
+	"errors"
+	"prod/internal/domain/dto"
+	"prod/internal/domain/utils/schedule"
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return location
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      *dto.Schedule
+		want    *dto.Schedule
+		wantErr bool
+	}{
+		{name: "nil", in: nil, want: nil},
+		{name: "no windows", in: &dto.Schedule{Timezone: "Europe/Moscow"}, want: nil},
+		{
+			name: "default timezone, sorted weekdays",
+			in:   &dto.Schedule{Windows: []dto.ScheduleWindow{{Weekdays: []int{7, 1, 5, 1}, From: "09:00", Until: "18:30"}}},
+			want: &dto.Schedule{Timezone: "UTC", Windows: []dto.ScheduleWindow{{Weekdays: []int{1, 5, 7}, From: "09:00", Until: "18:30"}}},
+		},
+		{
+			name: "until the end of the day",
+			in:   &dto.Schedule{Timezone: "Europe/Berlin", Windows: []dto.ScheduleWindow{{Weekdays: []int{6}, From: "22:00", Until: "24:00"}}},
+			want: &dto.Schedule{Timezone: "Europe/Berlin", Windows: []dto.ScheduleWindow{{Weekdays: []int{6}, From: "22:00", Until: "24:00"}}},
+		},
+		{
+			name: "overnight window is split in two",
+			in: &dto.Schedule{Windows: []dto.ScheduleWindow{
+				{Weekdays: []int{5}, From: "22:00", Until: "24:00"},
+				{Weekdays: []int{6}, From: "00:00", Until: "02:00"},
+			}},
+			want: &dto.Schedule{Timezone: "UTC", Windows: []dto.ScheduleWindow{
+				{Weekdays: []int{5}, From: "22:00", Until: "24:00"},
+				{Weekdays: []int{6}, From: "00:00", Until: "02:00"},
+			}},
+		},
+		{name: "crosses midnight", in: &dto.Schedule{Windows: []dto.ScheduleWindow{{Weekdays: []int{5}, From: "22:00", Until: "02:00"}}}, wantErr: true},
+		{name: "empty window", in: &dto.Schedule{Windows: []dto.ScheduleWindow{{Weekdays: []int{5}, From: "10:00", Until: "10:00"}}}, wantErr: true},
+		{name: "unknown timezone", in: &dto.Schedule{Timezone: "Mars/Olympus", Windows: []dto.ScheduleWindow{{Weekdays: []int{1}, From: "09:00", Until: "18:00"}}}, wantErr: true},
+		{name: "no weekdays", in: &dto.Schedule{Windows: []dto.ScheduleWindow{{From: "09:00", Until: "18:00"}}}, wantErr: true},
+		{name: "weekday 0", in: &dto.Schedule{Windows: []dto.ScheduleWindow{{Weekdays: []int{0}, From: "09:00", Until: "18:00"}}}, wantErr: true},
+		{name: "weekday 8", in: &dto.Schedule{Windows: []dto.ScheduleWindow{{Weekdays: []int{8}, From: "09:00", Until: "18:00"}}}, wantErr: true},
+		{name: "not HH:MM", in: &dto.Schedule{Windows: []dto.ScheduleWindow{{Weekdays: []int{1}, From: "9:00", Until: "18:00"}}}, wantErr: true},
+		{name: "past the end of the day", in: &dto.Schedule{Windows: []dto.ScheduleWindow{{Weekdays: []int{1}, From: "09:00", Until: "24:01"}}}, wantErr: true},
+		{name: "minutes out of range", in: &dto.Schedule{Windows: []dto.ScheduleWindow{{Weekdays: []int{1}, From: "09:60", Until: "18:00"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schedule.Normalize(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, schedule.ErrInvalid) {
+					t.Fatalf("got %v, want ErrInvalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBound(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Time
+		format string
+	}{
+		{name: "whole date", value: "2024-03-31", want: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), format: "2024-03-31"},
+		{name: "timestamp in UTC", value: "2024-03-31T09:30:00Z", want: time.Date(2024, 3, 31, 9, 30, 0, 0, time.UTC), format: "2024-03-31T09:30:00Z"},
+		{name: "timestamp with offset", value: "2024-03-31T12:30:00+03:00", want: time.Date(2024, 3, 31, 9, 30, 0, 0, time.UTC), format: "2024-03-31T09:30:00Z"},
+		{name: "midnight with offset is not a whole date", value: "2024-04-01T00:00:00+03:00", want: time.Date(2024, 3, 31, 21, 0, 0, 0, time.UTC), format: "2024-03-31T21:00:00Z"},
+		{name: "UTC midnight timestamp is a whole date", value: "2024-04-01T00:00:00Z", want: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), format: "2024-04-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schedule.ParseBound(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("ParseBound got %v, want %v", got, tt.want)
+			}
+			if format := schedule.FormatBound(got); format != tt.format {
+				t.Fatalf("FormatBound got %q, want %q", format, tt.format)
+			}
+		})
+	}
+
+	for _, value := range []string{"", "31.03.2024", "2024-03-31 09:30", "2024-02-30"} {
+		if _, err := schedule.ParseBound(value); err == nil {
+			t.Fatalf("ParseBound(%q) got no error", value)
+		}
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	moscow := mustLocation(t, "Europe/Moscow")
+	berlin := mustLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name      string
+		period    string
+		schedule  *dto.Schedule
+		now       time.Time
+		wantStart time.Time
+		wantNext  *time.Time
+	}{
+		{name: "ever", period: schedule.PeriodEver, now: time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)},
+		{name: "unknown period", period: "month", now: time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)},
+		{
+			name:      "day in UTC without a schedule",
+			period:    schedule.PeriodDay,
+			now:       time.Date(2024, 3, 31, 23, 59, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+			wantNext:  ptr(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:      "day in the schedule's timezone",
+			period:    schedule.PeriodDay,
+			schedule:  &dto.Schedule{Timezone: "Europe/Moscow"},
+			now:       time.Date(2024, 3, 31, 22, 30, 0, 0, time.UTC), // 01:30 1 апреля по Москве
+			wantStart: time.Date(2024, 4, 1, 0, 0, 0, 0, moscow),
+			wantNext:  ptr(time.Date(2024, 4, 2, 0, 0, 0, 0, moscow)),
+		},
+		{
+			name:      "week ending on Sunday",
+			period:    schedule.PeriodWeek,
+			now:       time.Date(2024, 3, 31, 23, 59, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC),
+			wantNext:  ptr(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:      "week starting on Monday",
+			period:    schedule.PeriodWeek,
+			now:       time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+			wantNext:  ptr(time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:      "day of the switch to summer time is 23 hours",
+			period:    schedule.PeriodDay,
+			schedule:  &dto.Schedule{Timezone: "Europe/Berlin"},
+			now:       time.Date(2024, 3, 31, 12, 0, 0, 0, berlin),
+			wantStart: time.Date(2024, 3, 31, 0, 0, 0, 0, berlin),
+			wantNext:  ptr(time.Date(2024, 4, 1, 0, 0, 0, 0, berlin)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, next := schedule.Period(tt.period, tt.schedule, tt.now)
+			if !start.Equal(tt.wantStart) {
+				t.Fatalf("start got %v, want %v", start, tt.wantStart)
+			}
+			if !equalTime(next, tt.wantNext) {
+				t.Fatalf("next got %v, want %v", next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestAvailability(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+
+	// Воскресенье днем и ночь с пятницы на субботу двумя окнами
+	daytime := &dto.Schedule{Timezone: "Europe/Berlin", Windows: []dto.ScheduleWindow{{Weekdays: []int{7}, From: "09:00", Until: "18:00"}}}
+	overnight := &dto.Schedule{Timezone: "Europe/Berlin", Windows: []dto.ScheduleWindow{
+		{Weekdays: []int{5}, From: "22:00", Until: "24:00"},
+		{Weekdays: []int{6}, From: "00:00", Until: "02:00"},
+	}}
+	weekdays := &dto.Schedule{Windows: []dto.ScheduleWindow{{Weekdays: []int{1, 2, 3, 4, 5}, From: "00:00", Until: "24:00"}}}
+
+	tests := []struct {
+		name     string
+		schedule *dto.Schedule
+		now      time.Time
+		wantOpen bool
+		wantNext *time.Time
+	}{
+		{name: "no schedule", schedule: nil, now: time.Date(2024, 3, 31, 3, 0, 0, 0, time.UTC), wantOpen: true},
+		{
+			// 31 марта 2024 в Берлине часы переводятся с 02:00 на 03:00
+			name:     "opens by the wall clock on the switch to summer time",
+			schedule: daytime,
+			now:      time.Date(2024, 3, 31, 9, 0, 0, 0, berlin),
+			wantOpen: true,
+		},
+		{
+			name:     "next opening on the switch to summer time",
+			schedule: daytime,
+			now:      time.Date(2024, 3, 31, 8, 59, 0, 0, berlin),
+			wantNext: ptr(time.Date(2024, 3, 31, 9, 0, 0, 0, berlin)),
+		},
+		{
+			name:     "closes by the wall clock on the switch to summer time",
+			schedule: daytime,
+			now:      time.Date(2024, 3, 31, 18, 0, 0, 0, berlin),
+			wantNext: ptr(time.Date(2024, 4, 7, 9, 0, 0, 0, berlin)),
+		},
+		{
+			// 27 октября 2024 в Берлине часы переводятся с 03:00 на 02:00
+			name:     "opens by the wall clock on the switch to winter time",
+			schedule: daytime,
+			now:      time.Date(2024, 10, 27, 8, 30, 0, 0, berlin),
+			wantNext: ptr(time.Date(2024, 10, 27, 9, 0, 0, 0, berlin)),
+		},
+		{
+			name:     "open until the wall clock end on the switch to winter time",
+			schedule: daytime,
+			now:      time.Date(2024, 10, 27, 17, 59, 0, 0, berlin),
+			wantOpen: true,
+		},
+		{name: "overnight before midnight", schedule: overnight, now: time.Date(2024, 3, 29, 23, 30, 0, 0, berlin), wantOpen: true},
+		{name: "overnight at midnight", schedule: overnight, now: time.Date(2024, 3, 30, 0, 0, 0, 0, berlin), wantOpen: true},
+		{name: "overnight after midnight", schedule: overnight, now: time.Date(2024, 3, 30, 1, 59, 0, 0, berlin), wantOpen: true},
+		{
+			name:     "overnight is over",
+			schedule: overnight,
+			now:      time.Date(2024, 3, 30, 2, 0, 0, 0, berlin),
+			wantNext: ptr(time.Date(2024, 4, 5, 22, 0, 0, 0, berlin)),
+		},
+		{
+			name:     "last minute of Friday",
+			schedule: weekdays,
+			now:      time.Date(2024, 3, 29, 23, 59, 0, 0, time.UTC),
+			wantOpen: true,
+		},
+		{
+			name:     "Saturday waits for Monday",
+			schedule: weekdays,
+			now:      time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC),
+			wantNext: ptr(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:     "last minute of Sunday",
+			schedule: weekdays,
+			now:      time.Date(2024, 3, 31, 23, 59, 0, 0, time.UTC),
+			wantNext: ptr(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)),
+		},
+		{name: "Monday midnight", schedule: weekdays, now: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), wantOpen: true},
+		{
+			name:     "weekday in the schedule's timezone, not in UTC",
+			schedule: &dto.Schedule{Timezone: "Europe/Moscow", Windows: []dto.ScheduleWindow{{Weekdays: []int{1}, From: "00:00", Until: "03:00"}}},
+			now:      time.Date(2024, 3, 31, 22, 0, 0, 0, time.UTC), // воскресенье в UTC, 01:00 понедельника по Москве
+			wantOpen: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			open, next := schedule.Availability(tt.schedule, tt.now)
+			if open != tt.wantOpen {
+				t.Fatalf("open got %v, want %v", open, tt.wantOpen)
+			}
+			if !equalTime(next, tt.wantNext) {
+				t.Fatalf("next got %v, want %v", next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}